@@ -22,6 +22,8 @@ import (
 // @in                          header
 // @name                        Authorization
 // @desc                        Add Bearer token to the request header
+
+// @securityDefinitions.basic  BasicAuth
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -24,6 +24,8 @@ type (
 				MaxConnectionAge      int    `default:"0"`
 				MaxConnectionAgeGrace int    `default:"0"`
 				Time                  int    `default:"0"`
+				// AllowAnonymous passes the calls without a token to the services
+				AllowAnonymous bool `default:"false"`
 			}
 		}
 
@@ -45,18 +47,116 @@ type (
 		}
 
 		Auth struct {
-			DatabaseName   string `default:"auth"`
-			CollectionName string `default:"users"`
+			DatabaseName               string `default:"auth"`
+			CollectionName             string `default:"users"`
+			RefreshTokenCollectionName string `default:"refresh_tokens"`
+			DenylistCollectionName     string `default:"token_denylist"`
+			TokenStore                 string `default:"mongo"`
+			ClientCollectionName       string `default:"oauth_clients"`
+			CodeCollectionName         string `default:"authorization_codes"`
+			DeviceCollectionName       string `default:"device_authorizations"`
+			FederationCollectionName   string `default:"federated_logins"`
+			SamlCollectionName         string `default:"saml_connections"`
+			ScimTokenCollectionName    string `default:"scim_tokens"`
+			ScimGroupCollectionName    string `default:"scim_groups"`
+			ConsentCollectionName      string `default:"consents"`
+			// VerificationCollectionName is the collection of the tokens
+			// sent to the emails of the users
+			VerificationCollectionName string `default:"verification_tokens"`
+			// RequireVerifiedEmail refuses the login of the users with an
+			// unverified email. The tokens of the users are only flagged
+			// when it is false.
+			RequireVerifiedEmail bool `default:"false"`
+			EmailVerificationExp int  `default:"86400"`
+			PasswordResetExp     int  `default:"3600"`
+			// AccountDeletionGracePeriod is the time in seconds which the
+			// users have to cancel the deletion of their accounts by
+			// logging in. The due accounts are anonymized every
+			// AccountDeletionInterval seconds.
+			AccountDeletionGracePeriod int `default:"2592000"`
+			AccountDeletionInterval    int `default:"3600"`
 		}
 
 		Jwt struct {
-			AccessTokenExp             int    `default:"3600"`
-			RefreshTokenExp            int    `default:"1296000"`
-			Issuer                     string `default:"hey-taxi-identity-api"`
-			AccessTokenPrivateKeyFile  string `default:"/etc/certs/access-token-private-key.pem"`
-			AccessTokenPublicKeyFile   string `default:"/etc/certs/access-token-public-key.pem"`
-			RefreshTokenPrivateKeyFile string `default:"/etc/certs/refresh-token-private-key.pem"`
-			RefreshTokenPublicKeyFile  string `default:"/etc/certs/refresh-token-public-key.pem"`
+			AccessTokenExp             int      `default:"3600"`
+			RefreshTokenExp            int      `default:"1296000"`
+			Issuer                     string   `default:"hey-taxi-identity-api"`
+			AccessTokenPrivateKeyFile  string   `default:"/etc/certs/access-token-private-key.pem"`
+			AccessTokenPublicKeyFile   string   `default:"/etc/certs/access-token-public-key.pem"`
+			RefreshTokenPrivateKeyFile string   `default:"/etc/certs/refresh-token-private-key.pem"`
+			RefreshTokenPublicKeyFile  string   `default:"/etc/certs/refresh-token-public-key.pem"`
+			AccessTokenAlgorithm       string   `default:"RS256"`
+			RefreshTokenAlgorithm      string   `default:"RS256"`
+			AccessTokenKeyDir          string   `default:""`
+			AccessTokenActiveKey       string   `default:""`
+			RefreshTokenKeyDir         string   `default:""`
+			RefreshTokenActiveKey      string   `default:""`
+			KeyReloadInterval          int      `default:"30"`
+			KeySetMaxAge               int      `default:"3600"`
+			Audiences                  []string `default:""`
+			Scopes                     []string `default:""`
+			DefaultScopes              []string `default:""`
+			AdminScopes                []string `default:""`
+			CustomClaimsMaxSize        int      `default:"1024"`
+			// ApiAudience is the audience of the account and admin routes of
+			// this api, which accept only the tokens issued for it. The
+			// first of Audiences is used when it is empty.
+			ApiAudience string `default:""`
+			// AccountScopes are required on the account routes like
+			// AdminScopes on the admin routes. They have to be one of Scopes.
+			AccountScopes []string `default:""`
+		}
+
+		Oauth struct {
+			// BaseUrl is the external url of the api. It is taken from the
+			// request when empty.
+			BaseUrl string `default:""`
+			// IntrospectionClients are the clients in "id:secret" format
+			// which may introspect the tokens. The gRPC introspection
+			// accepts the client tokens of the same ids instead.
+			IntrospectionClients []string `default:""`
+			LoginUrl             string   `default:""`
+			AuthorizationCodeExp int      `default:"60"`
+			VerificationUrl      string   `default:""`
+			DeviceCodeExp        int      `default:"600"`
+			DeviceCodeInterval   int      `default:"5"`
+			// TokenExchangeRoles are the roles of the users and
+			// TokenExchangeClients are the clients which may act on behalf
+			// of the users with the token exchange grant
+			TokenExchangeRoles   []string `default:"admin"`
+			TokenExchangeClients []string `default:""`
+		}
+
+		Federation struct {
+			// ProvidersFile is a JSON file of the upstream OpenID Connect
+			// providers. Federated login is disabled when it is empty.
+			ProvidersFile         string `default:""`
+			LoginExp              int    `default:"600"`
+			RequestTimeout        int    `default:"10"`
+			KeySetRefreshInterval int    `default:"60"`
+		}
+
+		Saml struct {
+			// MaxClockSkew is the allowed clock difference with the identity
+			// providers in seconds
+			MaxClockSkew int `default:"60"`
+		}
+
+		Mail struct {
+			// Driver is "log" to write the emails to the log or "file" to
+			// write them to Dir
+			Driver string `default:"log"`
+			From   string `default:"no-reply@hey-taxi.app"`
+			Dir    string `default:"mails"`
+			// VerifyEmailUrl is the page which the verification links point
+			// to. The token is added to its query.
+			VerifyEmailUrl   string `default:""`
+			ResetPasswordUrl string `default:""`
+		}
+
+		Scim struct {
+			// MaxResults is the maximum number of the resources in a page
+			MaxResults int `default:"200"`
 		}
 	}
 )
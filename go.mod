@@ -3,15 +3,20 @@ module github.com/orkungursel/hey-taxi-identity-api
 go 1.18
 
 require (
+	github.com/beevik/etree v1.1.0
 	github.com/golang/mock v1.6.0
 	github.com/labstack/echo/v4 v4.7.2
+	github.com/russellhaering/goxmldsig v1.4.0
 	github.com/swaggo/echo-swagger v1.3.0
 	github.com/swaggo/swag v1.8.1
 	google.golang.org/grpc v1.45.0
 	google.golang.org/protobuf v1.28.0
 )
 
-require github.com/google/go-cmp v0.5.7 // indirect
+require (
+	github.com/google/go-cmp v0.5.7 // indirect
+	github.com/jonboulle/clockwork v0.2.2 // indirect
+)
 
 require (
 	github.com/KyleBanks/depth v1.2.1 // indirect
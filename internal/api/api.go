@@ -2,9 +2,11 @@ package api
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/orkungursel/hey-taxi-identity-api/internal/api/grpc"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/infrastructure"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/server"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -23,20 +25,255 @@ func Api(s *server.Server, mng *mongo.Client) error {
 	logger := s.Logger()
 
 	repo := infrastructure.NewRepository(c, logger, mng)
-	tks := infrastructure.NewTokenService(c, logger)
+	if err := repo.EnsureIndexes(s.Context()); err != nil {
+		return err
+	}
+
+	rts, err := refreshTokenStore(s, mng)
+	if err != nil {
+		return err
+	}
+
+	dl, err := tokenDenylist(s, mng)
+	if err != nil {
+		return err
+	}
+
+	tks := infrastructure.NewTokenService(c, logger, repo, rts, dl)
+	tks.UseClaimsEnrichers(s.ClaimsEnrichers)
+	go tks.WatchKeys(s.Context())
 	psw := infrastructure.NewPasswordService(logger)
-	svc := infrastructure.NewAuthService(c, logger, repo, tks, psw)
+
+	vts, err := verificationTokenStore(s, mng)
+	if err != nil {
+		return err
+	}
+
+	mailer, err := newMailer(s)
+	if err != nil {
+		return err
+	}
+
+	svc := infrastructure.NewAuthService(c, logger, repo, tks, psw, vts, mailer)
+	go svc.WatchAccountDeletions(s.Context())
 	usvc := infrastructure.NewUserService(c, logger, repo)
+	clients := infrastructure.NewClientRepository(c, logger, mng)
+	csvc := infrastructure.NewClientService(c, logger, clients, psw)
 
-	ctrl := http.NewController(c, logger, svc, tks)
+	codes, err := authorizationCodeStore(s, mng)
+	if err != nil {
+		return err
+	}
+
+	devices, err := deviceAuthorizationStore(s, mng)
+	if err != nil {
+		return err
+	}
+
+	consents := infrastructure.NewConsentRepository(c, logger, mng)
+	if err := consents.EnsureIndexes(s.Context()); err != nil {
+		return err
+	}
+
+	cnsvc := infrastructure.NewConsentService(c, logger, consents, csvc, rts)
+	osvc := infrastructure.NewOAuthService(c, logger, svc, repo, tks, csvc, codes, devices, consents)
+
+	logins, err := federatedLoginStore(s, mng)
+	if err != nil {
+		return err
+	}
+
+	fsvc := infrastructure.NewFederationService(c, logger, repo, tks, logins)
+	connections := infrastructure.NewSamlConnectionRepository(c, logger, mng)
+	ssvc := infrastructure.NewSamlService(c, logger, repo, tks, connections, logins)
+	scimTokens := infrastructure.NewScimTokenRepository(c, logger, mng)
+	scimGroups := infrastructure.NewScimGroupRepository(c, logger, mng)
+	if err := scimGroups.EnsureIndexes(s.Context()); err != nil {
+		return err
+	}
+
+	scsvc := infrastructure.NewScimService(c, logger, repo, scimTokens, scimGroups)
+
+	ctrl := http.NewController(c, logger, svc, tks, cnsvc)
 	if err := s.RegisterHttpApi("/auth", ctrl); err != nil {
 		return err
 	}
 
+	wk := http.NewWellKnownController(c, logger, tks, osvc)
+	if err := s.RegisterHttpApiAsRoot("/.well-known", wk); err != nil {
+		return err
+	}
+
+	oauth := http.NewOAuthController(c, logger, osvc, tks)
+	if err := s.RegisterHttpApi("/oauth", oauth); err != nil {
+		return err
+	}
+
+	federation := http.NewFederationController(c, logger, fsvc)
+	if err := s.RegisterHttpApi("/federation", federation); err != nil {
+		return err
+	}
+
+	saml := http.NewSamlController(c, logger, ssvc)
+	if err := s.RegisterHttpApi("/saml", saml); err != nil {
+		return err
+	}
+
+	scim := http.NewScimController(c, logger, scsvc)
+	if err := s.RegisterHttpApi("/scim/v2", scim); err != nil {
+		return err
+	}
+
+	admin := http.NewAdminController(c, logger, tks, csvc, ssvc, scsvc)
+	if err := s.RegisterHttpApi("/admin", admin); err != nil {
+		return err
+	}
+
+	if err := s.RegisterGrpcInterceptor(grpc.AuthInterceptor(c, tks)); err != nil {
+		return err
+	}
+
 	g := grpc.NewGrpcUserService(logger, usvc, tks)
 	if err := s.RegisterGrpcService(g); err != nil {
 		return err
 	}
 
+	gt := grpc.NewGrpcTokenService(c, logger, tks)
+	if err := s.RegisterGrpcService(gt); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// refreshTokenStore creates the refresh token store selected in the config
+func refreshTokenStore(s *server.Server, mng *mongo.Client) (app.RefreshTokenStore, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryRefreshTokenStore(), nil
+	case "mongo":
+		rts := infrastructure.NewMongoRefreshTokenStore(c, s.Logger(), mng)
+		if err := rts.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return rts, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// tokenDenylist creates the token denylist selected in the config
+func tokenDenylist(s *server.Server, mng *mongo.Client) (app.TokenDenylist, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryTokenDenylist(), nil
+	case "mongo":
+		dl := infrastructure.NewMongoTokenDenylist(c, s.Logger(), mng)
+		if err := dl.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return dl, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// authorizationCodeStore creates the authorization code store selected in the
+// config
+func authorizationCodeStore(s *server.Server, mng *mongo.Client) (app.AuthorizationCodeStore, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryAuthorizationCodeStore(), nil
+	case "mongo":
+		codes := infrastructure.NewMongoAuthorizationCodeStore(c, s.Logger(), mng)
+		if err := codes.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return codes, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// deviceAuthorizationStore creates the device authorization store selected in
+// the config
+func deviceAuthorizationStore(s *server.Server, mng *mongo.Client) (app.DeviceAuthorizationStore, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryDeviceAuthorizationStore(), nil
+	case "mongo":
+		devices := infrastructure.NewMongoDeviceAuthorizationStore(c, s.Logger(), mng)
+		if err := devices.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return devices, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// federatedLoginStore creates the federated login store selected in the
+// config
+func federatedLoginStore(s *server.Server, mng *mongo.Client) (app.FederatedLoginStore, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryFederatedLoginStore(), nil
+	case "mongo":
+		logins := infrastructure.NewMongoFederatedLoginStore(c, s.Logger(), mng)
+		if err := logins.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return logins, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// verificationTokenStore creates the verification token store selected in
+// the config
+func verificationTokenStore(s *server.Server, mng *mongo.Client) (app.VerificationTokenStore, error) {
+	c := s.Config()
+
+	switch c.Auth.TokenStore {
+	case "memory":
+		return infrastructure.NewInMemoryVerificationTokenStore(), nil
+	case "mongo":
+		vts := infrastructure.NewMongoVerificationTokenStore(c, s.Logger(), mng)
+		if err := vts.EnsureIndexes(s.Context()); err != nil {
+			return nil, err
+		}
+
+		return vts, nil
+	}
+
+	return nil, fmt.Errorf("unknown token store: %s", c.Auth.TokenStore)
+}
+
+// newMailer creates the mailer of the driver selected in the config
+func newMailer(s *server.Server) (app.Mailer, error) {
+	c := s.Config()
+
+	switch c.Mail.Driver {
+	case "log":
+		return infrastructure.NewLogMailer(c, s.Logger()), nil
+	case "file":
+		return infrastructure.NewFileMailer(c, s.Logger()), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail driver: %s", c.Mail.Driver)
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+type claimsKey struct{}
+
+// AuthInterceptor validates the bearer token forwarded in the authorization
+// metadata, so that revoked and denied tokens are rejected. Only the tokens of
+// the service clients are accepted, since the services expose the data of all
+// users. Calls without a token are rejected unless anonymous access is enabled
+// in the config.
+func AuthInterceptor(config *config.Config, tks app.TokenService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(md.Get("authorization")) == 0 {
+			if config.Server.Grpc.AllowAnonymous {
+				return handler(ctx, req)
+			}
+
+			return nil, grpc.Errorf(codes.Unauthenticated, "authorization is required")
+		}
+
+		token := md.Get("authorization")[0]
+		if len(token) < 7 || !strings.EqualFold(token[:7], "bearer ") {
+			return nil, grpc.Errorf(codes.Unauthenticated, "invalid authorization")
+		}
+
+		claims, err := tks.ParseToken(ctx, token[7:])
+		if err != nil {
+			return nil, grpc.Errorf(codes.Unauthenticated, "unauthorized")
+		}
+
+		if claims.GetTokenType() != app.AccessTokenTypeClient {
+			return nil, grpc.Errorf(codes.PermissionDenied, "a client token is required")
+		}
+
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+// ClaimsFromContext returns the claims of the token validated by AuthInterceptor
+func ClaimsFromContext(ctx context.Context) (app.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(app.Claims)
+	return claims, ok
+}
@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/infrastructure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tks := mock.NewMockTokenService(ctrl)
+	tks.EXPECT().ParseToken(gomock.Any(), "token").Return(&infrastructure.Claims{TokenType: app.AccessTokenTypeClient}, nil).AnyTimes()
+	tks.EXPECT().ParseToken(gomock.Any(), "user").Return(&infrastructure.Claims{}, nil).AnyTimes()
+	tks.EXPECT().ParseToken(gomock.Any(), "revoked").Return(nil, errors.New("revoked")).AnyTimes()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name           string
+		authorization  string
+		allowAnonymous bool
+		wantCode       codes.Code
+	}{
+		{
+			name:     "should fail without a token",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:           "should pass without a token when anonymous access is allowed",
+			allowAnonymous: true,
+			wantCode:       codes.OK,
+		},
+		{
+			name:          "should fail when authorization is not a bearer token",
+			authorization: "Basic token",
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "should fail when token is invalid",
+			authorization: "Bearer revoked",
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "should fail for the tokens of the users",
+			authorization: "Bearer user",
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:          "should pass with a client token",
+			authorization: "Bearer token",
+			wantCode:      codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.New()
+			c.Server.Grpc.AllowAnonymous = tt.allowAnonymous
+
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			_, err := AuthInterceptor(c, tks)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("AuthInterceptor() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
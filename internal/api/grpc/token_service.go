@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	. "github.com/orkungursel/hey-taxi-identity-api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type GrpcTokenService struct {
+	config *config.Config
+	logger logger.ILogger
+	tks    app.TokenService
+	UnimplementedTokenServiceServer
+}
+
+func NewGrpcTokenService(config *config.Config, logger logger.ILogger, tks app.TokenService) *GrpcTokenService {
+	return &GrpcTokenService{
+		config: config,
+		logger: logger,
+		tks:    tks,
+	}
+}
+
+func (s *GrpcTokenService) Register(reg grpc.ServiceRegistrar) {
+	RegisterTokenServiceServer(reg, s)
+}
+
+// IntrospectToken introspects the token for the introspection clients like
+// the HTTP route. The caller has to forward a client token whose subject is
+// one of the introspection clients.
+func (s *GrpcTokenService) IntrospectToken(ctx context.Context, r *IntrospectTokenRequest) (*IntrospectTokenResponse, error) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil, grpc.Errorf(codes.Unauthenticated, "unauthorized")
+	}
+
+	if claims.GetTokenType() != app.AccessTokenTypeClient || !s.isIntrospectionClient(claims.GetSubject()) {
+		return nil, grpc.Errorf(codes.PermissionDenied, "client is not allowed to introspect tokens")
+	}
+
+	res, err := s.tks.Introspect(ctx, &app.IntrospectionRequest{
+		Token:         r.Token,
+		TokenTypeHint: r.TokenTypeHint,
+	})
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "failed to introspect token: %s", err)
+	}
+
+	var actor string
+	if res.Act != nil {
+		actor = res.Act.Subject
+	}
+
+	return &IntrospectTokenResponse{
+		Active:    res.Active,
+		Sub:       res.Sub,
+		Role:      res.Role,
+		Exp:       res.Exp,
+		Iat:       res.Iat,
+		Iss:       res.Iss,
+		Jti:       res.Jti,
+		TokenType: res.TokenType,
+		Aud:       res.Aud,
+		Scope:     res.Scope,
+		ClientId:  res.ClientId,
+		Actor:     actor,
+	}, nil
+}
+
+// isIntrospectionClient returns true if the client is one of the introspection
+// clients, which are given in "id:secret" format
+func (s *GrpcTokenService) isIntrospectionClient(id string) bool {
+	for _, c := range s.config.Oauth.IntrospectionClients {
+		if cid, _, ok := strings.Cut(strings.TrimSpace(c), ":"); ok && cid != "" && cid == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/infrastructure"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+	. "github.com/orkungursel/hey-taxi-identity-api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGrpcTokenService_IntrospectToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	config.Oauth.IntrospectionClients = []string{"billing:secret"}
+
+	tks := mock.NewMockTokenService(ctrl)
+	tks.EXPECT().Introspect(gomock.Any(), &app.IntrospectionRequest{Token: "token"}).
+		Return(&app.IntrospectionResponse{Active: true, Sub: "user"}, nil).AnyTimes()
+
+	s := NewGrpcTokenService(config, NewLoggerMock(), tks)
+
+	clientClaims := func(sub string) app.Claims {
+		return &infrastructure.Claims{TokenType: app.AccessTokenTypeClient, StandardClaims: jwt.StandardClaims{Subject: sub}}
+	}
+
+	tests := []struct {
+		name     string
+		claims   app.Claims
+		wantCode codes.Code
+	}{
+		{
+			name:     "should fail without a token",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "should fail for the user tokens",
+			claims:   &infrastructure.Claims{StandardClaims: jwt.StandardClaims{Subject: "billing"}},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "should fail for the other clients",
+			claims:   clientClaims("pricing"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "should introspect for the introspection clients",
+			claims:   clientClaims("billing"),
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = context.WithValue(ctx, claimsKey{}, tt.claims)
+			}
+
+			got, err := s.IntrospectToken(ctx, &IntrospectTokenRequest{Token: "token"})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("GrpcTokenService.IntrospectToken() code = %v, want %v", code, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK && (!got.Active || got.Sub != "user") {
+				t.Errorf("GrpcTokenService.IntrospectToken() = %+v", got)
+			}
+		})
+	}
+}
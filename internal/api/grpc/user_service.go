@@ -41,7 +41,7 @@ func (s *GrpcUserService) GetUserInfo(ctx context.Context, r *GetUserInfoRequest
 		usersData = append(usersData, &UserInfo{
 			Id:     user.Id,
 			Email:  user.Email,
-			Name:   strings.Join([]string{user.FirstName, user.LastName}, " "),
+			Name:   strings.TrimSpace(strings.Join([]string{user.FirstName, user.LastName}, " ")),
 			Role:   user.Role,
 			Avatar: user.Avatar,
 		})
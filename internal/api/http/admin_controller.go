@@ -0,0 +1,344 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type AdminController struct {
+	config        *config.Config
+	logger        logger.ILogger
+	tokenService  app.TokenService
+	clientService app.ClientService
+	samlService   app.SamlService
+	scimService   app.ScimService
+}
+
+func NewAdminController(config *config.Config, logger logger.ILogger, ts app.TokenService, cs app.ClientService, ss app.SamlService, scs app.ScimService) *AdminController {
+	return &AdminController{
+		tokenService:  ts,
+		clientService: cs,
+		samlService:   ss,
+		scimService:   scs,
+		logger:        logger,
+		config:        config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *AdminController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.ErrorHandler())
+	e.Use(middleware.AuthWithAudience(a.tokenService, ApiAudience(a.config)), middleware.FirstParty(), middleware.Role(model.RoleAdmin), middleware.Scopes(ConfigScopes(a.config.Jwt.AdminScopes)...))
+
+	e.POST("/tokens/deny/", a.denyToken())
+
+	e.GET("/clients/", a.getClients())
+	e.POST("/clients/", a.createClient())
+	e.GET("/clients/:id/", a.getClient())
+	e.DELETE("/clients/:id/", a.deleteClient())
+
+	e.GET("/saml/connections/", a.getSamlConnections())
+	e.POST("/saml/connections/", a.createSamlConnection())
+	e.GET("/saml/connections/:id/", a.getSamlConnection())
+	e.DELETE("/saml/connections/:id/", a.deleteSamlConnection())
+
+	e.GET("/scim/tokens/", a.getScimTokens())
+	e.POST("/scim/tokens/", a.createScimToken())
+	e.DELETE("/scim/tokens/:id/", a.deleteScimToken())
+}
+
+// @Summary      Deny Token
+// @Description  Denies a leaked access token by its jti until it expires
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body  app.DenyTokenRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/tokens/deny [post]
+func (a *AdminController) denyToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.DenyTokenRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		if err := a.tokenService.DenyAccessToken(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		a.logger.Infof("access token %s is denied by %s", payload.Jti, c.Get("claims").(app.Claims).GetSubject())
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Get Clients
+// @Description  Returns the registered OAuth clients
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   model.OAuthClient
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/clients [get]
+func (a *AdminController) getClients() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.clientService.GetClients(c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Create Client
+// @Description  Registers an OAuth client. The secret of a confidential client is only returned once.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body      app.CreateClientRequest  true  "Payload"
+// @Success      201      {object}  app.CreateClientResponse
+// @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      500      {object}  app.HTTPError
+// @Router       /admin/clients [post]
+func (a *AdminController) createClient() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.CreateClientRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.clientService.CreateClient(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, res)
+	}
+}
+
+// @Summary      Get Client
+// @Description  Returns an OAuth client
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Client id"
+// @Success      200  {object}  model.OAuthClient
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/clients/{id} [get]
+func (a *AdminController) getClient() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.clientService.GetClient(c.Request().Context(), c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Delete Client
+// @Description  Deletes an OAuth client
+// @Tags         Admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Client id"
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/clients/{id} [delete]
+func (a *AdminController) deleteClient() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.clientService.DeleteClient(c.Request().Context(), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Get SAML Connections
+// @Description  Returns the SAML connections of the organizations
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   model.SamlConnection
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/saml/connections [get]
+func (a *AdminController) getSamlConnections() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.samlService.GetConnections(c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Create SAML Connection
+// @Description  Creates the SAML connection of an organization from the metadata of its identity provider
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body      app.CreateSamlConnectionRequest  true  "Payload"
+// @Success      201      {object}  model.SamlConnection
+// @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      409      {object}  app.HTTPError
+// @Failure      500      {object}  app.HTTPError
+// @Router       /admin/saml/connections [post]
+func (a *AdminController) createSamlConnection() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.CreateSamlConnectionRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.samlService.CreateConnection(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, res)
+	}
+}
+
+// @Summary      Get SAML Connection
+// @Description  Returns the SAML connection of an organization
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Connection id"
+// @Success      200  {object}  model.SamlConnection
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/saml/connections/{id} [get]
+func (a *AdminController) getSamlConnection() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.samlService.GetConnection(c.Request().Context(), c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Delete SAML Connection
+// @Description  Deletes the SAML connection of an organization
+// @Tags         Admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Connection id"
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/saml/connections/{id} [delete]
+func (a *AdminController) deleteSamlConnection() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.samlService.DeleteConnection(c.Request().Context(), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Get SCIM Tokens
+// @Description  Returns the SCIM tokens of the organizations
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   model.ScimToken
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/scim/tokens [get]
+func (a *AdminController) getScimTokens() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.scimService.GetTokens(c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Create SCIM Token
+// @Description  Creates a bearer token for the directory of an organization to provision its users. The token is only returned once.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body      app.CreateScimTokenRequest  true  "Payload"
+// @Success      201      {object}  app.CreateScimTokenResponse
+// @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      500      {object}  app.HTTPError
+// @Router       /admin/scim/tokens [post]
+func (a *AdminController) createScimToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.CreateScimTokenRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.CreateToken(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, res)
+	}
+}
+
+// @Summary      Delete SCIM Token
+// @Description  Deletes a SCIM token of an organization
+// @Tags         Admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Token id"
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /admin/scim/tokens/{id} [delete]
+func (a *AdminController) deleteScimToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.scimService.DeleteToken(c.Request().Context(), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/infrastructure"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+)
+
+func TestAdminController_Auth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := config.New()
+	c.Jwt.Audiences = []string{"identity-api"}
+	c.Jwt.AdminScopes = []string{"admin"}
+
+	cs := mock.NewMockClientService(ctrl)
+	cs.EXPECT().GetClients(gomock.Any()).Return([]*model.OAuthClient{}, nil).AnyTimes()
+
+	admin := func(claims *infrastructure.Claims) *infrastructure.Claims {
+		claims.Role = model.RoleAdmin
+		claims.Scope = "admin"
+		claims.StandardClaims = jwt.StandardClaims{Subject: "admin", Audience: "identity-api"}
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		claims     *infrastructure.Claims
+		wantStatus int
+	}{
+		{
+			name:       "should allow the first-party tokens of the admins",
+			claims:     admin(&infrastructure.Claims{}),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should fail for the tokens of the admins issued to the clients",
+			claims:     admin(&infrastructure.Claims{ClientId: "partner"}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "should fail for the delegated tokens of the admins",
+			claims:     admin(&infrastructure.Claims{Actor: &app.Actor{Subject: "support"}}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "should fail when admin scope is not granted",
+			claims: &infrastructure.Claims{
+				Role:           model.RoleAdmin,
+				StandardClaims: jwt.StandardClaims{Subject: "admin", Audience: "identity-api"},
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "should fail for the tokens of the users",
+			claims:     &infrastructure.Claims{Role: model.RoleUser, StandardClaims: jwt.StandardClaims{Subject: "user", Audience: "identity-api"}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := mock.NewMockTokenService(ctrl)
+			ts.EXPECT().ValidateAccessTokenFromRequest(gomock.Any(), gomock.Any()).Return(tt.claims, nil)
+
+			e := echo.New()
+			NewAdminController(c, NewLoggerMock(), ts, cs, nil, nil).RegisterRoutes(e.Group("/admin"))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/clients/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET /admin/clients/ status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
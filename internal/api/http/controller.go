@@ -11,18 +11,20 @@ import (
 )
 
 type Controller struct {
-	config       *config.Config
-	logger       logger.ILogger
-	authService  app.AuthService
-	tokenService app.TokenService
+	config         *config.Config
+	logger         logger.ILogger
+	authService    app.AuthService
+	tokenService   app.TokenService
+	consentService app.ConsentService
 }
 
-func NewController(config *config.Config, logger logger.ILogger, s app.AuthService, ts app.TokenService) *Controller {
+func NewController(config *config.Config, logger logger.ILogger, s app.AuthService, ts app.TokenService, cs app.ConsentService) *Controller {
 	return &Controller{
-		authService:  s,
-		tokenService: ts,
-		logger:       logger,
-		config:       config,
+		authService:    s,
+		tokenService:   ts,
+		consentService: cs,
+		logger:         logger,
+		config:         config,
 	}
 }
 
@@ -33,7 +35,22 @@ func (a *Controller) RegisterRoutes(e *echo.Group) {
 	e.POST("/login/", a.login())
 	e.POST("/register/", a.register())
 	e.POST("/refresh-token/", a.refreshToken())
-	e.GET("/me/", a.me(), middleware.Auth(a.tokenService))
+	e.POST("/logout/", a.logout())
+	e.POST("/verify-email/", a.verifyEmail())
+	e.POST("/verify-email/resend/", a.resendVerificationEmail())
+	e.POST("/password/forgot/", a.forgotPassword())
+	e.POST("/password/reset/", a.resetPassword())
+
+	auth := middleware.AuthWithAudience(a.tokenService, ApiAudience(a.config))
+	scopes := middleware.Scopes(ConfigScopes(a.config.Jwt.AccountScopes)...)
+
+	e.POST("/logout-all/", a.logoutAll(), auth, scopes, middleware.FirstParty())
+	e.GET("/me/", a.me(), auth, scopes)
+	e.PATCH("/me/", a.updateProfile(), auth, scopes, middleware.FirstParty())
+	e.DELETE("/me/", a.deleteAccount(), auth, scopes, middleware.FirstParty())
+	e.POST("/me/password/", a.changePassword(), auth, scopes, middleware.FirstParty())
+	e.GET("/me/applications/", a.applications(), auth, scopes, middleware.FirstParty())
+	e.DELETE("/me/applications/:clientId/", a.revokeApplication(), auth, scopes, middleware.FirstParty())
 }
 
 // @Summary      Login
@@ -127,6 +144,157 @@ func (a *Controller) refreshToken() echo.HandlerFunc {
 	}
 }
 
+// @Summary      Logout
+// @Description  Revokes the session of the given refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  app.LogoutRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/logout [post]
+func (a *Controller) logout() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.LogoutRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := app.Validate(payload); err != nil {
+			return err
+		}
+
+		if err := a.authService.Logout(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Verify Email
+// @Description  Marks the email of the user as verified with the token sent to it
+// @Tags         Auth
+// @Accept       json
+// @Param        payload  body  app.VerifyEmailRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/verify-email [post]
+func (a *Controller) verifyEmail() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.VerifyEmailRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := a.authService.VerifyEmail(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Resend Verification Email
+// @Description  Sends a new verification token to the email if it belongs to an unverified user
+// @Tags         Auth
+// @Accept       json
+// @Param        payload  body  app.ResendVerificationEmailRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/verify-email/resend [post]
+func (a *Controller) resendVerificationEmail() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ResendVerificationEmailRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := a.authService.ResendVerificationEmail(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Forgot Password
+// @Description  Sends a password reset token to the email. The response is the same whether or not the email is registered.
+// @Tags         Auth
+// @Accept       json
+// @Param        payload  body  app.ForgotPasswordRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/password/forgot [post]
+func (a *Controller) forgotPassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ForgotPasswordRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := a.authService.ForgotPassword(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Reset Password
+// @Description  Sets the password with the token sent to the email and revokes all sessions of the user
+// @Tags         Auth
+// @Accept       json
+// @Param        payload  body  app.ResetPasswordRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/password/reset [post]
+func (a *Controller) resetPassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ResetPasswordRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		if err := a.authService.ResetPassword(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Logout from all devices
+// @Description  Revokes all access and refresh tokens of logged-in user
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/logout-all [post]
+// @Security     BearerAuth
+func (a *Controller) logoutAll() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, err := GetUserId(c)
+		if err != nil {
+			return err
+		}
+
+		if err := a.authService.LogoutAll(c.Request().Context(), userId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
 // @Summary      User Details
 // @Description  Fetch the details of logged-in user by access token
 // @Tags         Auth
@@ -135,6 +303,7 @@ func (a *Controller) refreshToken() echo.HandlerFunc {
 // @Success      200  {array}   app.UserResponse
 // @Failure      400      {object}  app.HTTPError
 // @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
 // @Failure      500      {object}  app.HTTPError
 // @Router       /auth/me [get]
 // @Security     BearerAuth
@@ -153,3 +322,149 @@ func (a *Controller) me() echo.HandlerFunc {
 		return c.JSON(http.StatusOK, res)
 	}
 }
+
+// @Summary      Update Profile
+// @Description  Updates the fields of the profile of the logged-in user which are set in the payload. An empty avatar or phone removes it.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      app.UpdateProfileRequest  true  "Payload"
+// @Success      200      {object}  app.UserResponse
+// @Failure      400      {object}  app.HTTPError
+// @Failure      401      {object}  app.HTTPError
+// @Failure      403      {object}  app.HTTPError
+// @Failure      500      {object}  app.HTTPError
+// @Router       /auth/me [patch]
+// @Security     BearerAuth
+func (a *Controller) updateProfile() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, err := GetUserId(c)
+		if err != nil {
+			return err
+		}
+
+		payload := &app.UpdateProfileRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		res, err := a.authService.UpdateProfile(c.Request().Context(), userId, payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Delete Account
+// @Description  Schedules the deletion of the account of the logged-in user and revokes all sessions. Logging in before delete_at cancels the deletion, and the personal data is removed after it.
+// @Tags         Auth
+// @Produce      json
+// @Success      202  {object}  app.DeleteAccountResponse
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/me [delete]
+// @Security     BearerAuth
+func (a *Controller) deleteAccount() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := c.Get("claims").(app.Claims)
+
+		res, err := a.authService.DeleteAccount(c.Request().Context(), claims)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, res)
+	}
+}
+
+// @Summary      Change Password
+// @Description  Changes the password of the logged-in user. All sessions are revoked when logout_other_devices is set, and a new token pair is returned for the current device.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      app.ChangePasswordRequest  true  "Payload"
+// @Success      200      {object}  app.RefreshTokenResponse
+// @Success      204
+// @Failure      400  {object}  app.HTTPError
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/me/password [post]
+// @Security     BearerAuth
+func (a *Controller) changePassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ChangePasswordRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, &payload); err != nil {
+			return err
+		}
+
+		claims := c.Get("claims").(app.Claims)
+
+		res, err := a.authService.ChangePassword(c.Request().Context(), claims, payload)
+		if err != nil {
+			return err
+		}
+
+		if res == nil {
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Authorized Applications
+// @Description  Lists the applications which the logged-in user granted access to
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {array}   app.ApplicationResponse
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/me/applications [get]
+// @Security     BearerAuth
+func (a *Controller) applications() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, err := GetUserId(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := a.consentService.GetApplications(c.Request().Context(), userId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Revoke Application
+// @Description  Revokes the access of the application and the refresh tokens issued to it
+// @Tags         Auth
+// @Param        clientId  path  string  true  "Client id"
+// @Success      204
+// @Failure      401  {object}  app.HTTPError
+// @Failure      403  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /auth/me/applications/{clientId} [delete]
+// @Security     BearerAuth
+func (a *Controller) revokeApplication() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, err := GetUserId(c)
+		if err != nil {
+			return err
+		}
+
+		if err := a.consentService.RevokeApplication(c.Request().Context(), userId, c.Param("clientId")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/server"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type FederationController struct {
+	config            *config.Config
+	logger            logger.ILogger
+	federationService app.FederationService
+}
+
+func NewFederationController(config *config.Config, logger logger.ILogger, s app.FederationService) *FederationController {
+	return &FederationController{
+		federationService: s,
+		logger:            logger,
+		config:            config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *FederationController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.ErrorHandler())
+
+	e.GET("/", a.providers())
+	e.GET("/:provider/", a.login())
+	e.GET("/:provider/callback/", a.callback())
+	e.POST("/:provider/callback/", a.callback())
+}
+
+// @Summary      Providers
+// @Description  Lists the upstream OpenID Connect providers to login with
+// @Tags         Federation
+// @Produce      json
+// @Success      200  {object}  app.FederationProvidersResponse
+// @Router       /federation [get]
+func (a *FederationController) providers() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, &app.FederationProvidersResponse{Providers: a.federationService.Providers()})
+	}
+}
+
+// @Summary      Federated Login
+// @Description  Redirects the user to the login page of the provider
+// @Tags         Federation
+// @Param        provider  path   string  true   "Provider"
+// @Param        audience  query  string  false  "Audience of the issued tokens"
+// @Param        scope     query  string  false  "Scope of the issued tokens"
+// @Success      302
+// @Failure      400  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /federation/{provider} [get]
+func (a *FederationController) login() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.FederatedLoginRequest{}
+		if err := c.Bind(payload); err != nil {
+			return err
+		}
+
+		payload.RedirectUri = BaseUrl(c, a.config) + server.ApiPrefix + "/federation/" + url.PathEscape(payload.Provider) + "/callback"
+
+		u, err := a.federationService.AuthorizationUrl(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusFound, u)
+	}
+}
+
+// @Summary      Federated Login Callback
+// @Description  Completes the login with the authorization response of the provider and returns a token pair. The user is linked by the verified email or created on the first login.
+// @Tags         Federation
+// @Produce      json
+// @Param        provider           path      string  true   "Provider"
+// @Param        state              query     string  true   "State"
+// @Param        code               query     string  false  "Authorization code"
+// @Param        error              query     string  false  "Error of the provider"
+// @Param        error_description  query     string  false  "Error description of the provider"
+// @Success      200                {object}  app.LoginResponse
+// @Failure      400                {object}  app.HTTPError
+// @Failure      401                {object}  app.HTTPError
+// @Failure      403                {object}  app.HTTPError
+// @Failure      409                {object}  app.HTTPError
+// @Failure      500                {object}  app.HTTPError
+// @Router       /federation/{provider}/callback [get]
+func (a *FederationController) callback() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.FederatedCallbackRequest{}
+		if err := c.Bind(payload); err != nil {
+			return err
+		}
+
+		res, err := a.federationService.Callback(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
@@ -23,3 +23,53 @@ func Auth(ts app.TokenService) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// AuthWithScopes authenticates the request like Auth and allows it only if the
+// access token is granted all of the scopes
+func AuthWithScopes(ts app.TokenService, scopes ...string) echo.MiddlewareFunc {
+	auth := Auth(ts)
+
+	scoped := Scopes(scopes...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return auth(scoped(next))
+	}
+}
+
+// Scopes allows the request only if the access token is granted all of the
+// scopes. It must be used after Auth.
+func Scopes(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("claims").(app.Claims)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+			}
+
+			if !app.HasScopes(claims.GetScope(), scopes...) {
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient scope")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AuthWithAudience authenticates the request like Auth and allows it only if
+// the access token is issued for the audience. The audience is not checked
+// when it is empty.
+func AuthWithAudience(ts app.TokenService, audience string) echo.MiddlewareFunc {
+	auth := Auth(ts)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return auth(func(c echo.Context) error {
+			claims := c.Get("claims").(app.Claims)
+
+			if audience != "" && claims.GetAudience() != audience {
+				return echo.NewHTTPError(http.StatusForbidden, "invalid audience")
+			}
+
+			return next(c)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientAuth authenticates the OAuth clients by HTTP basic auth. Clients are
+// given in "id:secret" format.
+func ClientAuth(clients []string) echo.MiddlewareFunc {
+	secrets := make(map[string]string)
+	for _, c := range clients {
+		id, secret, ok := strings.Cut(strings.TrimSpace(c), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+
+		secrets[id] = secret
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, secret, ok := c.Request().BasicAuth()
+			if ok {
+				if s, found := secrets[id]; found && subtle.ConstantTimeCompare([]byte(s), []byte(secret)) == 1 {
+					c.Set("client_id", id)
+					return next(c)
+				}
+			}
+
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid client")
+		}
+	}
+}
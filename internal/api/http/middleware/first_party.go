@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// FirstParty allows the request only if the access token is issued to the
+// user itself, so that the tokens of the OAuth clients, the service clients
+// and the actors are refused. It must be used after Auth.
+func FirstParty() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("claims").(app.Claims)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+			}
+
+			if !app.IsFirstPartyToken(claims) {
+				return app.ErrUserTokenRequired
+			}
+
+			return next(c)
+		}
+	}
+}
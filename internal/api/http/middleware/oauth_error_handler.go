@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// OAuthErrorHandler responds the errors in the format of RFC 6749
+func OAuthErrorHandler() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err == nil {
+				return nil
+			}
+
+			switch e := err.(type) {
+			case *app.OAuthError:
+				if e.Code == http.StatusUnauthorized {
+					c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+				}
+
+				return c.JSON(e.Code, e)
+			case *echo.HTTPError:
+				if e.Code == http.StatusUnauthorized {
+					return c.JSON(e.Code, app.NewOAuthError(e.Code, app.OAuthErrInvalidClient, ""))
+				}
+
+				if e.Code == http.StatusForbidden {
+					return c.JSON(e.Code, app.NewOAuthError(e.Code, app.OAuthErrInsufficientScope, ""))
+				}
+
+				if e.Code == http.StatusNotFound || e.Code == http.StatusMethodNotAllowed {
+					return e
+				}
+
+				return c.JSON(http.StatusBadRequest, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, ""))
+			case *app.Error:
+				if e.Code() >= http.StatusInternalServerError {
+					return c.JSON(http.StatusInternalServerError, app.NewOAuthError(http.StatusInternalServerError, app.OAuthErrServerError, ""))
+				}
+
+				return c.JSON(e.Code(), app.NewOAuthError(e.Code(), app.OAuthErrInvalidRequest, e.Error()))
+			}
+
+			return c.JSON(http.StatusBadRequest, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, err.Error()))
+		}
+	}
+}
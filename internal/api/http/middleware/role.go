@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// Role allows the request only if the authenticated user has one of the
+// roles. It must be used after Auth.
+func Role(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("claims").(app.Claims)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+			}
+
+			for _, r := range roles {
+				if claims.GetRole() == r {
+					return next(c)
+				}
+			}
+
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden")
+		}
+	}
+}
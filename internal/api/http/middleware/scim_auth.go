@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// ScimAuth authenticates the directories of the organizations by their bearer
+// tokens and sets the organization to the context
+func ScimAuth(s app.ScimService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth := c.Request().Header.Get(echo.HeaderAuthorization)
+			if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+				org, err := s.Authenticate(c.Request().Context(), auth[7:])
+				if err == nil {
+					c.Set("organization", org)
+					return next(c)
+				}
+			}
+
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="scim"`)
+			return app.NewScimError(http.StatusUnauthorized, "", "invalid token")
+		}
+	}
+}
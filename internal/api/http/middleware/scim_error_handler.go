@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// ScimErrorHandler responds the errors in the format of RFC 7644
+func ScimErrorHandler() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err == nil {
+				return nil
+			}
+
+			var res *app.ScimError
+			switch e := err.(type) {
+			case *app.ScimError:
+				res = e
+			case *app.Error:
+				if e.Code() == http.StatusInternalServerError {
+					res = app.NewScimError(e.Code(), "", http.StatusText(e.Code()))
+				} else {
+					res = app.NewScimError(e.Code(), "", e.Error())
+				}
+			case *echo.HTTPError:
+				res = app.NewScimError(e.Code, "", fmt.Sprint(e.Message))
+			default:
+				res = app.NewScimError(http.StatusBadRequest, "", err.Error())
+			}
+
+			c.Response().Header().Set(echo.HeaderContentType, app.ScimContentType)
+			return c.JSON(res.Code, res)
+		}
+	}
+}
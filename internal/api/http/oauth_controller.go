@@ -0,0 +1,406 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type OAuthController struct {
+	config       *config.Config
+	logger       logger.ILogger
+	oauthService app.OAuthService
+	tokenService app.TokenService
+}
+
+func NewOAuthController(config *config.Config, logger logger.ILogger, s app.OAuthService, ts app.TokenService) *OAuthController {
+	return &OAuthController{
+		oauthService: s,
+		tokenService: ts,
+		logger:       logger,
+		config:       config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *OAuthController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.OAuthErrorHandler())
+
+	// the authorizations are granted by the users themselves only
+	auth := middleware.AuthWithAudience(a.tokenService, ApiAudience(a.config))
+
+	e.GET("/authorize/", a.authorize())
+	e.POST("/authorize/", a.approve(), auth, middleware.FirstParty())
+	e.GET("/authorize/consent/", a.consent(), auth, middleware.FirstParty())
+	e.POST("/device_authorization/", a.deviceAuthorization())
+	e.GET("/device/", a.getDeviceAuthorization(), auth, middleware.FirstParty())
+	e.POST("/device/", a.decideDeviceAuthorization(), auth, middleware.FirstParty())
+	e.POST("/token/", a.token())
+	e.GET("/userinfo/", a.userInfo(), middleware.AuthWithScopes(a.tokenService, app.ScopeOpenId))
+	e.POST("/userinfo/", a.userInfo(), middleware.AuthWithScopes(a.tokenService, app.ScopeOpenId))
+	e.POST("/introspect/", a.introspect(), middleware.ClientAuth(a.config.Oauth.IntrospectionClients))
+	e.POST("/revoke/", a.revoke())
+}
+
+// @Summary      Authorize
+// @Description  Authorization endpoint (RFC 6749). Validates the request and redirects the user to the login page with the same query.
+// @Tags         OAuth
+// @Produce      json
+// @Param        response_type          query  string  true   "Response type"
+// @Param        client_id              query  string  true   "Client id"
+// @Param        redirect_uri           query  string  true   "Redirect uri"
+// @Param        scope                  query  string  false  "Scope"
+// @Param        state                  query  string  false  "State"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "PKCE code challenge method"
+// @Param        nonce                  query  string  false  "OpenID Connect nonce"
+// @Success      302
+// @Failure      400                    {object}  app.OAuthError
+// @Failure      500                    {object}  app.OAuthError
+// @Router       /oauth/authorize [get]
+func (a *OAuthController) authorize() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.AuthorizeRequest{}
+		if err := (&echo.DefaultBinder{}).BindQueryParams(c, payload); err != nil {
+			return err
+		}
+
+		if err := a.oauthService.ValidateAuthorizeRequest(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		loginUrl := a.config.Oauth.LoginUrl
+		if loginUrl == "" {
+			a.logger.Warnf("oauth login url is not configured")
+			return app.NewOAuthError(http.StatusInternalServerError, app.OAuthErrServerError, "")
+		}
+
+		sep := "?"
+		if strings.Contains(loginUrl, "?") {
+			sep = "&"
+		}
+
+		return c.Redirect(http.StatusFound, loginUrl+sep+c.QueryString())
+	}
+}
+
+// @Summary      Approve
+// @Description  Issues an authorization code for the logged in user and returns the redirect uri of the client
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Security     BearerAuth
+// @Param        response_type          formData  string  true   "Response type"
+// @Param        client_id              formData  string  true   "Client id"
+// @Param        redirect_uri           formData  string  true   "Redirect uri"
+// @Param        scope                  formData  string  false  "Scope"
+// @Param        state                  formData  string  false  "State"
+// @Param        code_challenge         formData  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  formData  string  true   "PKCE code challenge method"
+// @Param        nonce                  formData  string  false  "OpenID Connect nonce"
+// @Success      200                    {object}  app.AuthorizeResponse
+// @Failure      400  {object}  app.OAuthError
+// @Failure      401                    {object}  app.OAuthError
+// @Failure      403                    {object}  app.OAuthError
+// @Failure      500  {object}  app.OAuthError
+// @Router       /oauth/authorize [post]
+func (a *OAuthController) approve() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.AuthorizeRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		uid := c.Get("claims").(app.Claims).GetSubject()
+
+		res, err := a.oauthService.Authorize(c.Request().Context(), uid, payload)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Consent
+// @Description  Returns the scopes which the client requests from the logged in user. The consent screen can be skipped when the user already granted all of them to the client.
+// @Tags         OAuth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        response_type          query     string  true   "Response type"
+// @Param        client_id              query     string  true   "Client id"
+// @Param        redirect_uri           query     string  true   "Redirect uri"
+// @Param        scope                  query     string  false  "Scope"
+// @Param        code_challenge         query     string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query     string  true   "PKCE code challenge method"
+// @Success      200                    {object}  app.ConsentResponse
+// @Failure      400                    {object}  app.OAuthError
+// @Failure      401                    {object}  app.OAuthError
+// @Failure      403                    {object}  app.OAuthError
+// @Failure      500                    {object}  app.OAuthError
+// @Router       /oauth/authorize/consent [get]
+func (a *OAuthController) consent() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.AuthorizeRequest{}
+		if err := (&echo.DefaultBinder{}).BindQueryParams(c, payload); err != nil {
+			return err
+		}
+
+		uid := c.Get("claims").(app.Claims).GetSubject()
+
+		res, err := a.oauthService.Consent(c.Request().Context(), uid, payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Device Authorization
+// @Description  Device authorization endpoint (RFC 8628). Starts the device flow and returns the user code to be entered on the phone.
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        client_id      formData  string  true   "Client id"
+// @Param        client_secret  formData  string  false  "Client secret"
+// @Param        scope          formData  string  false  "Scope"
+// @Success      200            {object}  app.DeviceAuthorizationResponse
+// @Failure      400            {object}  app.OAuthError
+// @Failure      401            {object}  app.OAuthError
+// @Failure      500            {object}  app.OAuthError
+// @Router       /oauth/device_authorization [post]
+func (a *OAuthController) deviceAuthorization() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.DeviceAuthorizationRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		if err := bindClientCredentials(c, &payload.ClientId, &payload.ClientSecret); err != nil {
+			return err
+		}
+
+		res, err := a.oauthService.DeviceAuthorization(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Get Device Authorization
+// @Description  Returns the pending device authorization request of the user code
+// @Tags         OAuth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_code  query     string  true  "User code"
+// @Success      200        {object}  app.DeviceVerificationResponse
+// @Failure      400        {object}  app.OAuthError
+// @Failure      401        {object}  app.OAuthError
+// @Failure      403        {object}  app.OAuthError
+// @Failure      500        {object}  app.OAuthError
+// @Router       /oauth/device [get]
+func (a *OAuthController) getDeviceAuthorization() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.oauthService.GetDeviceAuthorization(c.Request().Context(), c.QueryParam("user_code"))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Decide Device Authorization
+// @Description  Approves or denies the device authorization request of the user code for the logged in user
+// @Tags         OAuth
+// @Accept       json
+// @Security     BearerAuth
+// @Param        payload  body  app.DeviceDecisionRequest  true  "Payload"
+// @Success      204
+// @Failure      400  {object}  app.OAuthError
+// @Failure      401  {object}  app.OAuthError
+// @Failure      403  {object}  app.OAuthError
+// @Failure      500  {object}  app.OAuthError
+// @Router       /oauth/device [post]
+func (a *OAuthController) decideDeviceAuthorization() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.DeviceDecisionRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		uid := c.Get("claims").(app.Claims).GetSubject()
+
+		if err := a.oauthService.DecideDeviceAuthorization(c.Request().Context(), uid, payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      Token
+// @Description  Token endpoint (RFC 6749). Confidential clients authenticate with HTTP basic auth or with the client_id and client_secret parameters. The token exchange grant (RFC 8693) issues a delegated access token of the subject token for the actor.
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type            formData  string  true   "Grant type"
+// @Param        username              formData  string  false  "Username"
+// @Param        password              formData  string  false  "Password"
+// @Param        refresh_token         formData  string  false  "Refresh token"
+// @Param        scope                 formData  string  false  "Scope"
+// @Param        audience              formData  string  false  "Audience"
+// @Param        code                  formData  string  false  "Authorization code"
+// @Param        redirect_uri          formData  string  false  "Redirect uri"
+// @Param        client_id             formData  string  false  "Client id"
+// @Param        code_verifier         formData  string  false  "PKCE code verifier"
+// @Param        client_secret         formData  string  false  "Client secret"
+// @Param        device_code           formData  string  false  "Device code"
+// @Param        subject_token         formData  string  false  "Subject token of the token exchange"
+// @Param        subject_token_type    formData  string  false  "Subject token type"
+// @Param        actor_token           formData  string  false  "Actor token of the token exchange"
+// @Param        actor_token_type      formData  string  false  "Actor token type"
+// @Param        requested_token_type  formData  string  false  "Requested token type"
+// @Success      200                   {object}  app.TokenResponse
+// @Failure      400                   {object}  app.OAuthError
+// @Failure      401                   {object}  app.OAuthError
+// @Failure      500                   {object}  app.OAuthError
+// @Router       /oauth/token [post]
+func (a *OAuthController) token() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.TokenRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		if err := bindClientCredentials(c, &payload.ClientId, &payload.ClientSecret); err != nil {
+			return err
+		}
+
+		res, err := a.oauthService.Token(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+		c.Response().Header().Set("Pragma", "no-cache")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      UserInfo
+// @Description  OpenID Connect UserInfo endpoint. Returns the claims of the user which the scopes of the access token allow.
+// @Tags         OAuth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  app.UserInfoResponse
+// @Failure      401  {object}  app.OAuthError
+// @Failure      403  {object}  app.OAuthError
+// @Failure      500  {object}  app.OAuthError
+// @Router       /oauth/userinfo [get]
+// @Router       /oauth/userinfo [post]
+func (a *OAuthController) userInfo() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.oauthService.UserInfo(c.Request().Context(), c.Get("claims").(app.Claims))
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Introspect
+// @Description  Token introspection (RFC 7662)
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Security     BasicAuth
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "Token type hint"
+// @Success      200              {object}  app.IntrospectionResponse
+// @Failure      400              {object}  app.OAuthError
+// @Failure      401              {object}  app.OAuthError
+// @Failure      500              {object}  app.OAuthError
+// @Router       /oauth/introspect [post]
+func (a *OAuthController) introspect() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.IntrospectionRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.tokenService.Introspect(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// @Summary      Revoke
+// @Description  Token revocation (RFC 7009)
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "Token type hint"
+// @Success      200
+// @Failure      400  {object}  app.OAuthError
+// @Failure      500  {object}  app.OAuthError
+// @Router       /oauth/revoke [post]
+func (a *OAuthController) revoke() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.RevocationRequest{}
+		if err := (&echo.DefaultBinder{}).BindBody(c, payload); err != nil {
+			return err
+		}
+
+		if err := a.tokenService.Revoke(c.Request().Context(), payload); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// bindClientCredentials reads the client credentials of the basic auth into the
+// request (RFC 6749 section 2.3.1). Only one authentication method is allowed.
+func bindClientCredentials(c echo.Context, clientId *string, clientSecret *string) error {
+	id, secret, ok := c.Request().BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	if *clientSecret != "" {
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "multiple client authentication methods")
+	}
+
+	var err error
+	if *clientId, err = url.QueryUnescape(id); err != nil {
+		return app.NewOAuthError(http.StatusUnauthorized, app.OAuthErrInvalidClient, "")
+	}
+
+	if *clientSecret, err = url.QueryUnescape(secret); err != nil {
+		return app.NewOAuthError(http.StatusUnauthorized, app.OAuthErrInvalidClient, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/server"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type SamlController struct {
+	config      *config.Config
+	logger      logger.ILogger
+	samlService app.SamlService
+}
+
+func NewSamlController(config *config.Config, logger logger.ILogger, s app.SamlService) *SamlController {
+	return &SamlController{
+		samlService: s,
+		logger:      logger,
+		config:      config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *SamlController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.ErrorHandler())
+
+	e.GET("/:connection/metadata/", a.metadata())
+	e.GET("/:connection/login/", a.login())
+	e.POST("/:connection/acs/", a.acs())
+}
+
+// @Summary      SAML Metadata
+// @Description  Returns the metadata of the service provider of the connection to import at the identity provider
+// @Tags         SAML
+// @Produce      xml
+// @Param        connection  path  string  true  "Connection"
+// @Success      200
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /saml/{connection}/metadata [get]
+func (a *SamlController) metadata() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.SamlMetadataRequest{}
+		if err := c.Bind(payload); err != nil {
+			return err
+		}
+
+		a.serviceProvider(c, payload)
+
+		b, err := a.samlService.Metadata(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.Blob(http.StatusOK, "application/samlmetadata+xml", b)
+	}
+}
+
+// @Summary      SAML Login
+// @Description  Redirects the user to the identity provider of the connection
+// @Tags         SAML
+// @Param        connection  path   string  true   "Connection"
+// @Param        audience    query  string  false  "Audience of the issued tokens"
+// @Param        scope       query  string  false  "Scope of the issued tokens"
+// @Success      302
+// @Failure      400  {object}  app.HTTPError
+// @Failure      404  {object}  app.HTTPError
+// @Failure      500  {object}  app.HTTPError
+// @Router       /saml/{connection}/login [get]
+func (a *SamlController) login() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.SamlLoginRequest{}
+		if err := c.Bind(payload); err != nil {
+			return err
+		}
+
+		a.serviceProvider(c, &payload.SamlMetadataRequest)
+
+		u, err := a.samlService.AuthnRequestUrl(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusFound, u)
+	}
+}
+
+// @Summary      SAML Assertion Consumer Service
+// @Description  Validates the response of the identity provider and returns a token pair. The user is linked by the email or created on the first login.
+// @Tags         SAML
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        connection    path      string  true  "Connection"
+// @Param        SAMLResponse  formData  string  true  "Response of the identity provider"
+// @Param        RelayState    formData  string  true  "Relay state"
+// @Success      200           {object}  app.LoginResponse
+// @Failure      400           {object}  app.HTTPError
+// @Failure      401           {object}  app.HTTPError
+// @Failure      403           {object}  app.HTTPError
+// @Failure      404           {object}  app.HTTPError
+// @Failure      409           {object}  app.HTTPError
+// @Failure      500           {object}  app.HTTPError
+// @Router       /saml/{connection}/acs [post]
+func (a *SamlController) acs() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.SamlAcsRequest{}
+		if err := c.Bind(payload); err != nil {
+			return err
+		}
+
+		a.serviceProvider(c, &payload.SamlMetadataRequest)
+
+		res, err := a.samlService.Acs(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// serviceProvider sets the urls of the service provider of the connection
+func (a *SamlController) serviceProvider(c echo.Context, r *app.SamlMetadataRequest) {
+	prefix := BaseUrl(c, a.config) + server.ApiPrefix + "/saml/" + url.PathEscape(r.Connection)
+
+	r.EntityId = prefix + "/metadata"
+	r.AcsUrl = prefix + "/acs"
+}
@@ -0,0 +1,475 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/server"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type ScimController struct {
+	config      *config.Config
+	logger      logger.ILogger
+	scimService app.ScimService
+}
+
+func NewScimController(config *config.Config, logger logger.ILogger, s app.ScimService) *ScimController {
+	return &ScimController{
+		scimService: s,
+		logger:      logger,
+		config:      config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *ScimController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.ScimErrorHandler())
+	e.Use(middleware.ScimAuth(a.scimService))
+
+	e.GET("/ServiceProviderConfig/", a.serviceProviderConfig())
+
+	e.GET("/Users/", a.getUsers())
+	e.POST("/Users/", a.createUser())
+	e.GET("/Users/:id/", a.getUser())
+	e.PUT("/Users/:id/", a.replaceUser())
+	e.PATCH("/Users/:id/", a.patchUser())
+	e.DELETE("/Users/:id/", a.deleteUser())
+
+	e.GET("/Groups/", a.getGroups())
+	e.POST("/Groups/", a.createGroup())
+	e.GET("/Groups/:id/", a.getGroup())
+	e.PUT("/Groups/:id/", a.replaceGroup())
+	e.PATCH("/Groups/:id/", a.patchGroup())
+	e.DELETE("/Groups/:id/", a.deleteGroup())
+}
+
+// @Summary      SCIM Service Provider Config
+// @Description  Returns the features of the SCIM API
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  app.ScimServiceProviderConfig
+// @Failure      401  {object}  app.ScimError
+// @Router       /scim/v2/ServiceProviderConfig [get]
+func (a *ScimController) serviceProviderConfig() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return a.respond(c, http.StatusOK, &app.ScimServiceProviderConfig{
+			Schemas: []string{app.ScimSchemaServiceProviderConfig},
+			Patch:   app.ScimFeature{Supported: true},
+			Filter:  app.ScimFeature{Supported: true, MaxResults: a.config.Scim.MaxResults},
+			AuthenticationSchemes: []app.ScimAuthenticationScheme{{
+				Type:        "oauthbearertoken",
+				Name:        "Bearer Token",
+				Description: "Token of the organization created by an administrator",
+			}},
+		})
+	}
+}
+
+// @Summary      SCIM Get Users
+// @Description  Returns a page of the users of the organization. Only the "eq" operator of userName and externalId is supported in the filter.
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        filter      query     string  false  "Filter"
+// @Param        startIndex  query     int     false  "1-based index of the first result"
+// @Param        count       query     int     false  "Number of the results"
+// @Success      200         {object}  app.ScimListResponse{Resources=[]app.ScimUser}
+// @Failure      400         {object}  app.ScimError
+// @Failure      401         {object}  app.ScimError
+// @Failure      500         {object}  app.ScimError
+// @Router       /scim/v2/Users [get]
+func (a *ScimController) getUsers() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r, err := scimListRequest(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := a.scimService.GetUsers(c.Request().Context(), scimOrganization(c), r)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range res.Resources.([]*app.ScimUser) {
+			u.Meta.Location = a.location(c, "Users", u.Id)
+		}
+
+		return a.respond(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Create User
+// @Description  Provisions a user of the organization. The userName is the email of the user.
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body      app.ScimUser  true  "Payload"
+// @Success      201      {object}  app.ScimUser
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      409      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Users [post]
+func (a *ScimController) createUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimUser{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.CreateUser(c.Request().Context(), scimOrganization(c), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondUser(c, http.StatusCreated, res)
+	}
+}
+
+// @Summary      SCIM Get User
+// @Description  Returns a user of the organization
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User id"
+// @Success      200  {object}  app.ScimUser
+// @Failure      401  {object}  app.ScimError
+// @Failure      404  {object}  app.ScimError
+// @Failure      500  {object}  app.ScimError
+// @Router       /scim/v2/Users/{id} [get]
+func (a *ScimController) getUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.scimService.GetUser(c.Request().Context(), scimOrganization(c), c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		return a.respondUser(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Replace User
+// @Description  Replaces the attributes of a user of the organization. Deactivating the user revokes its tokens.
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string        true  "User id"
+// @Param        payload  body      app.ScimUser  true  "Payload"
+// @Success      200      {object}  app.ScimUser
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      404      {object}  app.ScimError
+// @Failure      409      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Users/{id} [put]
+func (a *ScimController) replaceUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimUser{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.ReplaceUser(c.Request().Context(), scimOrganization(c), c.Param("id"), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondUser(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Patch User
+// @Description  Modifies a user of the organization. Deactivating the user revokes its tokens.
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string                true  "User id"
+// @Param        payload  body      app.ScimPatchRequest  true  "Payload"
+// @Success      200      {object}  app.ScimUser
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      404      {object}  app.ScimError
+// @Failure      409      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Users/{id} [patch]
+func (a *ScimController) patchUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimPatchRequest{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.PatchUser(c.Request().Context(), scimOrganization(c), c.Param("id"), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondUser(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Delete User
+// @Description  Deprovisions a user of the organization
+// @Tags         SCIM
+// @Security     BearerAuth
+// @Param        id  path  string  true  "User id"
+// @Success      204
+// @Failure      401  {object}  app.ScimError
+// @Failure      404  {object}  app.ScimError
+// @Failure      500  {object}  app.ScimError
+// @Router       /scim/v2/Users/{id} [delete]
+func (a *ScimController) deleteUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.scimService.DeleteUser(c.Request().Context(), scimOrganization(c), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// @Summary      SCIM Get Groups
+// @Description  Returns a page of the groups of the organization. Only the "eq" operator of displayName and externalId is supported in the filter.
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        filter      query     string  false  "Filter"
+// @Param        startIndex  query     int     false  "1-based index of the first result"
+// @Param        count       query     int     false  "Number of the results"
+// @Success      200         {object}  app.ScimListResponse{Resources=[]app.ScimGroup}
+// @Failure      400         {object}  app.ScimError
+// @Failure      401         {object}  app.ScimError
+// @Failure      500         {object}  app.ScimError
+// @Router       /scim/v2/Groups [get]
+func (a *ScimController) getGroups() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r, err := scimListRequest(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := a.scimService.GetGroups(c.Request().Context(), scimOrganization(c), r)
+		if err != nil {
+			return err
+		}
+
+		for _, g := range res.Resources.([]*app.ScimGroup) {
+			a.groupLocations(c, g)
+		}
+
+		return a.respond(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Create Group
+// @Description  Creates a group of the users of the organization
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        payload  body      app.ScimGroup  true  "Payload"
+// @Success      201      {object}  app.ScimGroup
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Groups [post]
+func (a *ScimController) createGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimGroup{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.CreateGroup(c.Request().Context(), scimOrganization(c), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondGroup(c, http.StatusCreated, res)
+	}
+}
+
+// @Summary      SCIM Get Group
+// @Description  Returns a group of the organization
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Group id"
+// @Success      200  {object}  app.ScimGroup
+// @Failure      401  {object}  app.ScimError
+// @Failure      404  {object}  app.ScimError
+// @Failure      500  {object}  app.ScimError
+// @Router       /scim/v2/Groups/{id} [get]
+func (a *ScimController) getGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res, err := a.scimService.GetGroup(c.Request().Context(), scimOrganization(c), c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		return a.respondGroup(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Replace Group
+// @Description  Replaces the attributes and the members of a group of the organization
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string         true  "Group id"
+// @Param        payload  body      app.ScimGroup  true  "Payload"
+// @Success      200      {object}  app.ScimGroup
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      404      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Groups/{id} [put]
+func (a *ScimController) replaceGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimGroup{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.ReplaceGroup(c.Request().Context(), scimOrganization(c), c.Param("id"), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondGroup(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Patch Group
+// @Description  Modifies a group of the organization, e.g. adds or removes members
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string                true  "Group id"
+// @Param        payload  body      app.ScimPatchRequest  true  "Payload"
+// @Success      200      {object}  app.ScimGroup
+// @Failure      400      {object}  app.ScimError
+// @Failure      401      {object}  app.ScimError
+// @Failure      404      {object}  app.ScimError
+// @Failure      500      {object}  app.ScimError
+// @Router       /scim/v2/Groups/{id} [patch]
+func (a *ScimController) patchGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		payload := &app.ScimPatchRequest{}
+		if err := decodeScim(c, payload); err != nil {
+			return err
+		}
+
+		res, err := a.scimService.PatchGroup(c.Request().Context(), scimOrganization(c), c.Param("id"), payload)
+		if err != nil {
+			return err
+		}
+
+		return a.respondGroup(c, http.StatusOK, res)
+	}
+}
+
+// @Summary      SCIM Delete Group
+// @Description  Deletes a group of the organization
+// @Tags         SCIM
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Group id"
+// @Success      204
+// @Failure      401  {object}  app.ScimError
+// @Failure      404  {object}  app.ScimError
+// @Failure      500  {object}  app.ScimError
+// @Router       /scim/v2/Groups/{id} [delete]
+func (a *ScimController) deleteGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := a.scimService.DeleteGroup(c.Request().Context(), scimOrganization(c), c.Param("id")); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func (a *ScimController) respondUser(c echo.Context, code int, u *app.ScimUser) error {
+	u.Meta.Location = a.location(c, "Users", u.Id)
+	c.Response().Header().Set(echo.HeaderLocation, u.Meta.Location)
+
+	return a.respond(c, code, u)
+}
+
+func (a *ScimController) respondGroup(c echo.Context, code int, g *app.ScimGroup) error {
+	a.groupLocations(c, g)
+	c.Response().Header().Set(echo.HeaderLocation, g.Meta.Location)
+
+	return a.respond(c, code, g)
+}
+
+// groupLocations sets the urls of the group and its members
+func (a *ScimController) groupLocations(c echo.Context, g *app.ScimGroup) {
+	g.Meta.Location = a.location(c, "Groups", g.Id)
+	for i := range g.Members {
+		g.Members[i].Ref = a.location(c, "Users", g.Members[i].Value)
+	}
+}
+
+// location returns the url of a resource
+func (a *ScimController) location(c echo.Context, resource string, id string) string {
+	return BaseUrl(c, a.config) + server.ApiPrefix + "/scim/v2/" + resource + "/" + url.PathEscape(id)
+}
+
+func (a *ScimController) respond(c echo.Context, code int, v interface{}) error {
+	c.Response().Header().Set(echo.HeaderContentType, app.ScimContentType)
+	return c.JSON(code, v)
+}
+
+// scimOrganization returns the organization of the authenticated token
+func scimOrganization(c echo.Context) string {
+	return c.Get("organization").(string)
+}
+
+// decodeScim decodes the JSON body, since the binder does not accept the SCIM
+// content type
+func decodeScim(c echo.Context, v interface{}) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidSyntax, "invalid JSON body")
+	}
+
+	return nil
+}
+
+// scimListRequest parses the query parameters of the list requests
+func scimListRequest(c echo.Context) (*app.ScimListRequest, error) {
+	r := &app.ScimListRequest{Filter: c.QueryParam("filter")}
+
+	if v := c.QueryParam("startIndex"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "startIndex has to be a number")
+		}
+
+		r.StartIndex = i
+	}
+
+	if v := c.QueryParam("count"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "count has to be a number")
+		}
+
+		r.Count = &i
+	}
+
+	return r, nil
+}
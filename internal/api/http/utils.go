@@ -2,8 +2,10 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
 )
 
@@ -15,3 +17,34 @@ func GetUserId(c echo.Context) (string, error) {
 
 	return claims.(app.Claims).GetSubject(), nil
 }
+
+// BaseUrl returns the external url of the api, which is taken from the
+// request unless it is configured
+func BaseUrl(c echo.Context, config *config.Config) string {
+	if baseUrl := strings.TrimSuffix(config.Oauth.BaseUrl, "/"); baseUrl != "" {
+		return baseUrl
+	}
+
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+// ApiAudience returns the audience of the tokens which the account and admin
+// routes accept. It is empty when no audiences are configured.
+func ApiAudience(config *config.Config) string {
+	if aud := strings.TrimSpace(config.Jwt.ApiAudience); aud != "" {
+		return aud
+	}
+
+	for _, aud := range config.Jwt.Audiences {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			return aud
+		}
+	}
+
+	return ""
+}
+
+// ConfigScopes returns the scopes of the config list without the empty ones
+func ConfigScopes(scopes []string) []string {
+	return app.ParseScope(strings.Join(scopes, " "))
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/api/http/middleware"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/server"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+)
+
+type WellKnownController struct {
+	config       *config.Config
+	logger       logger.ILogger
+	tokenService app.TokenService
+	oauthService app.OAuthService
+}
+
+func NewWellKnownController(config *config.Config, logger logger.ILogger, ts app.TokenService, os app.OAuthService) *WellKnownController {
+	return &WellKnownController{
+		tokenService: ts,
+		oauthService: os,
+		logger:       logger,
+		config:       config,
+	}
+}
+
+// RegisterRoutes registers the routes to the echo server
+func (a *WellKnownController) RegisterRoutes(e *echo.Group) {
+	e.Use(middleware.ErrorHandler())
+
+	e.GET("/jwks.json/", a.jwks())
+	e.GET("/openid-configuration/", a.openIDConfiguration())
+}
+
+// jwks serves the public keys to verify the issued tokens. Consumers are
+// allowed to cache the key set for the configured duration.
+func (a *WellKnownController) jwks() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := a.tokenService.KeySet(c.Request().Context())
+
+		c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", a.config.Jwt.KeySetMaxAge))
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// openIDConfiguration serves the OpenID Connect discovery document
+func (a *WellKnownController) openIDConfiguration() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		baseUrl := BaseUrl(c, a.config)
+
+		api := baseUrl + server.ApiPrefix
+
+		scopes := append([]string{}, app.OpenIdScopes...)
+		for _, s := range append(append([]string{}, a.config.Jwt.Scopes...), a.config.Jwt.AdminScopes...) {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+
+		res := &app.OpenIDConfiguration{
+			Issuer:                                    a.config.Jwt.Issuer,
+			AuthorizationEndpoint:                     api + "/oauth/authorize",
+			DeviceAuthorizationEndpoint:               api + "/oauth/device_authorization",
+			TokenEndpoint:                             api + "/oauth/token",
+			UserinfoEndpoint:                          api + "/oauth/userinfo",
+			JwksUri:                                   baseUrl + "/.well-known/jwks.json",
+			RevocationEndpoint:                        api + "/oauth/revoke",
+			IntrospectionEndpoint:                     api + "/oauth/introspect",
+			ResponseTypesSupported:                    []string{app.ResponseTypeCode},
+			SubjectTypesSupported:                     []string{"public"},
+			IdTokenSigningAlgValuesSupported:          []string{a.config.Jwt.AccessTokenAlgorithm},
+			GrantTypesSupported:                       a.oauthService.GrantTypes(),
+			ScopesSupported:                           scopes,
+			CodeChallengeMethodsSupported:             []string{app.CodeChallengeMethodS256},
+			ClaimsSupported:                           []string{"sub", "iss", "aud", "exp", "iat", "jti", "role", "scope", "token_type", "auth_time", "nonce", "name", "given_name", "family_name", "picture", "email", "email_verified"},
+			TokenEndpointAuthMethodsSupported:         []string{"none", "client_secret_basic", "client_secret_post"},
+			RevocationEndpointAuthMethodsSupported:    []string{"none"},
+			IntrospectionEndpointAuthMethodsSupported: []string{"client_secret_basic"},
+		}
+
+		c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", a.config.Jwt.KeySetMaxAge))
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
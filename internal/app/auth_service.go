@@ -11,4 +11,26 @@ type AuthService interface {
 	Register(ctx context.Context, r *RegisterRequest) (*LoginResponse, error)
 	RefreshToken(ctx context.Context, r *RefreshTokenRequest) (*RefreshTokenResponse, error)
 	Me(ctx context.Context, uid string) (*UserResponse, error)
+	// UpdateProfile updates the fields of the profile which are set in the
+	// request
+	UpdateProfile(ctx context.Context, uid string, r *UpdateProfileRequest) (*UserResponse, error)
+	Logout(ctx context.Context, r *LogoutRequest) error
+	LogoutAll(ctx context.Context, uid string) error
+	// VerifyEmail marks the email of the user as verified with the token sent
+	// to it
+	VerifyEmail(ctx context.Context, r *VerifyEmailRequest) error
+	// ResendVerificationEmail sends a new verification token to the email
+	ResendVerificationEmail(ctx context.Context, r *ResendVerificationEmailRequest) error
+	// ForgotPassword sends a password reset token to the email
+	ForgotPassword(ctx context.Context, r *ForgotPasswordRequest) error
+	// ResetPassword sets the password of the user with the token sent to the
+	// email and revokes all tokens of the user
+	ResetPassword(ctx context.Context, r *ResetPasswordRequest) error
+	// ChangePassword changes the password of the user of the access token. A
+	// new token pair is returned when the other devices are signed out.
+	ChangePassword(ctx context.Context, claims Claims, r *ChangePasswordRequest) (*RefreshTokenResponse, error)
+	// DeleteAccount schedules the deletion of the account of the access token
+	// and revokes all tokens of the user. Logging in before the deletion
+	// cancels it.
+	DeleteAccount(ctx context.Context, claims Claims) (*DeleteAccountResponse, error)
 }
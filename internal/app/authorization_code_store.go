@@ -0,0 +1,16 @@
+//go:generate mockgen -source authorization_code_store.go -destination mock/authorization_code_store_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type AuthorizationCodeStore interface {
+	Save(ctx context.Context, code *model.AuthorizationCode) error
+	// Consume returns the code and removes it, so that a code can be
+	// exchanged only once. It returns ErrAuthorizationCodeNotFound if the
+	// code does not exist or is already consumed.
+	Consume(ctx context.Context, id string) (*model.AuthorizationCode, error)
+}
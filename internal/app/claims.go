@@ -1,7 +1,42 @@
 package app
 
+// Types of the access tokens. Client tokens are issued to the clients with the
+// client credentials grant, and their subject is the client id.
+const (
+	AccessTokenTypeUser   = "user"
+	AccessTokenTypeClient = "client"
+)
+
+// Actor is the party which acts on behalf of the subject of a delegated token
+// (RFC 8693 section 4.1). A nested actor is the party which the actor itself
+// acts on behalf of.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+} // @name Actor
+
 type Claims interface {
 	GetSubject() string
 	GetRole() string
 	GetIssuer() string
+	GetTokenId() string
+	GetAudience() string
+	GetScope() string
+	// GetTokenType returns AccessTokenTypeUser or AccessTokenTypeClient
+	GetTokenType() string
+	// GetExpiresAt returns the expiration time of the token in unix seconds
+	GetExpiresAt() int64
+	// GetActor returns the actor of a delegated token, nil otherwise
+	GetActor() *Actor
+	// GetClientId returns the OAuth client which the token is issued to. It
+	// is empty for the tokens of the first-party apps.
+	GetClientId() string
+	// GetClaim returns a custom claim added by a ClaimsEnricher
+	GetClaim(name string) interface{}
+}
+
+// IsFirstPartyToken returns true if the token is issued to the user itself,
+// neither to an OAuth client nor to an actor on behalf of the user
+func IsFirstPartyToken(c Claims) bool {
+	return c.GetTokenType() == AccessTokenTypeUser && c.GetActor() == nil && c.GetClientId() == ""
 }
@@ -0,0 +1,14 @@
+//go:generate mockgen -source claims_enricher.go -destination mock/claims_enricher_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// ClaimsEnricher adds custom claims of the user to the access tokens. The
+// claims must not collide with the registered claims of the token.
+type ClaimsEnricher interface {
+	EnrichClaims(ctx context.Context, user *model.User) (map[string]interface{}, error)
+}
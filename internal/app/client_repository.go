@@ -0,0 +1,15 @@
+//go:generate mockgen -source client_repository.go -destination mock/client_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type ClientRepository interface {
+	GetClient(ctx context.Context, id string) (*model.OAuthClient, error)
+	GetClients(ctx context.Context) ([]*model.OAuthClient, error)
+	CreateClient(ctx context.Context, client *model.OAuthClient) error
+	DeleteClient(ctx context.Context, id string) error
+}
@@ -0,0 +1,20 @@
+//go:generate mockgen -source client_service.go -destination mock/client_service_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type ClientService interface {
+	// CreateClient registers a client. The secret of a confidential client is
+	// only returned in the response.
+	CreateClient(ctx context.Context, r *CreateClientRequest) (*CreateClientResponse, error)
+	GetClient(ctx context.Context, id string) (*model.OAuthClient, error)
+	// Authenticate returns the client if the secret matches. Public clients
+	// are authenticated by their id only.
+	Authenticate(ctx context.Context, id string, secret string) (*model.OAuthClient, error)
+	GetClients(ctx context.Context) ([]*model.OAuthClient, error)
+	DeleteClient(ctx context.Context, id string) error
+}
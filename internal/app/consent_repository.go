@@ -0,0 +1,19 @@
+//go:generate mockgen -source consent_repository.go -destination mock/consent_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type ConsentRepository interface {
+	GetConsent(ctx context.Context, userId string, clientId string) (*model.Consent, error)
+	GetConsents(ctx context.Context, userId string) ([]*model.Consent, error)
+	// GrantConsent adds the scopes to the consent of the user for the client,
+	// creating it on the first grant
+	GrantConsent(ctx context.Context, userId string, clientId string, scopes []string) error
+	// TouchConsent updates the last use of the consent
+	TouchConsent(ctx context.Context, userId string, clientId string) error
+	DeleteConsent(ctx context.Context, userId string, clientId string) error
+}
@@ -0,0 +1,14 @@
+//go:generate mockgen -source consent_service.go -destination mock/consent_service_mock.go -package mock
+package app
+
+import (
+	"context"
+)
+
+type ConsentService interface {
+	// GetApplications returns the clients which the user granted access to
+	GetApplications(ctx context.Context, uid string) ([]*ApplicationResponse, error)
+	// RevokeApplication revokes the refresh tokens issued to the client and
+	// deletes the consent of the user for the client if there is one
+	RevokeApplication(ctx context.Context, uid string, clientId string) error
+}
@@ -0,0 +1,26 @@
+//go:generate mockgen -source device_authorization_store.go -destination mock/device_authorization_store_mock.go -package mock
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type DeviceAuthorizationStore interface {
+	// Save stores the request. It returns ErrUserCodeExists if the user code
+	// is taken by another request.
+	Save(ctx context.Context, d *model.DeviceAuthorization) error
+	// Poll records the poll of the device and returns the request as it was
+	// before the poll, so that too frequent polls can be detected
+	Poll(ctx context.Context, id string, at time.Time) (*model.DeviceAuthorization, error)
+	// GetByUserCode returns the pending request of the user code
+	GetByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error)
+	// Decide approves or denies the pending request of the user code. It
+	// returns ErrDeviceAuthorizationNotFound if the request is not pending.
+	Decide(ctx context.Context, userCode string, status string, userId string, grant *TokenGrant) error
+	// Delete removes the request. It returns ErrDeviceAuthorizationNotFound
+	// if the request is already removed, so that tokens are issued once.
+	Delete(ctx context.Context, id string) error
+}
@@ -4,15 +4,134 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
 var (
 	ErrInvalidRequest = errors.New("invalid request")
 	ErrUserNotFound   = errors.New("user not found")
 	ErrInvalidToken   = errors.New("invalid token")
+	ErrTokenRevoked   = errors.New("token revoked")
 	ErrInvalidUserId  = errors.New("invalid user id")
+	ErrUserDisabled   = NewError(http.StatusForbidden, errors.New("user is disabled"))
+
+	ErrEmailNotVerified          = NewError(http.StatusForbidden, errors.New("email is not verified"))
+	ErrVerificationTokenNotFound = NewError(http.StatusBadRequest, errors.New("invalid or expired token"))
+	ErrInvalidCurrentPassword    = NewBadRequestError(errors.New("current password is invalid"))
+	ErrUserTokenRequired         = NewError(http.StatusForbidden, errors.New("a first-party token of the user is required"))
+	ErrAccountManaged            = NewError(http.StatusForbidden, errors.New("account is managed by the organization"))
+
+	ErrInvalidScope    = NewBadRequestError(errors.New("invalid scope"))
+	ErrInvalidAudience = NewBadRequestError(errors.New("invalid audience"))
+
+	ErrClientNotFound            = NewError(http.StatusNotFound, errors.New("client not found"))
+	ErrInvalidClient             = NewError(http.StatusUnauthorized, errors.New("invalid client credentials"))
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+	ErrConsentNotFound           = NewError(http.StatusNotFound, errors.New("application not found"))
+
+	ErrDeviceAuthorizationNotFound = errors.New("device authorization not found")
+	ErrUserCodeExists              = errors.New("user code already exists")
+
+	ErrFederationProviderNotFound = NewError(http.StatusNotFound, errors.New("provider not found"))
+	ErrFederatedLoginNotFound     = NewError(http.StatusBadRequest, errors.New("invalid or expired login state"))
+	ErrFederatedLoginFailed       = NewError(http.StatusUnauthorized, errors.New("failed to login with the provider"))
+	ErrFederatedEmailNotVerified  = NewError(http.StatusForbidden, errors.New("email is not verified by the provider"))
+	ErrIdentityAlreadyLinked      = NewError(http.StatusConflict, errors.New("an identity of the provider is already linked to the user"))
+	ErrLinkedEmailNotVerified     = NewError(http.StatusConflict, errors.New("an account with the email exists, verify the email of the account to login with the provider"))
+
+	ErrSamlConnectionNotFound    = NewError(http.StatusNotFound, errors.New("saml connection not found"))
+	ErrSamlConnectionExists      = NewError(http.StatusConflict, errors.New("saml connection already exists"))
+	ErrSamlEmailDomainNotAllowed = NewError(http.StatusForbidden, errors.New("email domain is not allowed for the organization"))
+	ErrSamlUserOfAnotherOrg      = NewError(http.StatusForbidden, errors.New("user does not belong to the organization"))
+
+	ErrScimTokenNotFound = NewError(http.StatusNotFound, errors.New("scim token not found"))
+	ErrInvalidScimToken  = NewError(http.StatusUnauthorized, errors.New("invalid scim token"))
+	ErrScimGroupNotFound = NewError(http.StatusNotFound, errors.New("group not found"))
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenRevoked  = errors.New("refresh token revoked")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
+)
+
+// OAuth error codes of RFC 6749
+const (
+	OAuthErrInvalidRequest       = "invalid_request"
+	OAuthErrInvalidClient        = "invalid_client"
+	OAuthErrUnauthorizedClient   = "unauthorized_client"
+	OAuthErrAccessDenied         = "access_denied"
+	OAuthErrUnsupportedResponse  = "unsupported_response_type"
+	OAuthErrInvalidGrant         = "invalid_grant"
+	OAuthErrInvalidScope         = "invalid_scope"
+	OAuthErrInvalidTarget        = "invalid_target"
+	OAuthErrUnsupportedGrantType = "unsupported_grant_type"
+	OAuthErrServerError          = "server_error"
+	OAuthErrAuthorizationPending = "authorization_pending"
+	OAuthErrSlowDown             = "slow_down"
+	OAuthErrExpiredToken         = "expired_token"
+	OAuthErrInsufficientScope    = "insufficient_scope"
+)
+
+// OAuthError is an error response of RFC 6749
+type OAuthError struct {
+	Code        int    `json:"-"`
+	Err         string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+} // @name OAuthError
+
+func NewOAuthError(code int, err string, description string) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Err:         err,
+		Description: description,
+	}
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Err
+	}
+
+	return e.Err + ": " + e.Description
+}
+
+// SCIM error types of RFC 7644
+const (
+	ScimErrInvalidFilter = "invalidFilter"
+	ScimErrInvalidValue  = "invalidValue"
+	ScimErrInvalidPath   = "invalidPath"
+	ScimErrUniqueness    = "uniqueness"
+	ScimErrMutability    = "mutability"
+	ScimErrInvalidSyntax = "invalidSyntax"
+	ScimErrNoTarget      = "noTarget"
 )
 
+// ScimError is an error response of RFC 7644
+type ScimError struct {
+	Code     int      `json:"-"`
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+} // @name ScimError
+
+func NewScimError(code int, scimType string, detail string) *ScimError {
+	return &ScimError{
+		Code:     code,
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *ScimError) Error() string {
+	if e.ScimType == "" {
+		return e.Detail
+	}
+
+	return e.ScimType + ": " + e.Detail
+}
+
 type Error struct {
 	code int
 	err  error
@@ -0,0 +1,16 @@
+//go:generate mockgen -source federated_login_store.go -destination mock/federated_login_store_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type FederatedLoginStore interface {
+	Save(ctx context.Context, login *model.FederatedLogin) error
+	// Consume returns the login and removes it, so that a callback can be
+	// completed only once. It returns ErrFederatedLoginNotFound if the login
+	// does not exist or is already consumed.
+	Consume(ctx context.Context, id string) (*model.FederatedLogin, error)
+}
@@ -0,0 +1,17 @@
+//go:generate mockgen -source federation_service.go -destination mock/federation_service_mock.go -package mock
+package app
+
+import (
+	"context"
+)
+
+type FederationService interface {
+	// Providers returns the names of the upstream providers
+	Providers() []string
+	// AuthorizationUrl starts a login at the provider and returns the url to
+	// redirect the user to
+	AuthorizationUrl(ctx context.Context, r *FederatedLoginRequest) (string, error)
+	// Callback completes the login with the response of the provider. The
+	// user is linked by the verified email or created on the first login.
+	Callback(ctx context.Context, r *FederatedCallbackRequest) (*LoginResponse, error)
+}
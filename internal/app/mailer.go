@@ -0,0 +1,18 @@
+//go:generate mockgen -source mailer.go -destination mock/mailer_mock.go -package mock
+package app
+
+import (
+	"context"
+)
+
+// MailMessage is a plain text email
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	// Send delivers the message to its recipient
+	Send(ctx context.Context, m *MailMessage) error
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: authorization_code_store.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockAuthorizationCodeStore is a mock of AuthorizationCodeStore interface.
+type MockAuthorizationCodeStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorizationCodeStoreMockRecorder
+}
+
+// MockAuthorizationCodeStoreMockRecorder is the mock recorder for MockAuthorizationCodeStore.
+type MockAuthorizationCodeStoreMockRecorder struct {
+	mock *MockAuthorizationCodeStore
+}
+
+// NewMockAuthorizationCodeStore creates a new mock instance.
+func NewMockAuthorizationCodeStore(ctrl *gomock.Controller) *MockAuthorizationCodeStore {
+	mock := &MockAuthorizationCodeStore{ctrl: ctrl}
+	mock.recorder = &MockAuthorizationCodeStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorizationCodeStore) EXPECT() *MockAuthorizationCodeStoreMockRecorder {
+	return m.recorder
+}
+
+// Consume mocks base method.
+func (m *MockAuthorizationCodeStore) Consume(ctx context.Context, id string) (*model.AuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, id)
+	ret0, _ := ret[0].(*model.AuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockAuthorizationCodeStoreMockRecorder) Consume(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockAuthorizationCodeStore)(nil).Consume), ctx, id)
+}
+
+// Save mocks base method.
+func (m *MockAuthorizationCodeStore) Save(ctx context.Context, code *model.AuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockAuthorizationCodeStoreMockRecorder) Save(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAuthorizationCodeStore)(nil).Save), ctx, code)
+}
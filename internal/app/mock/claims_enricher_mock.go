@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: claims_enricher.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockClaimsEnricher is a mock of ClaimsEnricher interface.
+type MockClaimsEnricher struct {
+	ctrl     *gomock.Controller
+	recorder *MockClaimsEnricherMockRecorder
+}
+
+// MockClaimsEnricherMockRecorder is the mock recorder for MockClaimsEnricher.
+type MockClaimsEnricherMockRecorder struct {
+	mock *MockClaimsEnricher
+}
+
+// NewMockClaimsEnricher creates a new mock instance.
+func NewMockClaimsEnricher(ctrl *gomock.Controller) *MockClaimsEnricher {
+	mock := &MockClaimsEnricher{ctrl: ctrl}
+	mock.recorder = &MockClaimsEnricherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClaimsEnricher) EXPECT() *MockClaimsEnricherMockRecorder {
+	return m.recorder
+}
+
+// EnrichClaims mocks base method.
+func (m *MockClaimsEnricher) EnrichClaims(ctx context.Context, user *model.User) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrichClaims", ctx, user)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrichClaims indicates an expected call of EnrichClaims.
+func (mr *MockClaimsEnricherMockRecorder) EnrichClaims(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrichClaims", reflect.TypeOf((*MockClaimsEnricher)(nil).EnrichClaims), ctx, user)
+}
@@ -0,0 +1,108 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: consent_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockConsentRepository is a mock of ConsentRepository interface.
+type MockConsentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockConsentRepositoryMockRecorder
+}
+
+// MockConsentRepositoryMockRecorder is the mock recorder for MockConsentRepository.
+type MockConsentRepositoryMockRecorder struct {
+	mock *MockConsentRepository
+}
+
+// NewMockConsentRepository creates a new mock instance.
+func NewMockConsentRepository(ctrl *gomock.Controller) *MockConsentRepository {
+	mock := &MockConsentRepository{ctrl: ctrl}
+	mock.recorder = &MockConsentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConsentRepository) EXPECT() *MockConsentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteConsent mocks base method.
+func (m *MockConsentRepository) DeleteConsent(ctx context.Context, userId, clientId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConsent", ctx, userId, clientId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConsent indicates an expected call of DeleteConsent.
+func (mr *MockConsentRepositoryMockRecorder) DeleteConsent(ctx, userId, clientId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConsent", reflect.TypeOf((*MockConsentRepository)(nil).DeleteConsent), ctx, userId, clientId)
+}
+
+// GetConsent mocks base method.
+func (m *MockConsentRepository) GetConsent(ctx context.Context, userId, clientId string) (*model.Consent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsent", ctx, userId, clientId)
+	ret0, _ := ret[0].(*model.Consent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsent indicates an expected call of GetConsent.
+func (mr *MockConsentRepositoryMockRecorder) GetConsent(ctx, userId, clientId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsent", reflect.TypeOf((*MockConsentRepository)(nil).GetConsent), ctx, userId, clientId)
+}
+
+// GetConsents mocks base method.
+func (m *MockConsentRepository) GetConsents(ctx context.Context, userId string) ([]*model.Consent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsents", ctx, userId)
+	ret0, _ := ret[0].([]*model.Consent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsents indicates an expected call of GetConsents.
+func (mr *MockConsentRepositoryMockRecorder) GetConsents(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsents", reflect.TypeOf((*MockConsentRepository)(nil).GetConsents), ctx, userId)
+}
+
+// GrantConsent mocks base method.
+func (m *MockConsentRepository) GrantConsent(ctx context.Context, userId, clientId string, scopes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantConsent", ctx, userId, clientId, scopes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantConsent indicates an expected call of GrantConsent.
+func (mr *MockConsentRepositoryMockRecorder) GrantConsent(ctx, userId, clientId, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantConsent", reflect.TypeOf((*MockConsentRepository)(nil).GrantConsent), ctx, userId, clientId, scopes)
+}
+
+// TouchConsent mocks base method.
+func (m *MockConsentRepository) TouchConsent(ctx context.Context, userId, clientId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchConsent", ctx, userId, clientId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchConsent indicates an expected call of TouchConsent.
+func (mr *MockConsentRepositoryMockRecorder) TouchConsent(ctx, userId, clientId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchConsent", reflect.TypeOf((*MockConsentRepository)(nil).TouchConsent), ctx, userId, clientId)
+}
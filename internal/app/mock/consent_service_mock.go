@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: consent_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// MockConsentService is a mock of ConsentService interface.
+type MockConsentService struct {
+	ctrl     *gomock.Controller
+	recorder *MockConsentServiceMockRecorder
+}
+
+// MockConsentServiceMockRecorder is the mock recorder for MockConsentService.
+type MockConsentServiceMockRecorder struct {
+	mock *MockConsentService
+}
+
+// NewMockConsentService creates a new mock instance.
+func NewMockConsentService(ctrl *gomock.Controller) *MockConsentService {
+	mock := &MockConsentService{ctrl: ctrl}
+	mock.recorder = &MockConsentServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConsentService) EXPECT() *MockConsentServiceMockRecorder {
+	return m.recorder
+}
+
+// GetApplications mocks base method.
+func (m *MockConsentService) GetApplications(ctx context.Context, uid string) ([]*app.ApplicationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplications", ctx, uid)
+	ret0, _ := ret[0].([]*app.ApplicationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplications indicates an expected call of GetApplications.
+func (mr *MockConsentServiceMockRecorder) GetApplications(ctx, uid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplications", reflect.TypeOf((*MockConsentService)(nil).GetApplications), ctx, uid)
+}
+
+// RevokeApplication mocks base method.
+func (m *MockConsentService) RevokeApplication(ctx context.Context, uid, clientId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeApplication", ctx, uid, clientId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeApplication indicates an expected call of RevokeApplication.
+func (mr *MockConsentServiceMockRecorder) RevokeApplication(ctx, uid, clientId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeApplication", reflect.TypeOf((*MockConsentService)(nil).RevokeApplication), ctx, uid, clientId)
+}
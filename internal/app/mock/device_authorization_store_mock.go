@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: device_authorization_store.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockDeviceAuthorizationStore is a mock of DeviceAuthorizationStore interface.
+type MockDeviceAuthorizationStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeviceAuthorizationStoreMockRecorder
+}
+
+// MockDeviceAuthorizationStoreMockRecorder is the mock recorder for MockDeviceAuthorizationStore.
+type MockDeviceAuthorizationStoreMockRecorder struct {
+	mock *MockDeviceAuthorizationStore
+}
+
+// NewMockDeviceAuthorizationStore creates a new mock instance.
+func NewMockDeviceAuthorizationStore(ctrl *gomock.Controller) *MockDeviceAuthorizationStore {
+	mock := &MockDeviceAuthorizationStore{ctrl: ctrl}
+	mock.recorder = &MockDeviceAuthorizationStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeviceAuthorizationStore) EXPECT() *MockDeviceAuthorizationStoreMockRecorder {
+	return m.recorder
+}
+
+// Decide mocks base method.
+func (m *MockDeviceAuthorizationStore) Decide(ctx context.Context, userCode, status, userId string, grant *app.TokenGrant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decide", ctx, userCode, status, userId, grant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Decide indicates an expected call of Decide.
+func (mr *MockDeviceAuthorizationStoreMockRecorder) Decide(ctx, userCode, status, userId, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decide", reflect.TypeOf((*MockDeviceAuthorizationStore)(nil).Decide), ctx, userCode, status, userId, grant)
+}
+
+// Delete mocks base method.
+func (m *MockDeviceAuthorizationStore) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDeviceAuthorizationStoreMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDeviceAuthorizationStore)(nil).Delete), ctx, id)
+}
+
+// GetByUserCode mocks base method.
+func (m *MockDeviceAuthorizationStore) GetByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserCode", ctx, userCode)
+	ret0, _ := ret[0].(*model.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserCode indicates an expected call of GetByUserCode.
+func (mr *MockDeviceAuthorizationStoreMockRecorder) GetByUserCode(ctx, userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserCode", reflect.TypeOf((*MockDeviceAuthorizationStore)(nil).GetByUserCode), ctx, userCode)
+}
+
+// Poll mocks base method.
+func (m *MockDeviceAuthorizationStore) Poll(ctx context.Context, id string, at time.Time) (*model.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Poll", ctx, id, at)
+	ret0, _ := ret[0].(*model.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Poll indicates an expected call of Poll.
+func (mr *MockDeviceAuthorizationStoreMockRecorder) Poll(ctx, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Poll", reflect.TypeOf((*MockDeviceAuthorizationStore)(nil).Poll), ctx, id, at)
+}
+
+// Save mocks base method.
+func (m *MockDeviceAuthorizationStore) Save(ctx context.Context, d *model.DeviceAuthorization) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockDeviceAuthorizationStoreMockRecorder) Save(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockDeviceAuthorizationStore)(nil).Save), ctx, d)
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: federated_login_store.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockFederatedLoginStore is a mock of FederatedLoginStore interface.
+type MockFederatedLoginStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockFederatedLoginStoreMockRecorder
+}
+
+// MockFederatedLoginStoreMockRecorder is the mock recorder for MockFederatedLoginStore.
+type MockFederatedLoginStoreMockRecorder struct {
+	mock *MockFederatedLoginStore
+}
+
+// NewMockFederatedLoginStore creates a new mock instance.
+func NewMockFederatedLoginStore(ctrl *gomock.Controller) *MockFederatedLoginStore {
+	mock := &MockFederatedLoginStore{ctrl: ctrl}
+	mock.recorder = &MockFederatedLoginStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFederatedLoginStore) EXPECT() *MockFederatedLoginStoreMockRecorder {
+	return m.recorder
+}
+
+// Consume mocks base method.
+func (m *MockFederatedLoginStore) Consume(ctx context.Context, id string) (*model.FederatedLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, id)
+	ret0, _ := ret[0].(*model.FederatedLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockFederatedLoginStoreMockRecorder) Consume(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockFederatedLoginStore)(nil).Consume), ctx, id)
+}
+
+// Save mocks base method.
+func (m *MockFederatedLoginStore) Save(ctx context.Context, login *model.FederatedLogin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, login)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockFederatedLoginStoreMockRecorder) Save(ctx, login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockFederatedLoginStore)(nil).Save), ctx, login)
+}
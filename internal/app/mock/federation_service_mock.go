@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: federation_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// MockFederationService is a mock of FederationService interface.
+type MockFederationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockFederationServiceMockRecorder
+}
+
+// MockFederationServiceMockRecorder is the mock recorder for MockFederationService.
+type MockFederationServiceMockRecorder struct {
+	mock *MockFederationService
+}
+
+// NewMockFederationService creates a new mock instance.
+func NewMockFederationService(ctrl *gomock.Controller) *MockFederationService {
+	mock := &MockFederationService{ctrl: ctrl}
+	mock.recorder = &MockFederationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFederationService) EXPECT() *MockFederationServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthorizationUrl mocks base method.
+func (m *MockFederationService) AuthorizationUrl(ctx context.Context, r *app.FederatedLoginRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizationUrl", ctx, r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizationUrl indicates an expected call of AuthorizationUrl.
+func (mr *MockFederationServiceMockRecorder) AuthorizationUrl(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizationUrl", reflect.TypeOf((*MockFederationService)(nil).AuthorizationUrl), ctx, r)
+}
+
+// Callback mocks base method.
+func (m *MockFederationService) Callback(ctx context.Context, r *app.FederatedCallbackRequest) (*app.LoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Callback", ctx, r)
+	ret0, _ := ret[0].(*app.LoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Callback indicates an expected call of Callback.
+func (mr *MockFederationServiceMockRecorder) Callback(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Callback", reflect.TypeOf((*MockFederationService)(nil).Callback), ctx, r)
+}
+
+// Providers mocks base method.
+func (m *MockFederationService) Providers() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Providers")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Providers indicates an expected call of Providers.
+func (mr *MockFederationServiceMockRecorder) Providers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Providers", reflect.TypeOf((*MockFederationService)(nil).Providers))
+}
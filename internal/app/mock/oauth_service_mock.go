@@ -0,0 +1,168 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// MockOAuthService is a mock of OAuthService interface.
+type MockOAuthService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthServiceMockRecorder
+}
+
+// MockOAuthServiceMockRecorder is the mock recorder for MockOAuthService.
+type MockOAuthServiceMockRecorder struct {
+	mock *MockOAuthService
+}
+
+// NewMockOAuthService creates a new mock instance.
+func NewMockOAuthService(ctrl *gomock.Controller) *MockOAuthService {
+	mock := &MockOAuthService{ctrl: ctrl}
+	mock.recorder = &MockOAuthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthService) EXPECT() *MockOAuthServiceMockRecorder {
+	return m.recorder
+}
+
+// Authorize mocks base method.
+func (m *MockOAuthService) Authorize(ctx context.Context, uid string, r *app.AuthorizeRequest) (*app.AuthorizeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, uid, r)
+	ret0, _ := ret[0].(*app.AuthorizeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockOAuthServiceMockRecorder) Authorize(ctx, uid, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockOAuthService)(nil).Authorize), ctx, uid, r)
+}
+
+// Consent mocks base method.
+func (m *MockOAuthService) Consent(ctx context.Context, uid string, r *app.AuthorizeRequest) (*app.ConsentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consent", ctx, uid, r)
+	ret0, _ := ret[0].(*app.ConsentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consent indicates an expected call of Consent.
+func (mr *MockOAuthServiceMockRecorder) Consent(ctx, uid, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consent", reflect.TypeOf((*MockOAuthService)(nil).Consent), ctx, uid, r)
+}
+
+// DecideDeviceAuthorization mocks base method.
+func (m *MockOAuthService) DecideDeviceAuthorization(ctx context.Context, uid string, r *app.DeviceDecisionRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecideDeviceAuthorization", ctx, uid, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecideDeviceAuthorization indicates an expected call of DecideDeviceAuthorization.
+func (mr *MockOAuthServiceMockRecorder) DecideDeviceAuthorization(ctx, uid, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecideDeviceAuthorization", reflect.TypeOf((*MockOAuthService)(nil).DecideDeviceAuthorization), ctx, uid, r)
+}
+
+// DeviceAuthorization mocks base method.
+func (m *MockOAuthService) DeviceAuthorization(ctx context.Context, r *app.DeviceAuthorizationRequest) (*app.DeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceAuthorization", ctx, r)
+	ret0, _ := ret[0].(*app.DeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeviceAuthorization indicates an expected call of DeviceAuthorization.
+func (mr *MockOAuthServiceMockRecorder) DeviceAuthorization(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceAuthorization", reflect.TypeOf((*MockOAuthService)(nil).DeviceAuthorization), ctx, r)
+}
+
+// GetDeviceAuthorization mocks base method.
+func (m *MockOAuthService) GetDeviceAuthorization(ctx context.Context, userCode string) (*app.DeviceVerificationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAuthorization", ctx, userCode)
+	ret0, _ := ret[0].(*app.DeviceVerificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAuthorization indicates an expected call of GetDeviceAuthorization.
+func (mr *MockOAuthServiceMockRecorder) GetDeviceAuthorization(ctx, userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAuthorization", reflect.TypeOf((*MockOAuthService)(nil).GetDeviceAuthorization), ctx, userCode)
+}
+
+// GrantTypes mocks base method.
+func (m *MockOAuthService) GrantTypes() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantTypes")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GrantTypes indicates an expected call of GrantTypes.
+func (mr *MockOAuthServiceMockRecorder) GrantTypes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantTypes", reflect.TypeOf((*MockOAuthService)(nil).GrantTypes))
+}
+
+// Token mocks base method.
+func (m *MockOAuthService) Token(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", ctx, r)
+	ret0, _ := ret[0].(*app.TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockOAuthServiceMockRecorder) Token(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockOAuthService)(nil).Token), ctx, r)
+}
+
+// UserInfo mocks base method.
+func (m *MockOAuthService) UserInfo(ctx context.Context, claims app.Claims) (*app.UserInfoResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserInfo", ctx, claims)
+	ret0, _ := ret[0].(*app.UserInfoResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserInfo indicates an expected call of UserInfo.
+func (mr *MockOAuthServiceMockRecorder) UserInfo(ctx, claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserInfo", reflect.TypeOf((*MockOAuthService)(nil).UserInfo), ctx, claims)
+}
+
+// ValidateAuthorizeRequest mocks base method.
+func (m *MockOAuthService) ValidateAuthorizeRequest(ctx context.Context, r *app.AuthorizeRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAuthorizeRequest", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateAuthorizeRequest indicates an expected call of ValidateAuthorizeRequest.
+func (mr *MockOAuthServiceMockRecorder) ValidateAuthorizeRequest(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAuthorizeRequest", reflect.TypeOf((*MockOAuthService)(nil).ValidateAuthorizeRequest), ctx, r)
+}
@@ -7,8 +7,10 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
 	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
 )
 
@@ -35,6 +37,20 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AnonymizeUser mocks base method.
+func (m *MockRepository) AnonymizeUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnonymizeUser indicates an expected call of AnonymizeUser.
+func (mr *MockRepositoryMockRecorder) AnonymizeUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeUser", reflect.TypeOf((*MockRepository)(nil).AnonymizeUser), ctx, id)
+}
+
 // CreateUser mocks base method.
 func (m *MockRepository) CreateUser(ctx context.Context, user *model.User) (string, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +80,22 @@ func (mr *MockRepositoryMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockRepository)(nil).DeleteUser), ctx, id)
 }
 
+// FindUsers mocks base method.
+func (m *MockRepository) FindUsers(ctx context.Context, q *app.UserQuery) ([]*model.User, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", ctx, q)
+	ret0, _ := ret[0].([]*model.User)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockRepositoryMockRecorder) FindUsers(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockRepository)(nil).FindUsers), ctx, q)
+}
+
 // GetUser mocks base method.
 func (m *MockRepository) GetUser(ctx context.Context, id string) (*model.User, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +126,21 @@ func (mr *MockRepositoryMockRecorder) GetUserByEmail(ctx, email interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockRepository)(nil).GetUserByEmail), ctx, email)
 }
 
+// GetUserByIdentity mocks base method.
+func (m *MockRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockRepositoryMockRecorder) GetUserByIdentity(ctx, provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockRepository)(nil).GetUserByIdentity), ctx, provider, subject)
+}
+
 // GetUsersByIds mocks base method.
 func (m *MockRepository) GetUsersByIds(ctx context.Context, ids []string) ([]*model.User, error) {
 	m.ctrl.T.Helper()
@@ -109,16 +156,129 @@ func (mr *MockRepositoryMockRecorder) GetUsersByIds(ctx, ids interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIds", reflect.TypeOf((*MockRepository)(nil).GetUsersByIds), ctx, ids)
 }
 
-// UpdateUser mocks base method.
-func (m *MockRepository) UpdateUser(ctx context.Context, id string, user *model.User) error {
+// GetUsersToDelete mocks base method.
+func (m *MockRepository) GetUsersToDelete(ctx context.Context, at time.Time, limit int) ([]*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersToDelete", ctx, at, limit)
+	ret0, _ := ret[0].([]*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersToDelete indicates an expected call of GetUsersToDelete.
+func (mr *MockRepositoryMockRecorder) GetUsersToDelete(ctx, at, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersToDelete", reflect.TypeOf((*MockRepository)(nil).GetUsersToDelete), ctx, at, limit)
+}
+
+// IncrementTokenGeneration mocks base method.
+func (m *MockRepository) IncrementTokenGeneration(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementTokenGeneration", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementTokenGeneration indicates an expected call of IncrementTokenGeneration.
+func (mr *MockRepositoryMockRecorder) IncrementTokenGeneration(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementTokenGeneration", reflect.TypeOf((*MockRepository)(nil).IncrementTokenGeneration), ctx, id)
+}
+
+// LinkIdentity mocks base method.
+func (m *MockRepository) LinkIdentity(ctx context.Context, id string, identity *model.ExternalIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, id, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockRepositoryMockRecorder) LinkIdentity(ctx, id, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockRepository)(nil).LinkIdentity), ctx, id, identity)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockRepository) ScheduleDeletion(ctx context.Context, id string, at *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", ctx, id, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockRepositoryMockRecorder) ScheduleDeletion(ctx, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockRepository)(nil).ScheduleDeletion), ctx, id, at)
+}
+
+// SetPassword mocks base method.
+func (m *MockRepository) SetPassword(ctx context.Context, id, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockRepositoryMockRecorder) SetPassword(ctx, id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockRepository)(nil).SetPassword), ctx, id, password)
+}
+
+// SetUserDisabled mocks base method.
+func (m *MockRepository) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDisabled", ctx, id, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDisabled indicates an expected call of SetUserDisabled.
+func (mr *MockRepositoryMockRecorder) SetUserDisabled(ctx, id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDisabled", reflect.TypeOf((*MockRepository)(nil).SetUserDisabled), ctx, id, disabled)
+}
+
+// UpdateDirectoryUser mocks base method.
+func (m *MockRepository) UpdateDirectoryUser(ctx context.Context, id string, user *model.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDirectoryUser", ctx, id, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDirectoryUser indicates an expected call of UpdateDirectoryUser.
+func (mr *MockRepositoryMockRecorder) UpdateDirectoryUser(ctx, id, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDirectoryUser", reflect.TypeOf((*MockRepository)(nil).UpdateDirectoryUser), ctx, id, user)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockRepository) UpdateProfile(ctx context.Context, id string, user *model.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, id, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockRepositoryMockRecorder) UpdateProfile(ctx, id, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockRepository)(nil).UpdateProfile), ctx, id, user)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockRepository) VerifyEmail(ctx context.Context, id, email string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", ctx, id, user)
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, id, email)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockRepositoryMockRecorder) UpdateUser(ctx, id, user interface{}) *gomock.Call {
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockRepositoryMockRecorder) VerifyEmail(ctx, id, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepository)(nil).UpdateUser), ctx, id, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockRepository)(nil).VerifyEmail), ctx, id, email)
 }
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: saml_connection_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockSamlConnectionRepository is a mock of SamlConnectionRepository interface.
+type MockSamlConnectionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSamlConnectionRepositoryMockRecorder
+}
+
+// MockSamlConnectionRepositoryMockRecorder is the mock recorder for MockSamlConnectionRepository.
+type MockSamlConnectionRepositoryMockRecorder struct {
+	mock *MockSamlConnectionRepository
+}
+
+// NewMockSamlConnectionRepository creates a new mock instance.
+func NewMockSamlConnectionRepository(ctrl *gomock.Controller) *MockSamlConnectionRepository {
+	mock := &MockSamlConnectionRepository{ctrl: ctrl}
+	mock.recorder = &MockSamlConnectionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSamlConnectionRepository) EXPECT() *MockSamlConnectionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateConnection mocks base method.
+func (m *MockSamlConnectionRepository) CreateConnection(ctx context.Context, connection *model.SamlConnection) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConnection", ctx, connection)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateConnection indicates an expected call of CreateConnection.
+func (mr *MockSamlConnectionRepositoryMockRecorder) CreateConnection(ctx, connection interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConnection", reflect.TypeOf((*MockSamlConnectionRepository)(nil).CreateConnection), ctx, connection)
+}
+
+// DeleteConnection mocks base method.
+func (m *MockSamlConnectionRepository) DeleteConnection(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConnection", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConnection indicates an expected call of DeleteConnection.
+func (mr *MockSamlConnectionRepositoryMockRecorder) DeleteConnection(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConnection", reflect.TypeOf((*MockSamlConnectionRepository)(nil).DeleteConnection), ctx, id)
+}
+
+// GetConnection mocks base method.
+func (m *MockSamlConnectionRepository) GetConnection(ctx context.Context, id string) (*model.SamlConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnection", ctx, id)
+	ret0, _ := ret[0].(*model.SamlConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConnection indicates an expected call of GetConnection.
+func (mr *MockSamlConnectionRepositoryMockRecorder) GetConnection(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnection", reflect.TypeOf((*MockSamlConnectionRepository)(nil).GetConnection), ctx, id)
+}
+
+// GetConnections mocks base method.
+func (m *MockSamlConnectionRepository) GetConnections(ctx context.Context) ([]*model.SamlConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnections", ctx)
+	ret0, _ := ret[0].([]*model.SamlConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConnections indicates an expected call of GetConnections.
+func (mr *MockSamlConnectionRepositoryMockRecorder) GetConnections(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnections", reflect.TypeOf((*MockSamlConnectionRepository)(nil).GetConnections), ctx)
+}
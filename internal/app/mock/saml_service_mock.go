@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: saml_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockSamlService is a mock of SamlService interface.
+type MockSamlService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSamlServiceMockRecorder
+}
+
+// MockSamlServiceMockRecorder is the mock recorder for MockSamlService.
+type MockSamlServiceMockRecorder struct {
+	mock *MockSamlService
+}
+
+// NewMockSamlService creates a new mock instance.
+func NewMockSamlService(ctrl *gomock.Controller) *MockSamlService {
+	mock := &MockSamlService{ctrl: ctrl}
+	mock.recorder = &MockSamlServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSamlService) EXPECT() *MockSamlServiceMockRecorder {
+	return m.recorder
+}
+
+// Acs mocks base method.
+func (m *MockSamlService) Acs(ctx context.Context, r *app.SamlAcsRequest) (*app.LoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acs", ctx, r)
+	ret0, _ := ret[0].(*app.LoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acs indicates an expected call of Acs.
+func (mr *MockSamlServiceMockRecorder) Acs(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acs", reflect.TypeOf((*MockSamlService)(nil).Acs), ctx, r)
+}
+
+// AuthnRequestUrl mocks base method.
+func (m *MockSamlService) AuthnRequestUrl(ctx context.Context, r *app.SamlLoginRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthnRequestUrl", ctx, r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthnRequestUrl indicates an expected call of AuthnRequestUrl.
+func (mr *MockSamlServiceMockRecorder) AuthnRequestUrl(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthnRequestUrl", reflect.TypeOf((*MockSamlService)(nil).AuthnRequestUrl), ctx, r)
+}
+
+// CreateConnection mocks base method.
+func (m *MockSamlService) CreateConnection(ctx context.Context, r *app.CreateSamlConnectionRequest) (*model.SamlConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConnection", ctx, r)
+	ret0, _ := ret[0].(*model.SamlConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateConnection indicates an expected call of CreateConnection.
+func (mr *MockSamlServiceMockRecorder) CreateConnection(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConnection", reflect.TypeOf((*MockSamlService)(nil).CreateConnection), ctx, r)
+}
+
+// DeleteConnection mocks base method.
+func (m *MockSamlService) DeleteConnection(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConnection", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConnection indicates an expected call of DeleteConnection.
+func (mr *MockSamlServiceMockRecorder) DeleteConnection(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConnection", reflect.TypeOf((*MockSamlService)(nil).DeleteConnection), ctx, id)
+}
+
+// GetConnection mocks base method.
+func (m *MockSamlService) GetConnection(ctx context.Context, id string) (*model.SamlConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnection", ctx, id)
+	ret0, _ := ret[0].(*model.SamlConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConnection indicates an expected call of GetConnection.
+func (mr *MockSamlServiceMockRecorder) GetConnection(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnection", reflect.TypeOf((*MockSamlService)(nil).GetConnection), ctx, id)
+}
+
+// GetConnections mocks base method.
+func (m *MockSamlService) GetConnections(ctx context.Context) ([]*model.SamlConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnections", ctx)
+	ret0, _ := ret[0].([]*model.SamlConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConnections indicates an expected call of GetConnections.
+func (mr *MockSamlServiceMockRecorder) GetConnections(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnections", reflect.TypeOf((*MockSamlService)(nil).GetConnections), ctx)
+}
+
+// Metadata mocks base method.
+func (m *MockSamlService) Metadata(ctx context.Context, r *app.SamlMetadataRequest) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Metadata", ctx, r)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Metadata indicates an expected call of Metadata.
+func (mr *MockSamlServiceMockRecorder) Metadata(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Metadata", reflect.TypeOf((*MockSamlService)(nil).Metadata), ctx, r)
+}
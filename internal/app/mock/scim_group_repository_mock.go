@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scim_group_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockScimGroupRepository is a mock of ScimGroupRepository interface.
+type MockScimGroupRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockScimGroupRepositoryMockRecorder
+}
+
+// MockScimGroupRepositoryMockRecorder is the mock recorder for MockScimGroupRepository.
+type MockScimGroupRepositoryMockRecorder struct {
+	mock *MockScimGroupRepository
+}
+
+// NewMockScimGroupRepository creates a new mock instance.
+func NewMockScimGroupRepository(ctrl *gomock.Controller) *MockScimGroupRepository {
+	mock := &MockScimGroupRepository{ctrl: ctrl}
+	mock.recorder = &MockScimGroupRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScimGroupRepository) EXPECT() *MockScimGroupRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateGroup mocks base method.
+func (m *MockScimGroupRepository) CreateGroup(ctx context.Context, group *model.ScimGroup) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", ctx, group)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *MockScimGroupRepositoryMockRecorder) CreateGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockScimGroupRepository)(nil).CreateGroup), ctx, group)
+}
+
+// DeleteGroup mocks base method.
+func (m *MockScimGroupRepository) DeleteGroup(ctx context.Context, organization, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", ctx, organization, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *MockScimGroupRepositoryMockRecorder) DeleteGroup(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*MockScimGroupRepository)(nil).DeleteGroup), ctx, organization, id)
+}
+
+// FindGroups mocks base method.
+func (m *MockScimGroupRepository) FindGroups(ctx context.Context, q *app.GroupQuery) ([]*model.ScimGroup, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindGroups", ctx, q)
+	ret0, _ := ret[0].([]*model.ScimGroup)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindGroups indicates an expected call of FindGroups.
+func (mr *MockScimGroupRepositoryMockRecorder) FindGroups(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindGroups", reflect.TypeOf((*MockScimGroupRepository)(nil).FindGroups), ctx, q)
+}
+
+// GetGroup mocks base method.
+func (m *MockScimGroupRepository) GetGroup(ctx context.Context, organization, id string) (*model.ScimGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroup", ctx, organization, id)
+	ret0, _ := ret[0].(*model.ScimGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroup indicates an expected call of GetGroup.
+func (mr *MockScimGroupRepositoryMockRecorder) GetGroup(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroup", reflect.TypeOf((*MockScimGroupRepository)(nil).GetGroup), ctx, organization, id)
+}
+
+// RemoveMember mocks base method.
+func (m *MockScimGroupRepository) RemoveMember(ctx context.Context, organization, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", ctx, organization, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockScimGroupRepositoryMockRecorder) RemoveMember(ctx, organization, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockScimGroupRepository)(nil).RemoveMember), ctx, organization, userId)
+}
+
+// UpdateGroup mocks base method.
+func (m *MockScimGroupRepository) UpdateGroup(ctx context.Context, group *model.ScimGroup) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGroup", ctx, group)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateGroup indicates an expected call of UpdateGroup.
+func (mr *MockScimGroupRepositoryMockRecorder) UpdateGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroup", reflect.TypeOf((*MockScimGroupRepository)(nil).UpdateGroup), ctx, group)
+}
@@ -0,0 +1,274 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scim_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockScimService is a mock of ScimService interface.
+type MockScimService struct {
+	ctrl     *gomock.Controller
+	recorder *MockScimServiceMockRecorder
+}
+
+// MockScimServiceMockRecorder is the mock recorder for MockScimService.
+type MockScimServiceMockRecorder struct {
+	mock *MockScimService
+}
+
+// NewMockScimService creates a new mock instance.
+func NewMockScimService(ctrl *gomock.Controller) *MockScimService {
+	mock := &MockScimService{ctrl: ctrl}
+	mock.recorder = &MockScimServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScimService) EXPECT() *MockScimServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockScimService) Authenticate(ctx context.Context, token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockScimServiceMockRecorder) Authenticate(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockScimService)(nil).Authenticate), ctx, token)
+}
+
+// CreateGroup mocks base method.
+func (m *MockScimService) CreateGroup(ctx context.Context, organization string, g *app.ScimGroup) (*app.ScimGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", ctx, organization, g)
+	ret0, _ := ret[0].(*app.ScimGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *MockScimServiceMockRecorder) CreateGroup(ctx, organization, g interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockScimService)(nil).CreateGroup), ctx, organization, g)
+}
+
+// CreateToken mocks base method.
+func (m *MockScimService) CreateToken(ctx context.Context, r *app.CreateScimTokenRequest) (*app.CreateScimTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, r)
+	ret0, _ := ret[0].(*app.CreateScimTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockScimServiceMockRecorder) CreateToken(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockScimService)(nil).CreateToken), ctx, r)
+}
+
+// CreateUser mocks base method.
+func (m *MockScimService) CreateUser(ctx context.Context, organization string, u *app.ScimUser) (*app.ScimUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, organization, u)
+	ret0, _ := ret[0].(*app.ScimUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockScimServiceMockRecorder) CreateUser(ctx, organization, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockScimService)(nil).CreateUser), ctx, organization, u)
+}
+
+// DeleteGroup mocks base method.
+func (m *MockScimService) DeleteGroup(ctx context.Context, organization, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", ctx, organization, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *MockScimServiceMockRecorder) DeleteGroup(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*MockScimService)(nil).DeleteGroup), ctx, organization, id)
+}
+
+// DeleteToken mocks base method.
+func (m *MockScimService) DeleteToken(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteToken indicates an expected call of DeleteToken.
+func (mr *MockScimServiceMockRecorder) DeleteToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockScimService)(nil).DeleteToken), ctx, id)
+}
+
+// DeleteUser mocks base method.
+func (m *MockScimService) DeleteUser(ctx context.Context, organization, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, organization, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockScimServiceMockRecorder) DeleteUser(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockScimService)(nil).DeleteUser), ctx, organization, id)
+}
+
+// GetGroup mocks base method.
+func (m *MockScimService) GetGroup(ctx context.Context, organization, id string) (*app.ScimGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroup", ctx, organization, id)
+	ret0, _ := ret[0].(*app.ScimGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroup indicates an expected call of GetGroup.
+func (mr *MockScimServiceMockRecorder) GetGroup(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroup", reflect.TypeOf((*MockScimService)(nil).GetGroup), ctx, organization, id)
+}
+
+// GetGroups mocks base method.
+func (m *MockScimService) GetGroups(ctx context.Context, organization string, r *app.ScimListRequest) (*app.ScimListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroups", ctx, organization, r)
+	ret0, _ := ret[0].(*app.ScimListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroups indicates an expected call of GetGroups.
+func (mr *MockScimServiceMockRecorder) GetGroups(ctx, organization, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroups", reflect.TypeOf((*MockScimService)(nil).GetGroups), ctx, organization, r)
+}
+
+// GetTokens mocks base method.
+func (m *MockScimService) GetTokens(ctx context.Context) ([]*model.ScimToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokens", ctx)
+	ret0, _ := ret[0].([]*model.ScimToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokens indicates an expected call of GetTokens.
+func (mr *MockScimServiceMockRecorder) GetTokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokens", reflect.TypeOf((*MockScimService)(nil).GetTokens), ctx)
+}
+
+// GetUser mocks base method.
+func (m *MockScimService) GetUser(ctx context.Context, organization, id string) (*app.ScimUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, organization, id)
+	ret0, _ := ret[0].(*app.ScimUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockScimServiceMockRecorder) GetUser(ctx, organization, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockScimService)(nil).GetUser), ctx, organization, id)
+}
+
+// GetUsers mocks base method.
+func (m *MockScimService) GetUsers(ctx context.Context, organization string, r *app.ScimListRequest) (*app.ScimListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, organization, r)
+	ret0, _ := ret[0].(*app.ScimListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockScimServiceMockRecorder) GetUsers(ctx, organization, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockScimService)(nil).GetUsers), ctx, organization, r)
+}
+
+// PatchGroup mocks base method.
+func (m *MockScimService) PatchGroup(ctx context.Context, organization, id string, r *app.ScimPatchRequest) (*app.ScimGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchGroup", ctx, organization, id, r)
+	ret0, _ := ret[0].(*app.ScimGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchGroup indicates an expected call of PatchGroup.
+func (mr *MockScimServiceMockRecorder) PatchGroup(ctx, organization, id, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchGroup", reflect.TypeOf((*MockScimService)(nil).PatchGroup), ctx, organization, id, r)
+}
+
+// PatchUser mocks base method.
+func (m *MockScimService) PatchUser(ctx context.Context, organization, id string, r *app.ScimPatchRequest) (*app.ScimUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchUser", ctx, organization, id, r)
+	ret0, _ := ret[0].(*app.ScimUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchUser indicates an expected call of PatchUser.
+func (mr *MockScimServiceMockRecorder) PatchUser(ctx, organization, id, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchUser", reflect.TypeOf((*MockScimService)(nil).PatchUser), ctx, organization, id, r)
+}
+
+// ReplaceGroup mocks base method.
+func (m *MockScimService) ReplaceGroup(ctx context.Context, organization, id string, g *app.ScimGroup) (*app.ScimGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceGroup", ctx, organization, id, g)
+	ret0, _ := ret[0].(*app.ScimGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceGroup indicates an expected call of ReplaceGroup.
+func (mr *MockScimServiceMockRecorder) ReplaceGroup(ctx, organization, id, g interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceGroup", reflect.TypeOf((*MockScimService)(nil).ReplaceGroup), ctx, organization, id, g)
+}
+
+// ReplaceUser mocks base method.
+func (m *MockScimService) ReplaceUser(ctx context.Context, organization, id string, u *app.ScimUser) (*app.ScimUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceUser", ctx, organization, id, u)
+	ret0, _ := ret[0].(*app.ScimUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceUser indicates an expected call of ReplaceUser.
+func (mr *MockScimServiceMockRecorder) ReplaceUser(ctx, organization, id, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceUser", reflect.TypeOf((*MockScimService)(nil).ReplaceUser), ctx, organization, id, u)
+}
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scim_token_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockScimTokenRepository is a mock of ScimTokenRepository interface.
+type MockScimTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockScimTokenRepositoryMockRecorder
+}
+
+// MockScimTokenRepositoryMockRecorder is the mock recorder for MockScimTokenRepository.
+type MockScimTokenRepositoryMockRecorder struct {
+	mock *MockScimTokenRepository
+}
+
+// NewMockScimTokenRepository creates a new mock instance.
+func NewMockScimTokenRepository(ctrl *gomock.Controller) *MockScimTokenRepository {
+	mock := &MockScimTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockScimTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScimTokenRepository) EXPECT() *MockScimTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateToken mocks base method.
+func (m *MockScimTokenRepository) CreateToken(ctx context.Context, token *model.ScimToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockScimTokenRepositoryMockRecorder) CreateToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockScimTokenRepository)(nil).CreateToken), ctx, token)
+}
+
+// DeleteToken mocks base method.
+func (m *MockScimTokenRepository) DeleteToken(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteToken indicates an expected call of DeleteToken.
+func (mr *MockScimTokenRepositoryMockRecorder) DeleteToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockScimTokenRepository)(nil).DeleteToken), ctx, id)
+}
+
+// GetToken mocks base method.
+func (m *MockScimTokenRepository) GetToken(ctx context.Context, id string) (*model.ScimToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetToken", ctx, id)
+	ret0, _ := ret[0].(*model.ScimToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetToken indicates an expected call of GetToken.
+func (mr *MockScimTokenRepositoryMockRecorder) GetToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockScimTokenRepository)(nil).GetToken), ctx, id)
+}
+
+// GetTokens mocks base method.
+func (m *MockScimTokenRepository) GetTokens(ctx context.Context) ([]*model.ScimToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokens", ctx)
+	ret0, _ := ret[0].([]*model.ScimToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokens indicates an expected call of GetTokens.
+func (mr *MockScimTokenRepositoryMockRecorder) GetTokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokens", reflect.TypeOf((*MockScimTokenRepository)(nil).GetTokens), ctx)
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token_denylist.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockTokenDenylist is a mock of TokenDenylist interface.
+type MockTokenDenylist struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenDenylistMockRecorder
+}
+
+// MockTokenDenylistMockRecorder is the mock recorder for MockTokenDenylist.
+type MockTokenDenylistMockRecorder struct {
+	mock *MockTokenDenylist
+}
+
+// NewMockTokenDenylist creates a new mock instance.
+func NewMockTokenDenylist(ctrl *gomock.Controller) *MockTokenDenylist {
+	mock := &MockTokenDenylist{ctrl: ctrl}
+	mock.recorder = &MockTokenDenylistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenDenylist) EXPECT() *MockTokenDenylistMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockTokenDenylist) Add(ctx context.Context, token *model.RevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockTokenDenylistMockRecorder) Add(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockTokenDenylist)(nil).Add), ctx, token)
+}
+
+// Contains mocks base method.
+func (m *MockTokenDenylist) Contains(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contains", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Contains indicates an expected call of Contains.
+func (mr *MockTokenDenylistMockRecorder) Contains(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contains", reflect.TypeOf((*MockTokenDenylist)(nil).Contains), ctx, id)
+}
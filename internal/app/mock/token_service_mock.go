@@ -37,34 +37,122 @@ func (m *MockTokenService) EXPECT() *MockTokenServiceMockRecorder {
 	return m.recorder
 }
 
+// DenyAccessToken mocks base method.
+func (m *MockTokenService) DenyAccessToken(ctx context.Context, r *app.DenyTokenRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DenyAccessToken", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DenyAccessToken indicates an expected call of DenyAccessToken.
+func (mr *MockTokenServiceMockRecorder) DenyAccessToken(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DenyAccessToken", reflect.TypeOf((*MockTokenService)(nil).DenyAccessToken), ctx, r)
+}
+
 // GenerateAccessToken mocks base method.
-func (m *MockTokenService) GenerateAccessToken(ctx context.Context, user *model.User) (string, error) {
+func (m *MockTokenService) GenerateAccessToken(ctx context.Context, user *model.User, grant *app.TokenGrant) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateAccessToken", ctx, user)
+	ret := m.ctrl.Call(m, "GenerateAccessToken", ctx, user, grant)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateAccessToken indicates an expected call of GenerateAccessToken.
-func (mr *MockTokenServiceMockRecorder) GenerateAccessToken(ctx, user interface{}) *gomock.Call {
+func (mr *MockTokenServiceMockRecorder) GenerateAccessToken(ctx, user, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessToken", reflect.TypeOf((*MockTokenService)(nil).GenerateAccessToken), ctx, user, grant)
+}
+
+// GenerateClientAccessToken mocks base method.
+func (m *MockTokenService) GenerateClientAccessToken(ctx context.Context, client *model.OAuthClient, grant *app.TokenGrant) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateClientAccessToken", ctx, client, grant)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateClientAccessToken indicates an expected call of GenerateClientAccessToken.
+func (mr *MockTokenServiceMockRecorder) GenerateClientAccessToken(ctx, client, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateClientAccessToken", reflect.TypeOf((*MockTokenService)(nil).GenerateClientAccessToken), ctx, client, grant)
+}
+
+// GenerateDelegatedAccessToken mocks base method.
+func (m *MockTokenService) GenerateDelegatedAccessToken(ctx context.Context, user *model.User, r *app.DelegatedTokenRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateDelegatedAccessToken", ctx, user, r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateDelegatedAccessToken indicates an expected call of GenerateDelegatedAccessToken.
+func (mr *MockTokenServiceMockRecorder) GenerateDelegatedAccessToken(ctx, user, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateDelegatedAccessToken", reflect.TypeOf((*MockTokenService)(nil).GenerateDelegatedAccessToken), ctx, user, r)
+}
+
+// GenerateIdToken mocks base method.
+func (m *MockTokenService) GenerateIdToken(ctx context.Context, user *model.User, r *app.IdTokenRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateIdToken", ctx, user, r)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateIdToken indicates an expected call of GenerateIdToken.
+func (mr *MockTokenServiceMockRecorder) GenerateIdToken(ctx, user, r interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessToken", reflect.TypeOf((*MockTokenService)(nil).GenerateAccessToken), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateIdToken", reflect.TypeOf((*MockTokenService)(nil).GenerateIdToken), ctx, user, r)
 }
 
 // GenerateRefreshToken mocks base method.
-func (m *MockTokenService) GenerateRefreshToken(ctx context.Context, user *model.User) (string, error) {
+func (m *MockTokenService) GenerateRefreshToken(ctx context.Context, user *model.User, grant *app.TokenGrant) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateRefreshToken", ctx, user)
+	ret := m.ctrl.Call(m, "GenerateRefreshToken", ctx, user, grant)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateRefreshToken indicates an expected call of GenerateRefreshToken.
-func (mr *MockTokenServiceMockRecorder) GenerateRefreshToken(ctx, user interface{}) *gomock.Call {
+func (mr *MockTokenServiceMockRecorder) GenerateRefreshToken(ctx, user, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockTokenService)(nil).GenerateRefreshToken), ctx, user, grant)
+}
+
+// Introspect mocks base method.
+func (m *MockTokenService) Introspect(ctx context.Context, r *app.IntrospectionRequest) (*app.IntrospectionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Introspect", ctx, r)
+	ret0, _ := ret[0].(*app.IntrospectionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Introspect indicates an expected call of Introspect.
+func (mr *MockTokenServiceMockRecorder) Introspect(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Introspect", reflect.TypeOf((*MockTokenService)(nil).Introspect), ctx, r)
+}
+
+// KeySet mocks base method.
+func (m *MockTokenService) KeySet(ctx context.Context) *app.JSONWebKeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeySet", ctx)
+	ret0, _ := ret[0].(*app.JSONWebKeySet)
+	return ret0
+}
+
+// KeySet indicates an expected call of KeySet.
+func (mr *MockTokenServiceMockRecorder) KeySet(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockTokenService)(nil).GenerateRefreshToken), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeySet", reflect.TypeOf((*MockTokenService)(nil).KeySet), ctx)
 }
 
 // ParseToken mocks base method.
@@ -82,6 +170,94 @@ func (mr *MockTokenServiceMockRecorder) ParseToken(ctx, token interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MockTokenService)(nil).ParseToken), ctx, token)
 }
 
+// RefreshTokenGrant mocks base method.
+func (m *MockTokenService) RefreshTokenGrant(ctx context.Context, token string) (*app.TokenGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokenGrant", ctx, token)
+	ret0, _ := ret[0].(*app.TokenGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshTokenGrant indicates an expected call of RefreshTokenGrant.
+func (mr *MockTokenServiceMockRecorder) RefreshTokenGrant(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokenGrant", reflect.TypeOf((*MockTokenService)(nil).RefreshTokenGrant), ctx, token)
+}
+
+// ResolveClientGrant mocks base method.
+func (m *MockTokenService) ResolveClientGrant(ctx context.Context, client *model.OAuthClient, grant *app.TokenGrant) (*app.TokenGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveClientGrant", ctx, client, grant)
+	ret0, _ := ret[0].(*app.TokenGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveClientGrant indicates an expected call of ResolveClientGrant.
+func (mr *MockTokenServiceMockRecorder) ResolveClientGrant(ctx, client, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveClientGrant", reflect.TypeOf((*MockTokenService)(nil).ResolveClientGrant), ctx, client, grant)
+}
+
+// ResolveGrant mocks base method.
+func (m *MockTokenService) ResolveGrant(ctx context.Context, user *model.User, grant *app.TokenGrant) (*app.TokenGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveGrant", ctx, user, grant)
+	ret0, _ := ret[0].(*app.TokenGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveGrant indicates an expected call of ResolveGrant.
+func (mr *MockTokenServiceMockRecorder) ResolveGrant(ctx, user, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveGrant", reflect.TypeOf((*MockTokenService)(nil).ResolveGrant), ctx, user, grant)
+}
+
+// Revoke mocks base method.
+func (m *MockTokenService) Revoke(ctx context.Context, r *app.RevocationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockTokenServiceMockRecorder) Revoke(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockTokenService)(nil).Revoke), ctx, r)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockTokenService) RevokeRefreshToken(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockTokenServiceMockRecorder) RevokeRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockTokenService)(nil).RevokeRefreshToken), ctx, token)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockTokenService) RotateRefreshToken(ctx context.Context, token string, user *model.User) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, token, user)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockTokenServiceMockRecorder) RotateRefreshToken(ctx, token, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockTokenService)(nil).RotateRefreshToken), ctx, token, user)
+}
+
 // ValidateAccessTokenFromRequest mocks base method.
 func (m *MockTokenService) ValidateAccessTokenFromRequest(ctx context.Context, r *http.Request) (app.Claims, error) {
 	m.ctrl.T.Helper()
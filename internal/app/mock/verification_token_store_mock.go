@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: verification_token_store.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// MockVerificationTokenStore is a mock of VerificationTokenStore interface.
+type MockVerificationTokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockVerificationTokenStoreMockRecorder
+}
+
+// MockVerificationTokenStoreMockRecorder is the mock recorder for MockVerificationTokenStore.
+type MockVerificationTokenStoreMockRecorder struct {
+	mock *MockVerificationTokenStore
+}
+
+// NewMockVerificationTokenStore creates a new mock instance.
+func NewMockVerificationTokenStore(ctrl *gomock.Controller) *MockVerificationTokenStore {
+	mock := &MockVerificationTokenStore{ctrl: ctrl}
+	mock.recorder = &MockVerificationTokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVerificationTokenStore) EXPECT() *MockVerificationTokenStoreMockRecorder {
+	return m.recorder
+}
+
+// Consume mocks base method.
+func (m *MockVerificationTokenStore) Consume(ctx context.Context, id, purpose string) (*model.VerificationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, id, purpose)
+	ret0, _ := ret[0].(*model.VerificationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockVerificationTokenStoreMockRecorder) Consume(ctx, id, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockVerificationTokenStore)(nil).Consume), ctx, id, purpose)
+}
+
+// DeleteByUser mocks base method.
+func (m *MockVerificationTokenStore) DeleteByUser(ctx context.Context, userId, purpose string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUser", ctx, userId, purpose)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUser indicates an expected call of DeleteByUser.
+func (mr *MockVerificationTokenStoreMockRecorder) DeleteByUser(ctx, userId, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUser", reflect.TypeOf((*MockVerificationTokenStore)(nil).DeleteByUser), ctx, userId, purpose)
+}
+
+// Save mocks base method.
+func (m *MockVerificationTokenStore) Save(ctx context.Context, token *model.VerificationToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockVerificationTokenStoreMockRecorder) Save(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockVerificationTokenStore)(nil).Save), ctx, token)
+}
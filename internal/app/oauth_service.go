@@ -0,0 +1,49 @@
+//go:generate mockgen -source oauth_service.go -destination mock/oauth_service_mock.go -package mock
+package app
+
+import (
+	"context"
+)
+
+const (
+	GrantTypePassword          = "password"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeUrnAccessToken identifies the access tokens in the token
+	// exchange (RFC 8693 section 3)
+	TokenTypeUrnAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	ResponseTypeCode = "code"
+
+	CodeChallengeMethodS256 = "S256"
+)
+
+type OAuthService interface {
+	// Token issues tokens for the grant of the request (RFC 6749)
+	Token(ctx context.Context, r *TokenRequest) (*TokenResponse, error)
+	// GrantTypes returns the supported grant types
+	GrantTypes() []string
+	// ValidateAuthorizeRequest validates the client and the redirect uri of
+	// the authorization request before the user is authenticated
+	ValidateAuthorizeRequest(ctx context.Context, r *AuthorizeRequest) error
+	// Consent returns the scopes which the client requests from the user and
+	// whether the user has to consent to them
+	Consent(ctx context.Context, uid string, r *AuthorizeRequest) (*ConsentResponse, error)
+	// Authorize issues an authorization code to the client for the user
+	Authorize(ctx context.Context, uid string, r *AuthorizeRequest) (*AuthorizeResponse, error)
+	// UserInfo returns the claims of the user which the scopes of the access
+	// token allow
+	UserInfo(ctx context.Context, claims Claims) (*UserInfoResponse, error)
+	// DeviceAuthorization starts the device flow of the client (RFC 8628)
+	DeviceAuthorization(ctx context.Context, r *DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error)
+	// GetDeviceAuthorization returns the pending request of the user code to
+	// be shown to the user before the decision
+	GetDeviceAuthorization(ctx context.Context, userCode string) (*DeviceVerificationResponse, error)
+	// DecideDeviceAuthorization approves or denies the request of the user
+	// code for the user
+	DecideDeviceAuthorization(ctx context.Context, uid string, r *DeviceDecisionRequest) error
+}
@@ -0,0 +1,20 @@
+//go:generate mockgen -source refresh_token_store.go -destination mock/refresh_token_store_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type RefreshTokenStore interface {
+	Save(ctx context.Context, token *model.RefreshToken) error
+	Get(ctx context.Context, id string) (*model.RefreshToken, error)
+	// Rotate marks the token as replaced by another one. It returns
+	// ErrRefreshTokenReused if the token was already rotated or revoked.
+	Rotate(ctx context.Context, id string, replacedBy string) error
+	RevokeFamily(ctx context.Context, familyId string) error
+	// RevokeClient revokes the refresh tokens issued to the client on behalf
+	// of the user
+	RevokeClient(ctx context.Context, userId string, clientId string) error
+}
@@ -3,15 +3,54 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
 )
 
+// UserQuery filters the users of an organization. Empty fields are not
+// filtered, and the users are paged by Offset and Limit.
+type UserQuery struct {
+	Organization string
+	Email        string
+	ExternalId   string
+	Offset       int
+	Limit        int
+}
+
 type Repository interface {
 	GetUser(ctx context.Context, id string) (*model.User, error)
 	GetUsersByIds(ctx context.Context, ids []string) ([]*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	CreateUser(ctx context.Context, user *model.User) (string, error)
-	UpdateUser(ctx context.Context, id string, user *model.User) error
+	// UpdateProfile updates the fields of the profile which the user edits
+	UpdateProfile(ctx context.Context, id string, user *model.User) error
+	// UpdateDirectoryUser updates the fields which the directory of the
+	// organization provisions
+	UpdateDirectoryUser(ctx context.Context, id string, user *model.User) error
+	SetPassword(ctx context.Context, id string, password string) error
+	// VerifyEmail marks the email of the user as verified. It returns
+	// ErrUserNotFound if the email of the user has changed.
+	VerifyEmail(ctx context.Context, id string, email string) error
+	SetUserDisabled(ctx context.Context, id string, disabled bool) error
 	DeleteUser(ctx context.Context, id string) error
+	IncrementTokenGeneration(ctx context.Context, id string) error
+	// GetUserByIdentity returns the user which the external identity is
+	// linked to
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*model.User, error)
+	// LinkIdentity links the external identity to the user. A user can have
+	// one identity per provider.
+	LinkIdentity(ctx context.Context, id string, identity *model.ExternalIdentity) error
+	// FindUsers returns a page of the users of the query and the total number
+	// of the users
+	FindUsers(ctx context.Context, q *UserQuery) ([]*model.User, int, error)
+	// ScheduleDeletion sets the time when the user is deleted. The deletion
+	// is cancelled when it is nil.
+	ScheduleDeletion(ctx context.Context, id string, at *time.Time) error
+	// GetUsersToDelete returns up to limit users whose deletion is due at
+	// the time
+	GetUsersToDelete(ctx context.Context, at time.Time, limit int) ([]*model.User, error)
+	// AnonymizeUser removes the personal fields of the user whose deletion
+	// is due and leaves a tombstone in place of it
+	AnonymizeUser(ctx context.Context, id string) error
 }
@@ -1,15 +1,212 @@
 package app
 
+import "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email,lte=100"`
 	Password string `json:"password" validate:"required,gte=6,lte=60"`
+	Audience string `json:"audience"`
+	Scope    string `json:"scope"`
+	// ClientId is the authenticated client which logs in the user with the
+	// password grant. The tokens are issued to the client.
+	ClientId string `json:"-"`
 } // @name LoginRequest
 
 type RegisterRequest struct {
-	Email    string `json:"email" validate:"required,email,lte=100"`
-	Password string `json:"password" validate:"required,gte=6,lte=60"`
+	Email     string `json:"email" validate:"required,email,lte=100"`
+	Password  string `json:"password" validate:"required,gte=6,lte=60"`
+	FirstName string `json:"first_name" validate:"omitempty,lte=30"`
+	LastName  string `json:"last_name" validate:"omitempty,lte=30"`
+	Avatar    string `json:"avatar" validate:"omitempty,url,lte=500"`
+	Phone     string `json:"phone" validate:"omitempty,e164"`
+	Audience  string `json:"audience"`
+	Scope     string `json:"scope"`
 } // @name RegisterResponse
 
 type RefreshTokenRequest struct {
 	Token string `json:"token" validate:"required"`
+	// Scope narrows the scope of the new access token
+	Scope string `json:"scope"`
+	// ClientId is the authenticated client which redeems the token. The
+	// refresh tokens issued to a client are redeemed by the client only.
+	ClientId string `json:"-"`
 } // @name RefreshTokenRequest
+
+type LogoutRequest struct {
+	Token string `json:"token" validate:"required"`
+} // @name LogoutRequest
+
+// VerifyEmailRequest carries the token sent to the email of the user
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+} // @name VerifyEmailRequest
+
+type ResendVerificationEmailRequest struct {
+	Email string `json:"email" validate:"required,email,lte=100"`
+} // @name ResendVerificationEmailRequest
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,lte=100"`
+} // @name ForgotPasswordRequest
+
+// ResetPasswordRequest carries the token sent to the email of the user and
+// the new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,gte=6,lte=60"`
+} // @name ResetPasswordRequest
+
+// UpdateProfileRequest is a partial update of the profile of the user. The
+// fields which are not set are left as they are, and an empty avatar or phone
+// removes it.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" validate:"omitempty,gte=1,lte=30"`
+	LastName  *string `json:"last_name" validate:"omitempty,gte=1,lte=30"`
+	Avatar    *string `json:"avatar" validate:"omitempty,url,lte=500"`
+	Phone     *string `json:"phone" validate:"omitempty,e164"`
+} // @name UpdateProfileRequest
+
+// ChangePasswordRequest is the request of the logged in user to change the
+// password. The other devices are signed out when LogoutOtherDevices is set.
+type ChangePasswordRequest struct {
+	CurrentPassword    string `json:"current_password" validate:"required"`
+	NewPassword        string `json:"new_password" validate:"required,gte=6,lte=60,nefield=CurrentPassword"`
+	LogoutOtherDevices bool   `json:"logout_other_devices"`
+} // @name ChangePasswordRequest
+
+// TokenRequest is the access token request of RFC 6749
+type TokenRequest struct {
+	GrantType          string `json:"grant_type" form:"grant_type" validate:"required"`
+	Username           string `json:"username" form:"username"`
+	Password           string `json:"password" form:"password"`
+	RefreshToken       string `json:"refresh_token" form:"refresh_token"`
+	Scope              string `json:"scope" form:"scope"`
+	Audience           string `json:"audience" form:"audience"`
+	Code               string `json:"code" form:"code"`
+	RedirectUri        string `json:"redirect_uri" form:"redirect_uri"`
+	ClientId           string `json:"client_id" form:"client_id"`
+	CodeVerifier       string `json:"code_verifier" form:"code_verifier"`
+	ClientSecret       string `json:"client_secret" form:"client_secret"`
+	DeviceCode         string `json:"device_code" form:"device_code"`
+	SubjectToken       string `json:"subject_token" form:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type" form:"subject_token_type"`
+	ActorToken         string `json:"actor_token" form:"actor_token"`
+	ActorTokenType     string `json:"actor_token_type" form:"actor_token_type"`
+	RequestedTokenType string `json:"requested_token_type" form:"requested_token_type"`
+} // @name TokenRequest
+
+// AuthorizeRequest is the authorization request of RFC 6749 with PKCE (RFC 7636)
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type" form:"response_type" validate:"required"`
+	ClientId            string `json:"client_id" query:"client_id" form:"client_id" validate:"required"`
+	RedirectUri         string `json:"redirect_uri" query:"redirect_uri" form:"redirect_uri" validate:"required"`
+	Scope               string `json:"scope" query:"scope" form:"scope"`
+	State               string `json:"state" query:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" form:"code_challenge_method"`
+	Nonce               string `json:"nonce" query:"nonce" form:"nonce"`
+} // @name AuthorizeRequest
+
+// DeviceAuthorizationRequest is the device authorization request of RFC 8628
+type DeviceAuthorizationRequest struct {
+	ClientId     string `json:"client_id" form:"client_id" validate:"required"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"`
+} // @name DeviceAuthorizationRequest
+
+// DeviceDecisionRequest approves or denies the device authorization request
+// of the user code
+type DeviceDecisionRequest struct {
+	UserCode string `json:"user_code" validate:"required"`
+	Approve  bool   `json:"approve"`
+} // @name DeviceDecisionRequest
+
+// CreateClientRequest registers an OAuth client. Confidential clients are
+// issued a secret, public clients need at least one redirect uri.
+type CreateClientRequest struct {
+	Name         string   `json:"name" validate:"required,lte=100"`
+	RedirectUris []string `json:"redirect_uris" validate:"omitempty,dive,url"`
+	Scopes       []string `json:"scopes"`
+	Audience     string   `json:"audience"`
+	Confidential bool     `json:"confidential"`
+} // @name CreateClientRequest
+
+// IntrospectionRequest is the token introspection request of RFC 7662
+type IntrospectionRequest struct {
+	Token         string `json:"token" form:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+} // @name IntrospectionRequest
+
+// RevocationRequest is the token revocation request of RFC 7009
+type RevocationRequest struct {
+	Token         string `json:"token" form:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+} // @name RevocationRequest
+
+// DenyTokenRequest denies an access token by its jti
+type DenyTokenRequest struct {
+	Jti string `json:"jti" validate:"required"`
+} // @name DenyTokenRequest
+
+// FederatedLoginRequest starts a login at an upstream provider. Audience and
+// Scope are used for the tokens issued after the login.
+type FederatedLoginRequest struct {
+	Provider string `json:"provider" param:"provider" validate:"required"`
+	Audience string `json:"audience" query:"audience"`
+	Scope    string `json:"scope" query:"scope"`
+	// RedirectUri is the callback url which the provider redirects to
+	RedirectUri string `json:"-" validate:"required,url"`
+} // @name FederatedLoginRequest
+
+// FederatedCallbackRequest is the authorization response of the provider
+type FederatedCallbackRequest struct {
+	Provider         string `json:"provider" param:"provider" validate:"required"`
+	State            string `json:"state" query:"state" form:"state" validate:"required"`
+	Code             string `json:"code" query:"code" form:"code"`
+	Error            string `json:"error" query:"error" form:"error"`
+	ErrorDescription string `json:"error_description" query:"error_description" form:"error_description"`
+} // @name FederatedCallbackRequest
+
+// CreateSamlConnectionRequest imports the metadata of the SAML 2.0 identity
+// provider of an organization. Id is the slug of the organization. Only the
+// users with an email of the domains can login with the connection.
+type CreateSamlConnectionRequest struct {
+	Id               string                     `json:"id" validate:"required,lte=50"`
+	Name             string                     `json:"name" validate:"required,lte=100"`
+	Metadata         string                     `json:"metadata" validate:"required"`
+	Domains          []string                   `json:"domains" validate:"required,min=1,dive,fqdn"`
+	AttributeMapping model.SamlAttributeMapping `json:"attribute_mapping"`
+} // @name CreateSamlConnectionRequest
+
+// SamlMetadataRequest requests the metadata of the service provider of a
+// connection
+type SamlMetadataRequest struct {
+	Connection string `json:"connection" param:"connection" validate:"required"`
+	// EntityId and AcsUrl are the urls of the service provider
+	EntityId string `json:"-" validate:"required,url"`
+	AcsUrl   string `json:"-" validate:"required,url"`
+} // @name SamlMetadataRequest
+
+// SamlLoginRequest starts a login at the identity provider of a connection.
+// Audience and Scope are used for the tokens issued after the login.
+type SamlLoginRequest struct {
+	SamlMetadataRequest
+	Audience string `json:"audience" query:"audience"`
+	Scope    string `json:"scope" query:"scope"`
+} // @name SamlLoginRequest
+
+// SamlAcsRequest is the response of the identity provider which is posted to
+// the assertion consumer service
+type SamlAcsRequest struct {
+	SamlMetadataRequest
+	SAMLResponse string `json:"SAMLResponse" form:"SAMLResponse" validate:"required"`
+	RelayState   string `json:"RelayState" form:"RelayState" validate:"required"`
+} // @name SamlAcsRequest
+
+// CreateScimTokenRequest creates a bearer token of the SCIM API of an
+// organization. The organization is the id of its SAML connection, so the
+// users created at their first login are provisioned by the same token.
+type CreateScimTokenRequest struct {
+	Organization string `json:"organization" validate:"required,lte=50"`
+	Name         string `json:"name" validate:"required,lte=100"`
+} // @name CreateScimTokenRequest
@@ -1,6 +1,10 @@
 package app
 
-import "github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+import (
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
 
 type HTTPError struct {
 	Code     int         `json:"-"`
@@ -15,14 +19,154 @@ type LoginResponse struct {
 	AccessTokenExpiresIn  int          `json:"access_token_expires_in"`
 	RefreshToken          string       `json:"refresh_token"`
 	RefreshTokenExpiresIn int          `json:"refresh_token_expires_in"`
+	Scope                 string       `json:"scope,omitempty"`
 } // @name LoginResponse
 
 // RefreshTokenResponse is the response of RefreshTokenRequest
 type RefreshTokenResponse struct {
-	AccessToken          string `json:"access_token"`
-	AccessTokenExpiresIn int    `json:"access_token_expires_in"`
+	AccessToken           string `json:"access_token"`
+	AccessTokenExpiresIn  int    `json:"access_token_expires_in"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in"`
+	Scope                 string `json:"scope,omitempty"`
+	// UserId and ClientId identify the refreshed session for the OAuth token
+	// endpoint
+	UserId   string `json:"-"`
+	ClientId string `json:"-"`
 } // @name RefreshTokenResponse
 
+// TokenResponse is the response of TokenRequest
+type TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IdToken         string `json:"id_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+} // @name TokenResponse
+
+// AuthorizeResponse is the response of AuthorizeRequest. RedirectUri is the
+// redirect uri of the client with the code and the state of the request.
+type AuthorizeResponse struct {
+	RedirectUri string `json:"redirect_uri"`
+} // @name AuthorizeResponse
+
+// DeviceAuthorizationResponse is the response of DeviceAuthorizationRequest
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+} // @name DeviceAuthorizationResponse
+
+// DeviceVerificationResponse describes the pending device authorization
+// request of a user code
+type DeviceVerificationResponse struct {
+	UserCode   string `json:"user_code"`
+	ClientId   string `json:"client_id"`
+	ClientName string `json:"client_name"`
+	Scope      string `json:"scope,omitempty"`
+} // @name DeviceVerificationResponse
+
+// ConsentResponse tells whether the user has to consent to the authorization
+// request. The consent screen is skipped when the requested scopes are
+// already granted to the client.
+type ConsentResponse struct {
+	ClientId      string   `json:"client_id"`
+	ClientName    string   `json:"client_name"`
+	Scopes        []string `json:"scopes"`
+	GrantedScopes []string `json:"granted_scopes"`
+	Required      bool     `json:"consent_required"`
+} // @name ConsentResponse
+
+// ApplicationResponse is a client which the user granted access to
+type ApplicationResponse struct {
+	ClientId    string    `json:"client_id"`
+	Name        string    `json:"name"`
+	Scopes      []string  `json:"scopes"`
+	FirstUsedAt time.Time `json:"first_used_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+} // @name ApplicationResponse
+
+// UserInfoResponse is the UserInfo response of OpenID Connect. Only the claims
+// allowed by the granted scopes are set.
+type UserInfoResponse struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+} // @name UserInfoResponse
+
+// CreateClientResponse is the response of CreateClientRequest. ClientSecret
+// is only set for confidential clients and cannot be retrieved later.
+type CreateClientResponse struct {
+	model.OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+} // @name CreateClientResponse
+
+// OpenIDConfiguration is the OpenID Connect discovery document
+type OpenIDConfiguration struct {
+	Issuer                                    string   `json:"issuer"`
+	AuthorizationEndpoint                     string   `json:"authorization_endpoint"`
+	DeviceAuthorizationEndpoint               string   `json:"device_authorization_endpoint"`
+	UserinfoEndpoint                          string   `json:"userinfo_endpoint"`
+	TokenEndpoint                             string   `json:"token_endpoint"`
+	JwksUri                                   string   `json:"jwks_uri"`
+	RevocationEndpoint                        string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	ResponseTypesSupported                    []string `json:"response_types_supported"`
+	SubjectTypesSupported                     []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported          []string `json:"id_token_signing_alg_values_supported"`
+	GrantTypesSupported                       []string `json:"grant_types_supported"`
+	ScopesSupported                           []string `json:"scopes_supported"`
+	ClaimsSupported                           []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported             []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported         []string `json:"token_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethodsSupported    []string `json:"revocation_endpoint_auth_methods_supported"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+} // @name OpenIDConfiguration
+
+// IntrospectionResponse is the response of IntrospectionRequest. Only the
+// active field is set when the token is not active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Act       *Actor `json:"act,omitempty"`
+} // @name IntrospectionResponse
+
+// JSONWebKey is a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+} // @name JSONWebKey
+
+// JSONWebKeySet is the set of public keys to verify the issued tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+} // @name JSONWebKeySet
+
 type UserResponse struct {
 	Id        string `json:"id"`
 	FirstName string `json:"first_name"`
@@ -30,6 +174,13 @@ type UserResponse struct {
 	Email     string `json:"email"`
 	Role      string `json:"role"`
 	Avatar    string `json:"avatar"`
+	Phone     string `json:"phone,omitempty"`
+	// EmailVerified is set once the user proves the ownership of the email
+	EmailVerified bool `json:"email_verified"`
+	// Identities are the linked identities at the upstream providers
+	Identities []model.ExternalIdentity `json:"identities,omitempty"`
+	// Deleted is set for the tombstones of the deleted users
+	Deleted bool `json:"deleted,omitempty"`
 } // @name UserResponse
 
 func (r *UserResponse) fromUser(u *model.User) {
@@ -38,7 +189,11 @@ func (r *UserResponse) fromUser(u *model.User) {
 	r.LastName = u.LastName
 	r.Email = u.Email
 	r.Avatar = u.Avatar
+	r.Phone = u.Phone
 	r.Role = u.GetRole()
+	r.EmailVerified = u.EmailVerified
+	r.Identities = u.Identities
+	r.Deleted = u.Deleted
 }
 
 func UserResponseFromUser(u *model.User) *UserResponse {
@@ -46,3 +201,20 @@ func UserResponseFromUser(u *model.User) *UserResponse {
 	r.fromUser(u)
 	return r
 }
+
+// DeleteAccountResponse is the time when the account is deleted unless the
+// user logs in before it
+type DeleteAccountResponse struct {
+	DeleteAt time.Time `json:"delete_at"`
+} // @name DeleteAccountResponse
+
+// FederationProvidersResponse lists the upstream providers to login with
+type FederationProvidersResponse struct {
+	Providers []string `json:"providers"`
+} // @name FederationProvidersResponse
+
+// CreateScimTokenResponse is the created token. Token is only returned once.
+type CreateScimTokenResponse struct {
+	model.ScimToken
+	Token string `json:"token"`
+} // @name CreateScimTokenResponse
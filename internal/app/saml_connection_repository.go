@@ -0,0 +1,15 @@
+//go:generate mockgen -source saml_connection_repository.go -destination mock/saml_connection_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type SamlConnectionRepository interface {
+	GetConnection(ctx context.Context, id string) (*model.SamlConnection, error)
+	GetConnections(ctx context.Context) ([]*model.SamlConnection, error)
+	CreateConnection(ctx context.Context, connection *model.SamlConnection) error
+	DeleteConnection(ctx context.Context, id string) error
+}
@@ -0,0 +1,25 @@
+//go:generate mockgen -source saml_service.go -destination mock/saml_service_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type SamlService interface {
+	// CreateConnection imports the metadata of the identity provider of an
+	// organization
+	CreateConnection(ctx context.Context, r *CreateSamlConnectionRequest) (*model.SamlConnection, error)
+	GetConnection(ctx context.Context, id string) (*model.SamlConnection, error)
+	GetConnections(ctx context.Context) ([]*model.SamlConnection, error)
+	DeleteConnection(ctx context.Context, id string) error
+	// Metadata returns the metadata of the service provider of the connection
+	Metadata(ctx context.Context, r *SamlMetadataRequest) ([]byte, error)
+	// AuthnRequestUrl starts a login at the identity provider and returns the
+	// url to redirect the user to
+	AuthnRequestUrl(ctx context.Context, r *SamlLoginRequest) (string, error)
+	// Acs validates the response of the identity provider and issues a token
+	// pair. The user is created on the first login.
+	Acs(ctx context.Context, r *SamlAcsRequest) (*LoginResponse, error)
+}
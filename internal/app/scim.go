@@ -0,0 +1,127 @@
+package app
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SCIM 2.0 schemas of RFC 7643 and RFC 7644
+const (
+	ScimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	ScimContentType = "application/scim+json"
+)
+
+// ScimMeta is the metadata of a SCIM resource
+type ScimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+} // @name ScimMeta
+
+// ScimName is the name of a SCIM user
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+} // @name ScimName
+
+// ScimEmail is an email of a SCIM user
+type ScimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+} // @name ScimEmail
+
+// ScimUser is a user resource. UserName is the email of the user, and an
+// inactive user is disabled.
+type ScimUser struct {
+	Schemas     []string    `json:"schemas"`
+	Id          string      `json:"id,omitempty"`
+	ExternalId  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *ScimName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []ScimEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *ScimMeta   `json:"meta,omitempty"`
+} // @name ScimUser
+
+// ScimMember is a member of a SCIM group. Value is the id of the user.
+type ScimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+} // @name ScimMember
+
+// ScimGroup is a group resource
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id,omitempty"`
+	ExternalId  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []ScimMember `json:"members,omitempty"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+} // @name ScimGroup
+
+// ScimListRequest queries the resources. Only the "eq" operator is supported
+// in the filter. StartIndex is 1-based, and Count defaults to the maximum
+// number of the resources in a page.
+type ScimListRequest struct {
+	Filter     string
+	StartIndex int
+	Count      *int
+}
+
+// ScimListResponse is a page of the resources of a query
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+} // @name ScimListResponse
+
+// ScimPatchOperation is an operation of a PATCH request
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+} // @name ScimPatchOperation
+
+// ScimPatchRequest modifies a resource by the operations in order
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+} // @name ScimPatchRequest
+
+// ScimFeature tells whether a feature of the API is supported
+type ScimFeature struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults,omitempty"`
+} // @name ScimFeature
+
+// ScimAuthenticationScheme is an authentication scheme of the API
+type ScimAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+} // @name ScimAuthenticationScheme
+
+// ScimServiceProviderConfig describes the features of the API
+type ScimServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 ScimFeature                `json:"patch"`
+	Bulk                  ScimFeature                `json:"bulk"`
+	Filter                ScimFeature                `json:"filter"`
+	ChangePassword        ScimFeature                `json:"changePassword"`
+	Sort                  ScimFeature                `json:"sort"`
+	Etag                  ScimFeature                `json:"etag"`
+	AuthenticationSchemes []ScimAuthenticationScheme `json:"authenticationSchemes"`
+} // @name ScimServiceProviderConfig
@@ -0,0 +1,27 @@
+//go:generate mockgen -source scim_group_repository.go -destination mock/scim_group_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// GroupQuery filters the groups of an organization like UserQuery
+type GroupQuery struct {
+	Organization string
+	DisplayName  string
+	ExternalId   string
+	Offset       int
+	Limit        int
+}
+
+type ScimGroupRepository interface {
+	GetGroup(ctx context.Context, organization string, id string) (*model.ScimGroup, error)
+	FindGroups(ctx context.Context, q *GroupQuery) ([]*model.ScimGroup, int, error)
+	CreateGroup(ctx context.Context, group *model.ScimGroup) error
+	UpdateGroup(ctx context.Context, group *model.ScimGroup) error
+	DeleteGroup(ctx context.Context, organization string, id string) error
+	// RemoveMember removes the user from the groups of the organization
+	RemoveMember(ctx context.Context, organization string, userId string) error
+}
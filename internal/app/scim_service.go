@@ -0,0 +1,35 @@
+//go:generate mockgen -source scim_service.go -destination mock/scim_service_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// ScimService provisions the users and the groups of an organization from its
+// directory. The resources of other organizations are not visible.
+type ScimService interface {
+	// Authenticate returns the organization of the bearer token
+	Authenticate(ctx context.Context, token string) (string, error)
+	// CreateToken creates a bearer token of an organization. The token is
+	// only returned in the response.
+	CreateToken(ctx context.Context, r *CreateScimTokenRequest) (*CreateScimTokenResponse, error)
+	GetTokens(ctx context.Context) ([]*model.ScimToken, error)
+	DeleteToken(ctx context.Context, id string) error
+
+	GetUsers(ctx context.Context, organization string, r *ScimListRequest) (*ScimListResponse, error)
+	GetUser(ctx context.Context, organization string, id string) (*ScimUser, error)
+	CreateUser(ctx context.Context, organization string, u *ScimUser) (*ScimUser, error)
+	ReplaceUser(ctx context.Context, organization string, id string, u *ScimUser) (*ScimUser, error)
+	PatchUser(ctx context.Context, organization string, id string, r *ScimPatchRequest) (*ScimUser, error)
+	// DeleteUser deletes the user, which invalidates the tokens of the user
+	DeleteUser(ctx context.Context, organization string, id string) error
+
+	GetGroups(ctx context.Context, organization string, r *ScimListRequest) (*ScimListResponse, error)
+	GetGroup(ctx context.Context, organization string, id string) (*ScimGroup, error)
+	CreateGroup(ctx context.Context, organization string, g *ScimGroup) (*ScimGroup, error)
+	ReplaceGroup(ctx context.Context, organization string, id string, g *ScimGroup) (*ScimGroup, error)
+	PatchGroup(ctx context.Context, organization string, id string, r *ScimPatchRequest) (*ScimGroup, error)
+	DeleteGroup(ctx context.Context, organization string, id string) error
+}
@@ -0,0 +1,15 @@
+//go:generate mockgen -source scim_token_repository.go -destination mock/scim_token_repository_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type ScimTokenRepository interface {
+	GetToken(ctx context.Context, id string) (*model.ScimToken, error)
+	GetTokens(ctx context.Context) ([]*model.ScimToken, error)
+	CreateToken(ctx context.Context, token *model.ScimToken) error
+	DeleteToken(ctx context.Context, id string) error
+}
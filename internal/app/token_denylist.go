@@ -0,0 +1,14 @@
+//go:generate mockgen -source token_denylist.go -destination mock/token_denylist_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+// TokenDenylist keeps the revoked access tokens until they expire
+type TokenDenylist interface {
+	Add(ctx context.Context, token *model.RevokedToken) error
+	Contains(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scopes of OpenID Connect. They are always supported, so they do not have
+// to be configured.
+const (
+	ScopeOpenId  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// OpenIdScopes are the scopes of OpenID Connect
+var OpenIdScopes = []string{ScopeOpenId, ScopeProfile, ScopeEmail}
+
+// TokenGrant is the audience and the space delimited scope which a token is
+// issued for. Empty fields fall back to the configured defaults.
+type TokenGrant struct {
+	Audience string
+	Scope    string
+	// ClientId is the OAuth client which the tokens are issued to on behalf
+	// of the user, if any
+	ClientId string
+}
+
+// ParseScope splits the space delimited scope into sorted unique scopes
+func ParseScope(scope string) []string {
+	seen := make(map[string]bool)
+	scopes := make([]string, 0)
+
+	for _, s := range strings.Fields(scope) {
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+
+	sort.Strings(scopes)
+
+	return scopes
+}
+
+// HasScopes returns true if the space delimited scope contains all of the
+// required scopes
+func HasScopes(scope string, required ...string) bool {
+	granted := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		granted[s] = true
+	}
+
+	for _, r := range required {
+		if !granted[r] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,14 +4,60 @@ package app
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
 )
 
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+)
+
+// IdTokenRequest describes the ID token of an OpenID Connect authentication.
+// ClientId is the audience of the token and Scope selects its user claims.
+type IdTokenRequest struct {
+	ClientId string
+	Scope    string
+	Nonce    string
+	AuthTime time.Time
+}
+
+// DelegatedTokenRequest describes an access token of the user which the actor
+// uses on behalf of the user. The token does not outlive NotAfter.
+type DelegatedTokenRequest struct {
+	Grant    *TokenGrant
+	Actor    *Actor
+	NotAfter time.Time
+}
+
 type TokenService interface {
-	GenerateAccessToken(ctx context.Context, user *model.User) (string, error)
-	GenerateRefreshToken(ctx context.Context, user *model.User) (string, error)
+	// ResolveGrant validates the requested grant of the user and fills in the
+	// defaults
+	ResolveGrant(ctx context.Context, user *model.User, grant *TokenGrant) (*TokenGrant, error)
+	GenerateAccessToken(ctx context.Context, user *model.User, grant *TokenGrant) (string, error)
+	GenerateRefreshToken(ctx context.Context, user *model.User, grant *TokenGrant) (string, error)
+	// GenerateDelegatedAccessToken generates an access token of the user with
+	// the act claim of the actor (RFC 8693)
+	GenerateDelegatedAccessToken(ctx context.Context, user *model.User, r *DelegatedTokenRequest) (string, error)
+	// GenerateIdToken generates an OpenID Connect ID token for the client
+	GenerateIdToken(ctx context.Context, user *model.User, r *IdTokenRequest) (string, error)
+	// ResolveClientGrant validates the requested grant of the client and
+	// fills in the defaults
+	ResolveClientGrant(ctx context.Context, client *model.OAuthClient, grant *TokenGrant) (*TokenGrant, error)
+	// GenerateClientAccessToken generates an access token whose subject is
+	// the client
+	GenerateClientAccessToken(ctx context.Context, client *model.OAuthClient, grant *TokenGrant) (string, error)
 	ParseToken(ctx context.Context, token string) (Claims, error)
 	ValidateAccessTokenFromRequest(ctx context.Context, r *http.Request) (Claims, error)
 	ValidateRefreshToken(ctx context.Context, token string) (string, error)
+	// RefreshTokenGrant returns the audience and the scope which the refresh
+	// token was issued for
+	RefreshTokenGrant(ctx context.Context, token string) (*TokenGrant, error)
+	RotateRefreshToken(ctx context.Context, token string, user *model.User) (string, error)
+	RevokeRefreshToken(ctx context.Context, token string) error
+	KeySet(ctx context.Context) *JSONWebKeySet
+	Introspect(ctx context.Context, r *IntrospectionRequest) (*IntrospectionResponse, error)
+	Revoke(ctx context.Context, r *RevocationRequest) error
+	DenyAccessToken(ctx context.Context, r *DenyTokenRequest) error
 }
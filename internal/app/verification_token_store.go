@@ -0,0 +1,18 @@
+//go:generate mockgen -source verification_token_store.go -destination mock/verification_token_store_mock.go -package mock
+package app
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+type VerificationTokenStore interface {
+	Save(ctx context.Context, token *model.VerificationToken) error
+	// Consume returns the token of the purpose and removes it, so that a
+	// token can be used only once. It returns ErrVerificationTokenNotFound if
+	// the token does not exist, is expired or is already consumed.
+	Consume(ctx context.Context, id string, purpose string) (*model.VerificationToken, error)
+	// DeleteByUser removes the tokens of the purpose issued to the user
+	DeleteByUser(ctx context.Context, userId string, purpose string) error
+}
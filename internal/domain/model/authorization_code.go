@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// AuthorizationCode is an authorization code of the authorization code flow.
+// The code itself is not stored, the id is its hash.
+type AuthorizationCode struct {
+	Id                  string    `json:"id" bson:"_id"`
+	ClientId            string    `json:"client_id" bson:"client_id"`
+	UserId              string    `json:"user_id" bson:"user_id"`
+	RedirectUri         string    `json:"redirect_uri" bson:"redirect_uri"`
+	Audience            string    `json:"audience,omitempty" bson:"audience,omitempty"`
+	Scope               string    `json:"scope,omitempty" bson:"scope,omitempty"`
+	CodeChallenge       string    `json:"code_challenge" bson:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method" bson:"code_challenge_method"`
+	Nonce               string    `json:"nonce,omitempty" bson:"nonce,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt           time.Time `json:"created_at" bson:"created_at"`
+} // @name AuthorizationCode
+
+// IsExpired returns true if the code is expired
+func (c *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
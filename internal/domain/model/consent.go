@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Consent records the scopes which a user granted to an OAuth client.
+// CreatedAt is the first and LastUsedAt is the last time the client got
+// tokens of the user.
+type Consent struct {
+	UserId     string    `json:"user_id" bson:"user_id"`
+	ClientId   string    `json:"client_id" bson:"client_id"`
+	Scopes     []string  `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" bson:"last_used_at"`
+} // @name Consent
+
+// HasScopes returns true if all of the scopes are granted
+func (c *Consent) HasScopes(scopes ...string) bool {
+	granted := make(map[string]bool)
+	for _, s := range c.Scopes {
+		granted[s] = true
+	}
+
+	for _, s := range scopes {
+		if !granted[s] {
+			return false
+		}
+	}
+
+	return true
+}
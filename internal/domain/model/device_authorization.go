@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// DeviceAuthorization is an authorization request of the device flow. The
+// device code itself is not stored, the id is its hash. Audience and Scope
+// are the granted ones once the request is approved.
+type DeviceAuthorization struct {
+	Id           string    `json:"id" bson:"_id"`
+	UserCode     string    `json:"user_code" bson:"user_code"`
+	ClientId     string    `json:"client_id" bson:"client_id"`
+	Status       string    `json:"status" bson:"status"`
+	UserId       string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Audience     string    `json:"audience,omitempty" bson:"audience,omitempty"`
+	Scope        string    `json:"scope,omitempty" bson:"scope,omitempty"`
+	Interval     int       `json:"interval" bson:"interval"`
+	LastPolledAt time.Time `json:"last_polled_at,omitempty" bson:"last_polled_at,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+} // @name DeviceAuthorization
+
+// IsExpired returns true if the request is expired
+func (d *DeviceAuthorization) IsExpired() bool {
+	return time.Now().After(d.ExpiresAt)
+}
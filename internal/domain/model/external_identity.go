@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// ExternalIdentity is an identity of the user at an upstream OpenID Connect
+// or SAML provider. Subject is the id of the user at the provider.
+type ExternalIdentity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	Email    string    `json:"email,omitempty" bson:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+} // @name ExternalIdentity
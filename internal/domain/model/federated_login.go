@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// FederatedLogin is a login started at an upstream provider which waits for
+// the callback. The state itself is not stored, the id is its hash. For SAML
+// connections Nonce is the id of the authentication request.
+type FederatedLogin struct {
+	Id           string    `json:"id" bson:"_id"`
+	Provider     string    `json:"provider" bson:"provider"`
+	Nonce        string    `json:"nonce" bson:"nonce"`
+	CodeVerifier string    `json:"code_verifier" bson:"code_verifier"`
+	RedirectUri  string    `json:"redirect_uri" bson:"redirect_uri"`
+	Audience     string    `json:"audience,omitempty" bson:"audience,omitempty"`
+	Scope        string    `json:"scope,omitempty" bson:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+} // @name FederatedLogin
+
+// IsExpired returns true if the login is expired
+func (l *FederatedLogin) IsExpired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// OAuthClient is a registered client. Secret is the hash of the secret of a
+// confidential client.
+type OAuthClient struct {
+	Id           string    `json:"id" bson:"_id"`
+	Name         string    `json:"name" bson:"name"`
+	RedirectUris []string  `json:"redirect_uris" bson:"redirect_uris"`
+	Scopes       []string  `json:"scopes" bson:"scopes"`
+	Audience     string    `json:"audience,omitempty" bson:"audience,omitempty"`
+	Secret       string    `json:"-" bson:"secret,omitempty"`
+	Confidential bool      `json:"confidential" bson:"confidential"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+} // @name OAuthClient
+
+// HasRedirectUri returns true if the uri is registered for the client. Uris
+// are compared exactly as recommended by RFC 6749.
+func (c *OAuthClient) HasRedirectUri(uri string) bool {
+	for _, u := range c.RedirectUris {
+		if u == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsScopes returns true if all of the scopes are allowed for the client
+func (c *OAuthClient) AllowsScopes(scopes ...string) bool {
+	allowed := make(map[string]bool)
+	for _, s := range c.Scopes {
+		allowed[s] = true
+	}
+
+	for _, s := range scopes {
+		if !allowed[s] {
+			return false
+		}
+	}
+
+	return true
+}
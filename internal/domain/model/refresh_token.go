@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	Id         string    `json:"id" bson:"_id"`
+	UserId     string    `json:"user_id" bson:"user_id"`
+	FamilyId   string    `json:"family_id" bson:"family_id"`
+	ReplacedBy string    `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	Revoked    bool      `json:"revoked" bson:"revoked"`
+	Audience   string    `json:"audience,omitempty" bson:"audience,omitempty"`
+	Scope      string    `json:"scope,omitempty" bson:"scope,omitempty"`
+	ClientId   string    `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+} // @name RefreshToken
+
+// IsRotated returns true if the token has already been exchanged for a new one
+func (t *RefreshToken) IsRotated() bool {
+	return t.ReplacedBy != ""
+}
+
+// IsExpired returns true if the token is expired
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type RevokedToken struct {
+	Id        string    `json:"id" bson:"_id"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at" bson:"revoked_at"`
+} // @name RevokedToken
+
+// IsExpired returns true if the revoked token is expired, so that it does not
+// need to be denied anymore
+func (t *RevokedToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
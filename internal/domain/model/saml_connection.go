@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// SamlConnection is the SAML 2.0 identity provider of an organization. Id is
+// the slug of the organization which is used in the urls of the service
+// provider. IdpCertificates are the base64 encoded DER certificates which
+// sign the assertions.
+type SamlConnection struct {
+	Id               string               `json:"id" bson:"_id"`
+	Name             string               `json:"name" bson:"name"`
+	IdpEntityId      string               `json:"idp_entity_id" bson:"idp_entity_id"`
+	IdpSsoUrl        string               `json:"idp_sso_url" bson:"idp_sso_url"`
+	IdpCertificates  []string             `json:"idp_certificates" bson:"idp_certificates"`
+	Domains          []string             `json:"domains" bson:"domains"`
+	AttributeMapping SamlAttributeMapping `json:"attribute_mapping" bson:"attribute_mapping"`
+	CreatedAt        time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at" bson:"updated_at"`
+} // @name SamlConnection
+
+// SamlAttributeMapping maps the attributes of the assertions to the fields of
+// the users. The NameID is used as the email when Email is empty.
+type SamlAttributeMapping struct {
+	Email     string `json:"email,omitempty" bson:"email,omitempty"`
+	FirstName string `json:"first_name,omitempty" bson:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty" bson:"last_name,omitempty"`
+} // @name SamlAttributeMapping
+
+// Provider returns the provider name of the identities of the connection
+func (c *SamlConnection) Provider() string {
+	return "saml:" + c.Id
+}
+
+// AllowsEmail returns true if the email belongs to one of the domains of the
+// organization
+func (c *SamlConnection) AllowsEmail(email string) bool {
+	i := strings.LastIndex(email, "@")
+	if i < 1 {
+		return false
+	}
+
+	domain := strings.ToLower(email[i+1:])
+	for _, d := range c.Domains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+
+	return false
+}
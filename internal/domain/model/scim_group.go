@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ScimGroup is a group of the users of an organization. Members are the ids
+// of the users.
+type ScimGroup struct {
+	Id           string    `json:"id" bson:"_id"`
+	Organization string    `json:"organization" bson:"organization"`
+	DisplayName  string    `json:"display_name" bson:"display_name"`
+	ExternalId   string    `json:"external_id,omitempty" bson:"external_id,omitempty"`
+	Members      []string  `json:"members" bson:"members"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+} // @name ScimGroup
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// ScimToken is a bearer token of the SCIM API of an organization. Secret is
+// the hash of the secret part of the token.
+type ScimToken struct {
+	Id           string    `json:"id" bson:"_id"`
+	Organization string    `json:"organization" bson:"organization"`
+	Name         string    `json:"name" bson:"name"`
+	Secret       string    `json:"-" bson:"secret"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+} // @name ScimToken
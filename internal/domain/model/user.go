@@ -14,10 +14,36 @@ type User struct {
 	Password  string             `json:"-,omitempty" bson:"password,omitempty" redis:"password" validate:"omitempty,required,gte=6,lte=60"`
 	Role      string             `json:"role,omitempty" bson:"role,omitempty" redis:"role" validate:"omitempty,lte=10"`
 	Avatar    string             `json:"avatar,omitempty" bson:"avatar,omitempty" redis:"avatar" validate:"omitempty"`
+	Phone     string             `json:"phone,omitempty" bson:"phone,omitempty" redis:"phone" validate:"omitempty,e164"`
 	CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" redis:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" redis:"updated_at"`
+	// EmailVerified is set once the user proves the ownership of the email
+	EmailVerified bool `json:"email_verified" bson:"email_verified,omitempty" redis:"email_verified"`
+	// Identities are the linked identities at the upstream providers
+	Identities []ExternalIdentity `json:"identities,omitempty" bson:"identities,omitempty" redis:"-"`
+	// TokenGeneration is increased to invalidate all tokens issued to the user
+	TokenGeneration int `json:"-" bson:"token_generation,omitempty" redis:"token_generation"`
+	// Organization is the corporate account which provisions the user, and
+	// ExternalId is the id of the user in the directory of the organization
+	Organization string `json:"organization,omitempty" bson:"organization,omitempty" redis:"-"`
+	ExternalId   string `json:"external_id,omitempty" bson:"external_id,omitempty" redis:"-"`
+	// Disabled users can not login and their tokens are rejected
+	Disabled bool `json:"disabled,omitempty" bson:"disabled,omitempty" redis:"disabled"`
+	// DeleteAt is the time when the account is deleted. Logging in before it
+	// cancels the deletion.
+	DeleteAt *time.Time `json:"delete_at,omitempty" bson:"delete_at,omitempty" redis:"-"`
+	// Deleted users are tombstones whose personal fields are anonymized, so
+	// that the references of the other services still resolve to them
+	Deleted bool `json:"deleted,omitempty" bson:"deleted,omitempty" redis:"-"`
 } // @name User
 
+// DeletedUserFirstName and DeletedUserLastName are the names of the deleted
+// users
+const (
+	DeletedUserFirstName = "Deleted"
+	DeletedUserLastName  = "User"
+)
+
 // GetId returns the user id
 func (u *User) GetId() primitive.ObjectID {
 	return u.Id
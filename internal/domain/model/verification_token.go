@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Purposes of the verification tokens
+const (
+	VerificationPurposeEmail    = "email"
+	VerificationPurposePassword = "password"
+)
+
+// VerificationToken is a single use token sent to the email of the user. The
+// token itself is not stored, the id is its hash.
+type VerificationToken struct {
+	Id        string    `json:"id" bson:"_id"`
+	UserId    string    `json:"user_id" bson:"user_id"`
+	Purpose   string    `json:"purpose" bson:"purpose"`
+	Email     string    `json:"email" bson:"email"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+} // @name VerificationToken
+
+// IsExpired returns true if the token is expired
+func (t *VerificationToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -2,6 +2,9 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/orkungursel/hey-taxi-identity-api/config"
@@ -12,6 +15,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// accountDeletionBatchSize is the number of the accounts which are deleted
+// at once
+const accountDeletionBatchSize = 100
+
 type AuthService struct {
 	app.AuthService
 	config *config.Config
@@ -19,19 +26,25 @@ type AuthService struct {
 	repo   app.Repository
 	ts     app.TokenService
 	pws    app.PasswordService
+	vts    app.VerificationTokenStore
+	mailer app.Mailer
 }
 
-func NewAuthService(config *config.Config, logger logger.ILogger, repo app.Repository, ts app.TokenService, pws app.PasswordService) *AuthService {
+func NewAuthService(config *config.Config, logger logger.ILogger, repo app.Repository, ts app.TokenService, pws app.PasswordService, vts app.VerificationTokenStore, mailer app.Mailer) *AuthService {
 	return &AuthService{
 		config: config,
 		logger: logger,
 		repo:   repo,
 		ts:     ts,
 		pws:    pws,
+		vts:    vts,
+		mailer: mailer,
 	}
 }
 
-// Login is used to authenticate user
+// Login is used to authenticate user. Users with an unverified email are
+// refused when the verification is required, and the scheduled deletion of
+// the account is cancelled.
 func (s *AuthService) Login(ctx context.Context, r *app.LoginRequest) (*app.LoginResponse, error) {
 	if err := app.Validate(r); err != nil {
 		s.logger.Debugf("invalid login request: %s", err)
@@ -51,13 +64,26 @@ func (s *AuthService) Login(ctx context.Context, r *app.LoginRequest) (*app.Logi
 		return nil, errors.New("invalid email or password")
 	}
 
-	accessToken, err := s.ts.GenerateAccessToken(ctx, user)
+	if s.config.Auth.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, app.ErrEmailNotVerified
+	}
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: r.Audience, Scope: r.Scope, ClientId: r.ClientId})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cancelAccountDeletion(ctx, s.repo, s.logger, user); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
 	if err != nil {
 		s.logger.Warnf("failed to generate access token: %s", err)
 		return nil, err
 	}
 
-	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user)
+	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user, grant)
 	if err != nil {
 		s.logger.Warnf("failed to generate refresh token: %s", err)
 		return nil, err
@@ -69,10 +95,13 @@ func (s *AuthService) Login(ctx context.Context, r *app.LoginRequest) (*app.Logi
 		AccessTokenExpiresIn:  s.config.Jwt.AccessTokenExp,
 		RefreshToken:          refreshToken,
 		RefreshTokenExpiresIn: s.config.Jwt.RefreshTokenExp,
+		Scope:                 grant.Scope,
 	}, nil
 }
 
-// Register is used to register new user
+// Register is used to register new user. A verification token is sent to
+// the email, and no tokens are issued until the email is verified when the
+// verification is required.
 func (s *AuthService) Register(ctx context.Context, r *app.RegisterRequest) (*app.LoginResponse, error) {
 	if err := app.Validate(r); err != nil {
 		s.logger.Warnf("invalid register request: %s", err)
@@ -92,6 +121,10 @@ func (s *AuthService) Register(ctx context.Context, r *app.RegisterRequest) (*ap
 	user := &model.User{
 		Email:     r.Email,
 		Password:  hashedPassword,
+		FirstName: strings.TrimSpace(r.FirstName),
+		LastName:  strings.TrimSpace(r.LastName),
+		Avatar:    r.Avatar,
+		Phone:     r.Phone,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Role:      model.RoleUser,
@@ -109,13 +142,27 @@ func (s *AuthService) Register(ctx context.Context, r *app.RegisterRequest) (*ap
 
 	user.Id = objectId
 
-	accessToken, err := s.ts.GenerateAccessToken(ctx, user)
+	// the user can ask for a new one when it cannot be sent
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		s.logger.Warnf("failed to send verification email to user %s: %s", uid, err)
+	}
+
+	if s.config.Auth.RequireVerifiedEmail {
+		return &app.LoginResponse{UserDto: *app.UserResponseFromUser(user)}, nil
+	}
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: r.Audience, Scope: r.Scope})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
 	if err != nil {
 		s.logger.Warnf("failed to generate access token: %s", err)
 		return nil, err
 	}
 
-	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user)
+	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user, grant)
 	if err != nil {
 		s.logger.Warnf("failed to generate refresh token: %s", err)
 		return nil, err
@@ -127,6 +174,7 @@ func (s *AuthService) Register(ctx context.Context, r *app.RegisterRequest) (*ap
 		AccessTokenExpiresIn:  s.config.Jwt.AccessTokenExp,
 		RefreshToken:          refreshToken,
 		RefreshTokenExpiresIn: s.config.Jwt.RefreshTokenExp,
+		Scope:                 grant.Scope,
 	}, nil
 }
 
@@ -140,6 +188,68 @@ func (s *AuthService) Me(ctx context.Context, uid string) (*app.UserResponse, er
 	return app.UserResponseFromUser(user), nil
 }
 
+// UpdateProfile updates the fields of the profile which are set in the
+// request and returns the updated user
+func (s *AuthService) UpdateProfile(ctx context.Context, uid string, r *app.UpdateProfileRequest) (*app.UserResponse, error) {
+	update := *r
+	if update.FirstName != nil {
+		name := strings.TrimSpace(*update.FirstName)
+		update.FirstName = &name
+	}
+
+	if update.LastName != nil {
+		name := strings.TrimSpace(*update.LastName)
+		update.LastName = &name
+	}
+
+	// the empty avatar and phone remove them, so they are not validated
+	clearAvatar := update.Avatar != nil && *update.Avatar == ""
+	if clearAvatar {
+		update.Avatar = nil
+	}
+
+	clearPhone := update.Phone != nil && *update.Phone == ""
+	if clearPhone {
+		update.Phone = nil
+	}
+
+	if err := app.Validate(&update); err != nil {
+		s.logger.Debugf("invalid update profile request: %s", err)
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.FirstName != nil {
+		user.FirstName = *update.FirstName
+	}
+
+	if update.LastName != nil {
+		user.LastName = *update.LastName
+	}
+
+	if update.Avatar != nil || clearAvatar {
+		user.Avatar = stringValue(update.Avatar)
+	}
+
+	if update.Phone != nil || clearPhone {
+		user.Phone = stringValue(update.Phone)
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := s.repo.UpdateProfile(ctx, uid, user); err != nil {
+		return nil, err
+	}
+
+	return app.UserResponseFromUser(user), nil
+}
+
+// RefreshToken is used to exchange a refresh token for a new token pair. The
+// refresh tokens issued to a client are refused unless the client redeems
+// them.
 func (s *AuthService) RefreshToken(ctx context.Context, r *app.RefreshTokenRequest) (*app.RefreshTokenResponse, error) {
 	sub, err := s.ts.ValidateRefreshToken(ctx, r.Token)
 	if err != nil {
@@ -152,14 +262,542 @@ func (s *AuthService) RefreshToken(ctx context.Context, r *app.RefreshTokenReque
 		return nil, err
 	}
 
-	accessToken, err := s.ts.GenerateAccessToken(ctx, user)
+	grant, err := s.ts.RefreshTokenGrant(ctx, r.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.ClientId != "" && grant.ClientId != r.ClientId {
+		s.logger.Warnf("refresh token of client %s is presented by %q", grant.ClientId, r.ClientId)
+		return nil, app.ErrInvalidToken
+	}
+
+	if r.Scope != "" {
+		if !app.HasScopes(grant.Scope, app.ParseScope(r.Scope)...) {
+			return nil, app.ErrInvalidScope
+		}
+
+		grant = &app.TokenGrant{Audience: grant.Audience, Scope: r.Scope, ClientId: grant.ClientId}
+	}
+
+	grant, err = s.ts.ResolveGrant(ctx, user, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate access token: %s", err)
+		return nil, err
+	}
+
+	refreshToken, err := s.ts.RotateRefreshToken(ctx, r.Token, user)
+	if err != nil {
+		s.logger.Warnf("failed to rotate refresh token: %s", err)
+		return nil, err
+	}
+
+	return &app.RefreshTokenResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresIn:  s.config.Jwt.AccessTokenExp,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresIn: s.config.Jwt.RefreshTokenExp,
+		Scope:                 grant.Scope,
+		UserId:                sub,
+		ClientId:              grant.ClientId,
+	}, nil
+}
+
+// Logout is used to revoke the session of the given refresh token
+func (s *AuthService) Logout(ctx context.Context, r *app.LogoutRequest) error {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid logout request: %s", err)
+		return err
+	}
+
+	if err := s.ts.RevokeRefreshToken(ctx, r.Token); err != nil {
+		s.logger.Warnf("failed to revoke refresh token: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// LogoutAll is used to revoke all tokens of the user
+func (s *AuthService) LogoutAll(ctx context.Context, uid string) error {
+	if err := s.repo.IncrementTokenGeneration(ctx, uid); err != nil {
+		s.logger.Warnf("failed to revoke tokens of user %s: %s", uid, err)
+		return err
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email of the user as verified. The token is bound to
+// the email it is sent to, so that it cannot verify a changed email.
+func (s *AuthService) VerifyEmail(ctx context.Context, r *app.VerifyEmailRequest) error {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid verify email request: %s", err)
+		return err
+	}
+
+	t, err := s.vts.Consume(ctx, hashCode(r.Token), model.VerificationPurposeEmail)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUser(ctx, t.UserId)
+	if err != nil {
+		if errors.Is(err, app.ErrUserNotFound) {
+			return app.ErrVerificationTokenNotFound
+		}
+		return err
+	}
+
+	if user.Email != t.Email {
+		return app.ErrVerificationTokenNotFound
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	if err := s.repo.VerifyEmail(ctx, t.UserId, t.Email); err != nil {
+		if errors.Is(err, app.ErrUserNotFound) {
+			return app.ErrVerificationTokenNotFound
+		}
+		return err
+	}
+
+	s.logger.Infof("email of user %s is verified", t.UserId)
+
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification token to the email and
+// invalidates the previous ones. It succeeds for the unknown and the already
+// verified emails too, so that it does not reveal the registered emails.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, r *app.ResendVerificationEmailRequest) error {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid resend verification email request: %s", err)
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, r.Email)
+	if err != nil {
+		if errors.Is(err, app.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// ForgotPassword sends a password reset token to the email. It succeeds for
+// the unknown emails and when the token cannot be sent too, so that it does
+// not reveal the registered emails. The users of the organizations reset
+// their passwords at their identity providers.
+func (s *AuthService) ForgotPassword(ctx context.Context, r *app.ForgotPasswordRequest) error {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid forgot password request: %s", err)
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, r.Email)
+	if err != nil {
+		if errors.Is(err, app.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.Disabled || user.Organization != "" {
+		s.logger.Infof("password reset is not allowed for user %s", user.GetIdString())
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user, model.VerificationPurposePassword, s.config.Auth.PasswordResetExp)
+	if err != nil {
+		s.logger.Warnf("failed to issue password reset token to user %s: %s", user.GetIdString(), err)
+		return nil
+	}
+
+	if err := s.mailer.Send(ctx, &app.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("You can reset your password for %s with the following link:\n\n%s\n\nYou can ignore this email if you did not ask for it.\n", s.config.App.Name, s.tokenLink(s.config.Mail.ResetPasswordUrl, token)),
+	}); err != nil {
+		s.logger.Warnf("failed to send password reset email to user %s: %s", user.GetIdString(), err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets the password of the user with the token sent to the
+// email and revokes all tokens of the user. The email is verified by the
+// token too.
+func (s *AuthService) ResetPassword(ctx context.Context, r *app.ResetPasswordRequest) error {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid reset password request: %s", err)
+		return err
+	}
+
+	t, err := s.vts.Consume(ctx, hashCode(r.Token), model.VerificationPurposePassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUser(ctx, t.UserId)
+	if err != nil {
+		if errors.Is(err, app.ErrUserNotFound) {
+			return app.ErrVerificationTokenNotFound
+		}
+		return err
+	}
+
+	if user.Email != t.Email || user.Disabled {
+		return app.ErrVerificationTokenNotFound
+	}
+
+	hashedPassword, err := s.pws.Hash(ctx, r.Password)
+	if err != nil {
+		s.logger.Warnf("failed to hash password: %s", err)
+		return err
+	}
+
+	if err := s.repo.SetPassword(ctx, t.UserId, hashedPassword); err != nil {
+		return err
+	}
+
+	// the reset token was sent to the email, so it is verified as well
+	if !user.EmailVerified {
+		if err := s.repo.VerifyEmail(ctx, t.UserId, t.Email); err != nil && !errors.Is(err, app.ErrUserNotFound) {
+			return err
+		}
+	}
+
+	if err := s.repo.IncrementTokenGeneration(ctx, t.UserId); err != nil {
+		s.logger.Warnf("failed to revoke tokens of user %s: %s", t.UserId, err)
+		return err
+	}
+
+	if err := s.vts.DeleteByUser(ctx, t.UserId, model.VerificationPurposePassword); err != nil {
+		s.logger.Warnf("failed to delete password reset tokens of user %s: %s", t.UserId, err)
+	}
+
+	s.logger.Infof("password of user %s is reset", t.UserId)
+
+	return nil
+}
+
+// ChangePassword changes the password of the user after checking the current
+// one. Signing out the other devices revokes all tokens of the user, so that
+// a new token pair of the same audience and scope is returned for the current
+// device. The user is notified by email in both cases.
+func (s *AuthService) ChangePassword(ctx context.Context, claims app.Claims, r *app.ChangePasswordRequest) (*app.RefreshTokenResponse, error) {
+	if err := app.Validate(r); err != nil {
+		s.logger.Debugf("invalid change password request: %s", err)
+		return nil, err
+	}
+
+	// the clients and the actors of the delegated tokens cannot change the
+	// password
+	if !app.IsFirstPartyToken(claims) {
+		return nil, app.ErrUserTokenRequired
+	}
+
+	uid := claims.GetSubject()
+
+	user, err := s.repo.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.pws.Compare(ctx, user.Password, r.CurrentPassword); err != nil {
+		s.logger.Debugf("invalid current password: %s", err)
+		return nil, app.ErrInvalidCurrentPassword
+	}
+
+	hashedPassword, err := s.pws.Hash(ctx, r.NewPassword)
+	if err != nil {
+		s.logger.Warnf("failed to hash password: %s", err)
+		return nil, err
+	}
+
+	if err := s.repo.SetPassword(ctx, uid, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("password of user %s is changed", uid)
+
+	s.notify(ctx, user, "Your password was changed", fmt.Sprintf("The password of your %s account was changed. Please reset your password if it was not you.\n", s.config.App.Name))
+
+	if !r.LogoutOtherDevices {
+		return nil, nil
+	}
+
+	if err := s.repo.IncrementTokenGeneration(ctx, uid); err != nil {
+		s.logger.Warnf("failed to revoke tokens of user %s: %s", uid, err)
+		return nil, err
+	}
+
+	user.TokenGeneration++
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: claims.GetAudience(), Scope: claims.GetScope()})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
 	if err != nil {
 		s.logger.Warnf("failed to generate access token: %s", err)
 		return nil, err
 	}
 
+	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate refresh token: %s", err)
+		return nil, err
+	}
+
 	return &app.RefreshTokenResponse{
-		AccessToken:          accessToken,
-		AccessTokenExpiresIn: s.config.Jwt.AccessTokenExp,
+		AccessToken:           accessToken,
+		AccessTokenExpiresIn:  s.config.Jwt.AccessTokenExp,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresIn: s.config.Jwt.RefreshTokenExp,
+		Scope:                 grant.Scope,
 	}, nil
 }
+
+// DeleteAccount schedules the deletion of the account after the grace period
+// and revokes all tokens of the user, so that logging in is the only way to
+// cancel it. The account is deleted at once when there is no grace period.
+// The accounts of the organizations are left to their directories.
+func (s *AuthService) DeleteAccount(ctx context.Context, claims app.Claims) (*app.DeleteAccountResponse, error) {
+	// the clients and the actors of the delegated tokens cannot delete the
+	// account
+	if !app.IsFirstPartyToken(claims) {
+		return nil, app.ErrUserTokenRequired
+	}
+
+	uid := claims.GetSubject()
+
+	user, err := s.repo.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Organization != "" {
+		return nil, app.ErrAccountManaged
+	}
+
+	grace := time.Duration(s.config.Auth.AccountDeletionGracePeriod) * time.Second
+	deleteAt := time.Now().Add(grace)
+
+	if err := s.repo.ScheduleDeletion(ctx, uid, &deleteAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.IncrementTokenGeneration(ctx, uid); err != nil {
+		s.logger.Warnf("failed to revoke tokens of user %s: %s", uid, err)
+		return nil, err
+	}
+
+	if grace <= 0 {
+		s.notify(ctx, user, "Your account was deleted", fmt.Sprintf("Your %s account was deleted as you requested.\n", s.config.App.Name))
+
+		if err := s.deleteAccount(ctx, user); err != nil {
+			return nil, err
+		}
+
+		return &app.DeleteAccountResponse{DeleteAt: deleteAt}, nil
+	}
+
+	s.logger.Infof("deletion of user %s is scheduled at %s", uid, deleteAt.Format(time.RFC3339))
+
+	s.notify(ctx, user, "Your account will be deleted", fmt.Sprintf("Your %s account will be deleted on %s as you requested. Log in before then if you want to keep it.\n", s.config.App.Name, deleteAt.UTC().Format("January 2, 2006 15:04 MST")))
+
+	return &app.DeleteAccountResponse{DeleteAt: deleteAt}, nil
+}
+
+// WatchAccountDeletions deletes the accounts whose deletion is due every
+// AccountDeletionInterval seconds until the context is done
+func (s *AuthService) WatchAccountDeletions(ctx context.Context) {
+	if s.config.Auth.AccountDeletionInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.Auth.AccountDeletionInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if n := s.deleteDueAccounts(ctx); n > 0 {
+			s.logger.Infof("%d accounts are deleted", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteDueAccounts deletes the accounts whose deletion is due in batches and
+// returns the number of the deleted accounts. It stops at the first failure
+// and the rest is left to the next run.
+func (s *AuthService) deleteDueAccounts(ctx context.Context) int {
+	n := 0
+
+	for {
+		users, err := s.repo.GetUsersToDelete(ctx, time.Now(), accountDeletionBatchSize)
+		if err != nil {
+			s.logger.Warnf("failed to get users to delete: %s", err)
+			return n
+		}
+
+		for _, user := range users {
+			if err := s.deleteAccount(ctx, user); err != nil {
+				// the deletion is cancelled by a login in the meantime
+				if errors.Is(err, app.ErrUserNotFound) {
+					continue
+				}
+
+				return n
+			}
+
+			n++
+		}
+
+		if len(users) < accountDeletionBatchSize {
+			return n
+		}
+	}
+}
+
+// deleteAccount anonymizes the user and removes the tokens sent to the email
+func (s *AuthService) deleteAccount(ctx context.Context, user *model.User) error {
+	uid := user.GetIdString()
+
+	if err := s.repo.AnonymizeUser(ctx, uid); err != nil {
+		s.logger.Warnf("failed to anonymize user %s: %s", uid, err)
+		return err
+	}
+
+	for _, purpose := range []string{model.VerificationPurposeEmail, model.VerificationPurposePassword} {
+		if err := s.vts.DeleteByUser(ctx, uid, purpose); err != nil {
+			s.logger.Warnf("failed to delete %s tokens of user %s: %s", purpose, uid, err)
+		}
+	}
+
+	s.logger.Infof("user %s is deleted", uid)
+
+	return nil
+}
+
+// cancelAccountDeletion cancels the scheduled deletion of the user which
+// logged in
+func cancelAccountDeletion(ctx context.Context, repo app.Repository, logger logger.ILogger, user *model.User) error {
+	if user.DeleteAt == nil {
+		return nil
+	}
+
+	if err := repo.ScheduleDeletion(ctx, user.GetIdString(), nil); err != nil {
+		logger.Warnf("failed to cancel deletion of user %s: %s", user.GetIdString(), err)
+		return err
+	}
+
+	logger.Infof("deletion of user %s is cancelled by login", user.GetIdString())
+
+	user.DeleteAt = nil
+
+	return nil
+}
+
+// notify sends a security notification to the user. The failures are only
+// logged, since the change is already made.
+func (s *AuthService) notify(ctx context.Context, user *model.User, subject string, body string) {
+	if user.Email == "" {
+		return
+	}
+
+	if err := s.mailer.Send(ctx, &app.MailMessage{To: user.Email, Subject: subject, Body: body}); err != nil {
+		s.logger.Warnf("failed to send notification to user %s: %s", user.GetIdString(), err)
+	}
+}
+
+// sendVerificationEmail sends a new verification token to the email of the
+// user
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := s.issueToken(ctx, user, model.VerificationPurposeEmail, s.config.Auth.EmailVerificationExp)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &app.MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Please verify your email for %s with the following link:\n\n%s\n", s.config.App.Name, s.tokenLink(s.config.Mail.VerifyEmailUrl, token)),
+	})
+}
+
+// issueToken stores a new token of the purpose for the email of the user in
+// place of the previous ones and returns it. The token expires in exp
+// seconds.
+func (s *AuthService) issueToken(ctx context.Context, user *model.User, purpose string, exp int) (string, error) {
+	uid := user.GetIdString()
+
+	if err := s.vts.DeleteByUser(ctx, uid, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	if err := s.vts.Save(ctx, &model.VerificationToken{
+		Id:        hashCode(token),
+		UserId:    uid,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: now.Add(time.Duration(exp) * time.Second),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// tokenLink returns the link of the page with the token in its query, or the
+// token itself when the page is not configured
+func (s *AuthService) tokenLink(page string, token string) string {
+	if page == "" {
+		return token
+	}
+
+	sep := "?"
+	if strings.Contains(page, "?") {
+		sep = "&"
+	}
+
+	return page + sep + "token=" + url.QueryEscape(token)
+}
+
+// stringValue returns the value of the optional string, or the empty string
+// when it is not set
+func stringValue(v *string) string {
+	if v == nil {
+		return ""
+	}
+
+	return *v
+}
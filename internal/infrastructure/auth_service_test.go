@@ -2,9 +2,14 @@ package infrastructure
 
 import (
 	"context"
+	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
 	"github.com/orkungursel/hey-taxi-identity-api/config"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
@@ -51,7 +56,7 @@ func TestNewService(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewAuthService(tt.args.config, tt.args.logger, tt.args.repo, ts, pws); !reflect.DeepEqual(got, tt.want) {
+			if got := NewAuthService(tt.args.config, tt.args.logger, tt.args.repo, ts, pws, nil, nil); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewService() = %v, want %v", got, tt.want)
 			}
 		})
@@ -69,7 +74,7 @@ func TestAuthService_Login(t *testing.T) {
 	ts := NewMockTokenService(ctrl)
 	pws := NewMockPasswordService(ctrl)
 
-	service := NewAuthService(config, logger, repo, ts, pws)
+	service := NewAuthService(config, logger, repo, ts, pws, nil, nil)
 	ctx := context.Background()
 
 	repo.EXPECT().GetUserByEmail(ctx, gomock.Any()).
@@ -80,10 +85,15 @@ func TestAuthService_Login(t *testing.T) {
 			return nil, errors.New("not found")
 		}).AnyTimes()
 
-	ts.EXPECT().GenerateAccessToken(ctx, dummyAuthUser).
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+
+	ts.EXPECT().GenerateAccessToken(ctx, dummyAuthUser, gomock.Any()).
 		Return("access_token", nil).AnyTimes().MinTimes(1)
 
-	ts.EXPECT().GenerateRefreshToken(ctx, dummyAuthUser).
+	ts.EXPECT().GenerateRefreshToken(ctx, dummyAuthUser, gomock.Any()).
 		Return("refresh_token", nil).AnyTimes().MinTimes(1)
 
 	pws.EXPECT().Compare(ctx, dummyAuthUser.Password, gomock.Any()).
@@ -173,9 +183,18 @@ func TestAuthService_Register(t *testing.T) {
 	ts := NewMockTokenService(ctrl)
 	pws := NewMockPasswordService(ctrl)
 
-	service := NewAuthService(config, logger, repo, ts, pws)
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, logger, repo, ts, pws, NewInMemoryVerificationTokenStore(), mailer)
 
 	ctx := context.Background()
+	mailer.EXPECT().Send(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, m *app.MailMessage) error {
+			if m.To != dummyAuthUser2.Email {
+				t.Errorf("Mailer.Send() to = %v, want %v", m.To, dummyAuthUser2.Email)
+			}
+			return nil
+		}).Times(1)
+
 	repo.EXPECT().CreateUser(ctx, gomock.AssignableToTypeOf(&model.User{})).
 		Return(dummyAuthUser2.Id.Hex(), nil).Times(1)
 
@@ -187,10 +206,15 @@ func TestAuthService_Register(t *testing.T) {
 			return nil, errors.New("not found")
 		}).AnyTimes()
 
-	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any()).
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+
+	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any(), gomock.Any()).
 		Return("access_token", nil).AnyTimes().MinTimes(1)
 
-	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any()).
+	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any(), gomock.Any()).
 		Return("refresh_token", nil).AnyTimes().MinTimes(1)
 
 	pws.EXPECT().Hash(ctx, gomock.Any()).
@@ -277,7 +301,7 @@ func TestAuthService_Me(t *testing.T) {
 	ts := NewMockTokenService(ctrl)
 	pws := NewMockPasswordService(ctrl)
 
-	service := NewAuthService(config, logger, repo, ts, pws)
+	service := NewAuthService(config, logger, repo, ts, pws, nil, nil)
 
 	ctx := context.Background()
 	repo.EXPECT().GetUser(ctx, gomock.Any()).
@@ -331,3 +355,1016 @@ func TestAuthService_Me(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_RefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	logger := NewLoggerMock()
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	service := NewAuthService(config, logger, repo, ts, pws, nil, nil)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUser(ctx, dummyAuthUser.GetIdString()).
+		Return(dummyAuthUser, nil).AnyTimes()
+
+	ts.EXPECT().ValidateRefreshToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, token string) (string, error) {
+			if token == "refresh_token" {
+				return dummyAuthUser.GetIdString(), nil
+			}
+
+			return "", app.ErrRefreshTokenReused
+		}).AnyTimes()
+
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+
+	ts.EXPECT().GenerateAccessToken(ctx, dummyAuthUser, gomock.Any()).
+		Return("access_token", nil).AnyTimes()
+
+	ts.EXPECT().RefreshTokenGrant(ctx, "refresh_token").
+		Return(&app.TokenGrant{Audience: "driver-app", Scope: "rides:read rides:write", ClientId: "rider"}, nil).AnyTimes()
+
+	ts.EXPECT().RotateRefreshToken(ctx, "refresh_token", dummyAuthUser).
+		Return("rotated_refresh_token", nil).Times(2)
+
+	type args struct {
+		ctx context.Context
+		req *app.RefreshTokenRequest
+	}
+	tests := []struct {
+		name    string
+		args    args
+		svc     app.AuthService
+		want    *app.RefreshTokenResponse
+		wantErr bool
+	}{
+		{
+			name: "should return rotated tokens",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "refresh_token", ClientId: "rider"},
+			},
+			want: &app.RefreshTokenResponse{
+				AccessToken:           "access_token",
+				AccessTokenExpiresIn:  config.Jwt.AccessTokenExp,
+				RefreshToken:          "rotated_refresh_token",
+				RefreshTokenExpiresIn: config.Jwt.RefreshTokenExp,
+				Scope:                 "rides:read rides:write",
+				UserId:                dummyAuthUser.GetIdString(),
+				ClientId:              "rider",
+			},
+		},
+		{
+			name: "should narrow the scope",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "refresh_token", Scope: "rides:read", ClientId: "rider"},
+			},
+			want: &app.RefreshTokenResponse{
+				AccessToken:           "access_token",
+				AccessTokenExpiresIn:  config.Jwt.AccessTokenExp,
+				RefreshToken:          "rotated_refresh_token",
+				RefreshTokenExpiresIn: config.Jwt.RefreshTokenExp,
+				Scope:                 "rides:read",
+				UserId:                dummyAuthUser.GetIdString(),
+				ClientId:              "rider",
+			},
+		},
+		{
+			name: "should error when scope is wider than granted",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "refresh_token", Scope: "rides:read fleet:admin", ClientId: "rider"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error when the client of the token does not redeem it",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "refresh_token"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error when another client redeems the token",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "refresh_token", ClientId: "partner"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error when refresh token is reused",
+			svc:  service,
+			args: args{
+				ctx: ctx,
+				req: &app.RefreshTokenRequest{Token: "reused_refresh_token"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.svc.RefreshToken(tt.args.ctx, tt.args.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Service.RefreshToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Service.RefreshToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	logger := NewLoggerMock()
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	service := NewAuthService(config, logger, repo, ts, pws, nil, nil)
+	ctx := context.Background()
+
+	ts.EXPECT().RevokeRefreshToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, token string) error {
+			if token == "refresh_token" {
+				return nil
+			}
+
+			return app.ErrInvalidToken
+		}).AnyTimes()
+
+	tests := []struct {
+		name    string
+		req     *app.LogoutRequest
+		wantErr bool
+	}{
+		{
+			name: "should revoke refresh token",
+			req:  &app.LogoutRequest{Token: "refresh_token"},
+		},
+		{
+			name:    "should error when token is invalid",
+			req:     &app.LogoutRequest{Token: "invalid_token"},
+			wantErr: true,
+		},
+		{
+			name:    "should error when token is empty",
+			req:     &app.LogoutRequest{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.Logout(ctx, tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("Service.Logout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	logger := NewLoggerMock()
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	service := NewAuthService(config, logger, repo, ts, pws, nil, nil)
+	ctx := context.Background()
+
+	repo.EXPECT().IncrementTokenGeneration(ctx, dummyAuthUser.GetIdString()).
+		Return(nil).Times(1)
+	repo.EXPECT().IncrementTokenGeneration(ctx, dummyAuthUser2.GetIdString()).
+		Return(app.ErrUserNotFound).Times(1)
+
+	if err := service.LogoutAll(ctx, dummyAuthUser.GetIdString()); err != nil {
+		t.Errorf("Service.LogoutAll() error = %v, want nil", err)
+	}
+
+	if err := service.LogoutAll(ctx, dummyAuthUser2.GetIdString()); !errors.Is(err, app.ErrUserNotFound) {
+		t.Errorf("Service.LogoutAll() error = %v, want %v", err, app.ErrUserNotFound)
+	}
+}
+
+func TestAuthService_RequireVerifiedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	config.Auth.RequireVerifiedEmail = true
+
+	unverified := &model.User{Id: primitive.NewObjectID(), Email: "unverified@bar.com", Password: "password"}
+	verified := &model.User{Id: primitive.NewObjectID(), Email: "verified@bar.com", Password: "password", EmailVerified: true}
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, NewLoggerMock(), repo, ts, pws, NewInMemoryVerificationTokenStore(), mailer)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUserByEmail(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, email string) (*model.User, error) {
+			for _, u := range []*model.User{unverified, verified} {
+				if u.Email == email {
+					return u, nil
+				}
+			}
+			return nil, app.ErrUserNotFound
+		}).AnyTimes()
+	repo.EXPECT().CreateUser(ctx, gomock.Any()).Return(primitive.NewObjectID().Hex(), nil).AnyTimes()
+
+	pws.EXPECT().Compare(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hashedPassword string, password string) error {
+			if password == hashedPassword {
+				return nil
+			}
+			return errors.New("not match")
+		}).AnyTimes()
+	pws.EXPECT().Hash(ctx, gomock.Any()).Return("hash", nil).AnyTimes()
+
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, verified, gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, verified, gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	mailer.EXPECT().Send(ctx, gomock.Any()).Return(nil).AnyTimes()
+
+	tests := []struct {
+		name    string
+		req     *app.LoginRequest
+		wantErr error
+	}{
+		{
+			name: "should login when email is verified",
+			req:  &app.LoginRequest{Email: verified.Email, Password: "password"},
+		},
+		{
+			name:    "should refuse the login when email is not verified",
+			req:     &app.LoginRequest{Email: unverified.Email, Password: "password"},
+			wantErr: app.ErrEmailNotVerified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Login(ctx, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.Login() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && got.AccessToken != "access_token" {
+				t.Errorf("Service.Login() access token = %v, want access_token", got.AccessToken)
+			}
+		})
+	}
+
+	t.Run("should not issue tokens on register", func(t *testing.T) {
+		got, err := service.Register(ctx, &app.RegisterRequest{Email: "new@bar.com", Password: "password"})
+		if err != nil {
+			t.Fatalf("Service.Register() error = %v", err)
+		}
+
+		if got.AccessToken != "" || got.RefreshToken != "" || got.UserDto.Email != "new@bar.com" {
+			t.Errorf("Service.Register() = %+v", got)
+		}
+	})
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	config.Mail.VerifyEmailUrl = "https://hey-taxi.app/verify-email"
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com"}
+	other := &model.User{Id: primitive.NewObjectID(), Email: "verified@bar.com", EmailVerified: true}
+
+	repo := mock.NewMockRepository(ctrl)
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, NewLoggerMock(), repo, nil, nil, NewInMemoryVerificationTokenStore(), mailer)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUserByEmail(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, email string) (*model.User, error) {
+			for _, u := range []*model.User{user, other} {
+				if u.Email == email {
+					cp := *u
+					return &cp, nil
+				}
+			}
+			return nil, app.ErrUserNotFound
+		}).AnyTimes()
+	repo.EXPECT().GetUser(ctx, user.GetIdString()).
+		DoAndReturn(func(_ context.Context, _ string) (*model.User, error) {
+			cp := *user
+			return &cp, nil
+		}).AnyTimes()
+	repo.EXPECT().VerifyEmail(ctx, user.GetIdString(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, email string) error {
+			if email != user.Email {
+				return app.ErrUserNotFound
+			}
+			user.EmailVerified = true
+			return nil
+		}).AnyTimes()
+
+	var sent []string
+	mailer.EXPECT().Send(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, m *app.MailMessage) error {
+			i := strings.Index(m.Body, "?token=")
+			if m.To != user.Email || i < 0 {
+				t.Fatalf("Mailer.Send() = %+v", m)
+			}
+
+			token, err := url.QueryUnescape(strings.TrimSpace(m.Body[i+len("?token="):]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sent = append(sent, token)
+			return nil
+		}).AnyTimes()
+
+	resend := func(t *testing.T, email string) {
+		if err := service.ResendVerificationEmail(ctx, &app.ResendVerificationEmailRequest{Email: email}); err != nil {
+			t.Fatalf("Service.ResendVerificationEmail() error = %v", err)
+		}
+	}
+
+	t.Run("should not send a token to unknown or verified emails", func(t *testing.T) {
+		resend(t, "unknown@bar.com")
+		resend(t, other.Email)
+
+		if len(sent) != 0 {
+			t.Errorf("Service.ResendVerificationEmail() sent %d tokens, want 0", len(sent))
+		}
+	})
+
+	t.Run("should invalidate the previous token on resend", func(t *testing.T) {
+		resend(t, user.Email)
+		resend(t, user.Email)
+
+		err := service.VerifyEmail(ctx, &app.VerifyEmailRequest{Token: sent[0]})
+		if !errors.Is(err, app.ErrVerificationTokenNotFound) {
+			t.Errorf("Service.VerifyEmail() error = %v, want %v", err, app.ErrVerificationTokenNotFound)
+		}
+	})
+
+	t.Run("should fail when the email is changed after the token is sent", func(t *testing.T) {
+		resend(t, user.Email)
+		user.Email = "changed@bar.com"
+		defer func() { user.Email = "foo@bar.com" }()
+
+		err := service.VerifyEmail(ctx, &app.VerifyEmailRequest{Token: sent[len(sent)-1]})
+		if !errors.Is(err, app.ErrVerificationTokenNotFound) {
+			t.Errorf("Service.VerifyEmail() error = %v, want %v", err, app.ErrVerificationTokenNotFound)
+		}
+	})
+
+	t.Run("should verify the email once", func(t *testing.T) {
+		resend(t, user.Email)
+		token := sent[len(sent)-1]
+
+		if err := service.VerifyEmail(ctx, &app.VerifyEmailRequest{Token: token}); err != nil {
+			t.Fatalf("Service.VerifyEmail() error = %v", err)
+		}
+
+		if !user.EmailVerified {
+			t.Errorf("Service.VerifyEmail() email verified = false, want true")
+		}
+
+		err := service.VerifyEmail(ctx, &app.VerifyEmailRequest{Token: token})
+		if !errors.Is(err, app.ErrVerificationTokenNotFound) {
+			t.Errorf("Service.VerifyEmail() error = %v, want %v", err, app.ErrVerificationTokenNotFound)
+		}
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+	config.Mail.VerifyEmailUrl = "https://hey-taxi.app/verify-email"
+	config.Mail.ResetPasswordUrl = "https://hey-taxi.app/reset-password"
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", Password: "old"}
+	corporate := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", Organization: "acme"}
+
+	repo := mock.NewMockRepository(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, NewLoggerMock(), repo, nil, pws, NewInMemoryVerificationTokenStore(), mailer)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUserByEmail(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, email string) (*model.User, error) {
+			for _, u := range []*model.User{user, corporate} {
+				if u.Email == email {
+					cp := *u
+					return &cp, nil
+				}
+			}
+			return nil, app.ErrUserNotFound
+		}).AnyTimes()
+	repo.EXPECT().GetUser(ctx, user.GetIdString()).
+		DoAndReturn(func(_ context.Context, _ string) (*model.User, error) {
+			cp := *user
+			return &cp, nil
+		}).AnyTimes()
+	repo.EXPECT().SetPassword(ctx, user.GetIdString(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, password string) error {
+			user.Password = password
+			return nil
+		}).AnyTimes()
+	repo.EXPECT().VerifyEmail(ctx, user.GetIdString(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, email string) error {
+			if email != user.Email {
+				return app.ErrUserNotFound
+			}
+			user.EmailVerified = true
+			return nil
+		}).AnyTimes()
+	repo.EXPECT().IncrementTokenGeneration(ctx, user.GetIdString()).
+		DoAndReturn(func(_ context.Context, _ string) error {
+			user.TokenGeneration++
+			return nil
+		}).AnyTimes()
+
+	pws.EXPECT().Hash(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, password string) (string, error) {
+			return "hashed:" + password, nil
+		}).AnyTimes()
+
+	var sent []string
+	mailer.EXPECT().Send(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, m *app.MailMessage) error {
+			i := strings.Index(m.Body, "?token=")
+			if m.To != user.Email || i < 0 {
+				t.Fatalf("Mailer.Send() = %+v", m)
+			}
+
+			token, err := url.QueryUnescape(strings.Fields(m.Body[i+len("?token="):])[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sent = append(sent, token)
+			return nil
+		}).AnyTimes()
+
+	forgot := func(t *testing.T, email string) {
+		if err := service.ForgotPassword(ctx, &app.ForgotPasswordRequest{Email: email}); err != nil {
+			t.Fatalf("Service.ForgotPassword() error = %v", err)
+		}
+	}
+
+	wantTokenErr := func(t *testing.T, err error) {
+		t.Helper()
+
+		if !errors.Is(err, app.ErrVerificationTokenNotFound) {
+			t.Errorf("Service.ResetPassword() error = %v, want %v", err, app.ErrVerificationTokenNotFound)
+		}
+	}
+
+	t.Run("should answer the same way for unknown and corporate emails", func(t *testing.T) {
+		forgot(t, "unknown@bar.com")
+		forgot(t, corporate.Email)
+
+		if len(sent) != 0 {
+			t.Errorf("Service.ForgotPassword() sent %d tokens, want 0", len(sent))
+		}
+	})
+
+	t.Run("should fail when the token is of another purpose", func(t *testing.T) {
+		if err := service.ResendVerificationEmail(ctx, &app.ResendVerificationEmailRequest{Email: user.Email}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := service.ResetPassword(ctx, &app.ResetPasswordRequest{Token: sent[len(sent)-1], Password: "new-password"})
+		wantTokenErr(t, err)
+	})
+
+	t.Run("should fail when the password is too short", func(t *testing.T) {
+		forgot(t, user.Email)
+
+		if err := service.ResetPassword(ctx, &app.ResetPasswordRequest{Token: sent[len(sent)-1], Password: "new"}); err == nil {
+			t.Errorf("Service.ResetPassword() error = nil, want error")
+		}
+	})
+
+	t.Run("should set the password and revoke the sessions once", func(t *testing.T) {
+		forgot(t, user.Email)
+		token := sent[len(sent)-1]
+
+		if err := service.ResetPassword(ctx, &app.ResetPasswordRequest{Token: token, Password: "new-password"}); err != nil {
+			t.Fatalf("Service.ResetPassword() error = %v", err)
+		}
+
+		if user.Password != "hashed:new-password" || user.TokenGeneration != 1 || !user.EmailVerified {
+			t.Errorf("Service.ResetPassword() user = %+v", user)
+		}
+
+		err := service.ResetPassword(ctx, &app.ResetPasswordRequest{Token: token, Password: "other-password"})
+		wantTokenErr(t, err)
+	})
+
+	t.Run("should fail when the token is expired", func(t *testing.T) {
+		config.Auth.PasswordResetExp = -1
+		defer func() { config.Auth.PasswordResetExp = 3600 }()
+
+		forgot(t, user.Email)
+
+		err := service.ResetPassword(ctx, &app.ResetPasswordRequest{Token: sent[len(sent)-1], Password: "new-password"})
+		wantTokenErr(t, err)
+	})
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", Password: "current"}
+	uid := user.GetIdString()
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, NewLoggerMock(), repo, ts, pws, nil, mailer)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUser(ctx, uid).
+		DoAndReturn(func(_ context.Context, _ string) (*model.User, error) {
+			cp := *user
+			return &cp, nil
+		}).AnyTimes()
+	repo.EXPECT().SetPassword(ctx, uid, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, password string) error {
+			user.Password = password
+			return nil
+		}).AnyTimes()
+	repo.EXPECT().IncrementTokenGeneration(ctx, uid).
+		DoAndReturn(func(_ context.Context, _ string) error {
+			user.TokenGeneration++
+			return nil
+		}).AnyTimes()
+
+	pws.EXPECT().Compare(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hashedPassword string, password string) error {
+			if hashedPassword == password || hashedPassword == "hashed:"+password {
+				return nil
+			}
+			return errors.New("not match")
+		}).AnyTimes()
+	pws.EXPECT().Hash(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, password string) (string, error) {
+			return "hashed:" + password, nil
+		}).AnyTimes()
+
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, u *model.User, _ *app.TokenGrant) (string, error) {
+			return "access_token:" + strconv.Itoa(u.TokenGeneration), nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any(), gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	notified := 0
+	mailer.EXPECT().Send(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, m *app.MailMessage) error {
+			if m.To != user.Email {
+				t.Errorf("Mailer.Send() to = %v, want %v", m.To, user.Email)
+			}
+			notified++
+			return nil
+		}).AnyTimes()
+
+	claims := &Claims{Scope: "rides:read", StandardClaims: jwt.StandardClaims{Subject: uid, Audience: "rides"}}
+
+	tests := []struct {
+		name         string
+		claims       *Claims
+		req          *app.ChangePasswordRequest
+		want         *app.RefreshTokenResponse
+		wantErr      error
+		wantPassword string
+	}{
+		{
+			name:         "should fail when the current password is wrong",
+			claims:       claims,
+			req:          &app.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"},
+			wantErr:      app.ErrInvalidCurrentPassword,
+			wantPassword: "current",
+		},
+		{
+			name:         "should fail for the delegated tokens",
+			claims:       &Claims{Actor: &app.Actor{Subject: "support"}, StandardClaims: claims.StandardClaims},
+			req:          &app.ChangePasswordRequest{CurrentPassword: "current", NewPassword: "new-password"},
+			wantErr:      app.ErrUserTokenRequired,
+			wantPassword: "current",
+		},
+		{
+			name:         "should fail for the tokens of the clients",
+			claims:       &Claims{ClientId: "rider", StandardClaims: claims.StandardClaims},
+			req:          &app.ChangePasswordRequest{CurrentPassword: "current", NewPassword: "new-password"},
+			wantErr:      app.ErrUserTokenRequired,
+			wantPassword: "current",
+		},
+		{
+			name:         "should change the password",
+			claims:       claims,
+			req:          &app.ChangePasswordRequest{CurrentPassword: "current", NewPassword: "new-password"},
+			wantPassword: "hashed:new-password",
+		},
+		{
+			name:   "should sign out the other devices",
+			claims: claims,
+			req:    &app.ChangePasswordRequest{CurrentPassword: "new-password", NewPassword: "other-password", LogoutOtherDevices: true},
+			want: &app.RefreshTokenResponse{
+				AccessToken:           "access_token:1",
+				AccessTokenExpiresIn:  config.Jwt.AccessTokenExp,
+				RefreshToken:          "refresh_token",
+				RefreshTokenExpiresIn: config.Jwt.RefreshTokenExp,
+				Scope:                 "rides:read",
+			},
+			wantPassword: "hashed:other-password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notified = 0
+
+			got, err := service.ChangePassword(ctx, tt.claims, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.ChangePassword() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Service.ChangePassword() = %+v, want %+v", got, tt.want)
+			}
+
+			if user.Password != tt.wantPassword {
+				t.Errorf("Service.ChangePassword() password = %v, want %v", user.Password, tt.wantPassword)
+			}
+
+			if wantNotified := tt.wantErr == nil; (notified == 1) != wantNotified {
+				t.Errorf("Service.ChangePassword() notified = %d", notified)
+			}
+		})
+	}
+
+	t.Run("should fail when the new password is the current one", func(t *testing.T) {
+		_, err := service.ChangePassword(ctx, claims, &app.ChangePasswordRequest{CurrentPassword: "other-password", NewPassword: "other-password"})
+		if err == nil {
+			t.Errorf("Service.ChangePassword() error = nil, want error")
+		}
+	})
+}
+
+func TestAuthService_UpdateProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", FirstName: "Foo", LastName: "Bar", Phone: "+905551112233"}
+	uid := user.GetIdString()
+
+	repo := mock.NewMockRepository(ctrl)
+	service := NewAuthService(config.New(), NewLoggerMock(), repo, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUser(ctx, uid).
+		DoAndReturn(func(_ context.Context, _ string) (*model.User, error) {
+			cp := *user
+			return &cp, nil
+		}).AnyTimes()
+	repo.EXPECT().UpdateProfile(ctx, uid, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, u *model.User) error {
+			user.FirstName, user.LastName, user.Avatar, user.Phone = u.FirstName, u.LastName, u.Avatar, u.Phone
+			user.UpdatedAt = u.UpdatedAt
+			return nil
+		}).AnyTimes()
+
+	str := func(v string) *string { return &v }
+
+	tests := []struct {
+		name    string
+		req     *app.UpdateProfileRequest
+		want    *app.UserResponse
+		wantErr bool
+	}{
+		{
+			name: "should update only the fields which are set",
+			req:  &app.UpdateProfileRequest{FirstName: str("  Jane "), Avatar: str("https://cdn.hey-taxi.app/jane.png")},
+			want: &app.UserResponse{Id: uid, Email: "foo@bar.com", FirstName: "Jane", LastName: "Bar", Avatar: "https://cdn.hey-taxi.app/jane.png", Phone: "+905551112233", Role: model.RoleUser},
+		},
+		{
+			name: "should remove the avatar and the phone when they are empty",
+			req:  &app.UpdateProfileRequest{Avatar: str(""), Phone: str("")},
+			want: &app.UserResponse{Id: uid, Email: "foo@bar.com", FirstName: "Jane", LastName: "Bar", Role: model.RoleUser},
+		},
+		{
+			name:    "should fail when the first name is empty",
+			req:     &app.UpdateProfileRequest{FirstName: str(" ")},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when the last name is too long",
+			req:     &app.UpdateProfileRequest{LastName: str(strings.Repeat("a", 31))},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when the avatar is not a url",
+			req:     &app.UpdateProfileRequest{Avatar: str("avatar.png")},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when the phone is not in E.164 format",
+			req:     &app.UpdateProfileRequest{Phone: str("0555 111 22 33")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := user.UpdatedAt
+
+			got, err := service.UpdateProfile(ctx, uid, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Service.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if user.UpdatedAt != before {
+					t.Errorf("Service.UpdateProfile() updated the user on error")
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Service.UpdateProfile() = %+v, want %+v", got, tt.want)
+			}
+
+			if !user.UpdatedAt.After(before) {
+				t.Errorf("Service.UpdateProfile() updated at = %v, want after %v", user.UpdatedAt, before)
+			}
+		})
+	}
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := config.New()
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", Password: "password", FirstName: "Foo", LastName: "Bar"}
+	corporate := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", Organization: "acme"}
+	users := map[string]*model.User{user.GetIdString(): user, corporate.GetIdString(): corporate}
+
+	repo := mock.NewMockRepository(ctrl)
+	ts := NewMockTokenService(ctrl)
+	pws := NewMockPasswordService(ctrl)
+	vts := NewInMemoryVerificationTokenStore()
+	mailer := mock.NewMockMailer(ctrl)
+	service := NewAuthService(config, NewLoggerMock(), repo, ts, pws, vts, mailer)
+	ctx := context.Background()
+
+	repo.EXPECT().GetUser(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.User, error) {
+			if u, ok := users[id]; ok {
+				cp := *u
+				return &cp, nil
+			}
+			return nil, app.ErrUserNotFound
+		}).AnyTimes()
+	repo.EXPECT().GetUserByEmail(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, email string) (*model.User, error) {
+			for _, u := range users {
+				if u.Email == email {
+					cp := *u
+					return &cp, nil
+				}
+			}
+			return nil, app.ErrUserNotFound
+		}).AnyTimes()
+	repo.EXPECT().ScheduleDeletion(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string, at *time.Time) error {
+			users[id].DeleteAt = at
+			return nil
+		}).AnyTimes()
+	repo.EXPECT().IncrementTokenGeneration(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) error {
+			users[id].TokenGeneration++
+			return nil
+		}).AnyTimes()
+	repo.EXPECT().GetUsersToDelete(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, at time.Time, _ int) ([]*model.User, error) {
+			due := make([]*model.User, 0)
+			for _, u := range users {
+				if u.DeleteAt != nil && !u.DeleteAt.After(at) {
+					cp := *u
+					due = append(due, &cp)
+				}
+			}
+			return due, nil
+		}).AnyTimes()
+	repo.EXPECT().AnonymizeUser(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) error {
+			u := users[id]
+			*u = model.User{
+				Id:              u.Id,
+				FirstName:       model.DeletedUserFirstName,
+				LastName:        model.DeletedUserLastName,
+				TokenGeneration: u.TokenGeneration + 1,
+				Disabled:        true,
+				Deleted:         true,
+			}
+			return nil
+		}).AnyTimes()
+
+	pws.EXPECT().Compare(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hashedPassword string, password string) error {
+			if hashedPassword == password {
+				return nil
+			}
+			return errors.New("not match")
+		}).AnyTimes()
+
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any(), gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any(), gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	var subjects []string
+	mailer.EXPECT().Send(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, m *app.MailMessage) error {
+			subjects = append(subjects, m.Subject)
+			return nil
+		}).AnyTimes()
+
+	claims := &Claims{StandardClaims: jwt.StandardClaims{Subject: user.GetIdString()}}
+
+	tests := []struct {
+		name    string
+		claims  *Claims
+		wantErr error
+	}{
+		{
+			name:    "should fail for the delegated tokens",
+			claims:  &Claims{Actor: &app.Actor{Subject: "support"}, StandardClaims: claims.StandardClaims},
+			wantErr: app.ErrUserTokenRequired,
+		},
+		{
+			name:    "should fail for the tokens of the clients",
+			claims:  &Claims{ClientId: "rider", StandardClaims: claims.StandardClaims},
+			wantErr: app.ErrUserTokenRequired,
+		},
+		{
+			name:    "should fail for the users of the organizations",
+			claims:  &Claims{StandardClaims: jwt.StandardClaims{Subject: corporate.GetIdString()}},
+			wantErr: app.ErrAccountManaged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.DeleteAccount(ctx, tt.claims); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.DeleteAccount() error = %v, want %v", err, tt.wantErr)
+			}
+
+			for _, u := range users {
+				if u.DeleteAt != nil || u.TokenGeneration != 0 {
+					t.Errorf("Service.DeleteAccount() user %s is scheduled for deletion", u.GetIdString())
+				}
+			}
+		})
+	}
+
+	t.Run("should schedule the deletion and revoke the tokens", func(t *testing.T) {
+		subjects = nil
+
+		got, err := service.DeleteAccount(ctx, claims)
+		if err != nil {
+			t.Fatalf("Service.DeleteAccount() error = %v", err)
+		}
+
+		grace := time.Duration(config.Auth.AccountDeletionGracePeriod) * time.Second
+		if d := time.Until(got.DeleteAt); d > grace || d < grace-time.Minute {
+			t.Errorf("Service.DeleteAccount() delete at = %v, want in %v", got.DeleteAt, grace)
+		}
+
+		if user.DeleteAt == nil || !user.DeleteAt.Equal(got.DeleteAt) {
+			t.Errorf("Service.DeleteAccount() scheduled at = %v, want %v", user.DeleteAt, got.DeleteAt)
+		}
+
+		if user.TokenGeneration != 1 {
+			t.Errorf("Service.DeleteAccount() token generation = %d, want 1", user.TokenGeneration)
+		}
+
+		if len(subjects) != 1 {
+			t.Errorf("Service.DeleteAccount() notifications = %v", subjects)
+		}
+
+		if n := service.deleteDueAccounts(ctx); n != 0 || user.Deleted {
+			t.Errorf("Service.deleteDueAccounts() = %d, want no deletion in the grace period", n)
+		}
+	})
+
+	t.Run("should cancel the deletion by login", func(t *testing.T) {
+		if _, err := service.Login(ctx, &app.LoginRequest{Email: user.Email, Password: user.Password}); err != nil {
+			t.Fatalf("Service.Login() error = %v", err)
+		}
+
+		if user.DeleteAt != nil {
+			t.Errorf("Service.Login() delete at = %v, want nil", user.DeleteAt)
+		}
+	})
+
+	t.Run("should anonymize the users after the grace period", func(t *testing.T) {
+		uid := user.GetIdString()
+
+		if _, err := service.DeleteAccount(ctx, claims); err != nil {
+			t.Fatalf("Service.DeleteAccount() error = %v", err)
+		}
+
+		token, err := service.issueToken(ctx, user, model.VerificationPurposePassword, config.Auth.PasswordResetExp)
+		if err != nil {
+			t.Fatalf("Service.issueToken() error = %v", err)
+		}
+
+		past := time.Now().Add(-time.Second)
+		user.DeleteAt = &past
+
+		if n := service.deleteDueAccounts(ctx); n != 1 {
+			t.Errorf("Service.deleteDueAccounts() = %d, want 1", n)
+		}
+
+		if !user.Deleted || user.Email != "" || user.Password != "" || user.FirstName != model.DeletedUserFirstName {
+			t.Errorf("Service.deleteDueAccounts() user = %+v, want tombstone", user)
+		}
+
+		if user.GetIdString() != uid {
+			t.Errorf("Service.deleteDueAccounts() id = %v, want %v", user.GetIdString(), uid)
+		}
+
+		if _, err := vts.Consume(ctx, hashCode(token), model.VerificationPurposePassword); err == nil {
+			t.Errorf("VerificationTokenStore.Consume() error = nil, want the tokens to be deleted")
+		}
+
+		if corporate.Deleted {
+			t.Errorf("Service.deleteDueAccounts() deleted the user %s", corporate.GetIdString())
+		}
+
+		if _, err := service.Login(ctx, &app.LoginRequest{Email: "foo@bar.com", Password: "password"}); err == nil {
+			t.Errorf("Service.Login() error = nil, want error")
+		}
+	})
+
+	t.Run("should delete at once without a grace period", func(t *testing.T) {
+		other := &model.User{Id: primitive.NewObjectID(), Email: "baz@bar.com"}
+		users[other.GetIdString()] = other
+
+		config.Auth.AccountDeletionGracePeriod = 0
+		defer func() { config.Auth.AccountDeletionGracePeriod = 2592000 }()
+
+		_, err := service.DeleteAccount(ctx, &Claims{StandardClaims: jwt.StandardClaims{Subject: other.GetIdString()}})
+		if err != nil {
+			t.Fatalf("Service.DeleteAccount() error = %v", err)
+		}
+
+		if !other.Deleted || other.Email != "" {
+			t.Errorf("Service.DeleteAccount() user = %+v, want tombstone", other)
+		}
+	})
+}
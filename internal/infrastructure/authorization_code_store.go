@@ -0,0 +1,138 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAuthorizationCodeStore struct {
+	app.AuthorizationCodeStore
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoAuthorizationCodeStore(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoAuthorizationCodeStore {
+	return &MongoAuthorizationCodeStore{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.CodeCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the store. Expired codes are
+// removed by mongo through the ttl index on expires_at.
+func (s *MongoAuthorizationCodeStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		s.logger.Warnf("error while creating authorization code indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Save stores an authorization code
+func (s *MongoAuthorizationCodeStore) Save(ctx context.Context, code *model.AuthorizationCode) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.InsertOne(ctx, code); err != nil {
+		s.logger.Warnf("error while saving authorization code: %s", err)
+		return app.NewInternalServerError(errors.New("error while saving authorization code"))
+	}
+
+	return nil
+}
+
+// Consume returns the code and removes it in a single operation, so that
+// only one of concurrent exchanges can succeed
+func (s *MongoAuthorizationCodeStore) Consume(ctx context.Context, id string) (*model.AuthorizationCode, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	c := &model.AuthorizationCode{}
+	if err := s.db.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrAuthorizationCodeNotFound
+		}
+
+		s.logger.Warnf("error while consuming authorization code: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while consuming authorization code"))
+	}
+
+	if c.IsExpired() {
+		return nil, app.ErrAuthorizationCodeNotFound
+	}
+
+	return c, nil
+}
+
+func (s *MongoAuthorizationCodeStore) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryAuthorizationCodeStore struct {
+	app.AuthorizationCodeStore
+	mu    sync.Mutex
+	codes map[string]*model.AuthorizationCode
+}
+
+func NewInMemoryAuthorizationCodeStore() *InMemoryAuthorizationCodeStore {
+	return &InMemoryAuthorizationCodeStore{
+		codes: make(map[string]*model.AuthorizationCode),
+	}
+}
+
+// Save stores an authorization code and drops the expired ones
+func (s *InMemoryAuthorizationCodeStore) Save(ctx context.Context, code *model.AuthorizationCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.codes {
+		if c.IsExpired() {
+			delete(s.codes, id)
+		}
+	}
+
+	c := *code
+	s.codes[code.Id] = &c
+
+	return nil
+}
+
+// Consume returns the code and removes it
+func (s *InMemoryAuthorizationCodeStore) Consume(ctx context.Context, id string) (*model.AuthorizationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.codes[id]
+	if !ok {
+		return nil, app.ErrAuthorizationCodeNotFound
+	}
+
+	delete(s.codes, id)
+
+	if c.IsExpired() {
+		return nil, app.ErrAuthorizationCodeNotFound
+	}
+
+	return c, nil
+}
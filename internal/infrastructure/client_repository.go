@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ClientRepository struct {
+	app.ClientRepository
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewClientRepository(config *config.Config, logger logger.ILogger, db *mongo.Client) *ClientRepository {
+	return &ClientRepository{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.ClientCollectionName),
+	}
+}
+
+// GetClient returns a client by id
+func (r *ClientRepository) GetClient(ctx context.Context, id string) (*model.OAuthClient, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	c := &model.OAuthClient{}
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrClientNotFound
+		}
+
+		r.logger.Warnf("error while finding client: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding client"))
+	}
+
+	return c, nil
+}
+
+// GetClients returns all clients
+func (r *ClientRepository) GetClients(ctx context.Context) ([]*model.OAuthClient, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		r.logger.Warnf("error while getting clients: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding clients"))
+	}
+
+	clients := make([]*model.OAuthClient, 0)
+	if err := res.All(ctx, &clients); err != nil {
+		r.logger.Warnf("error while decoding clients: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while decoding clients"))
+	}
+
+	return clients, nil
+}
+
+// CreateClient creates a new client
+func (r *ClientRepository) CreateClient(ctx context.Context, client *model.OAuthClient) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.InsertOne(ctx, client); err != nil {
+		r.logger.Warnf("error while creating client: %s", err)
+		return app.NewInternalServerError(errors.New("error while creating client"))
+	}
+
+	return nil
+}
+
+// DeleteClient deletes a client by id
+func (r *ClientRepository) DeleteClient(ctx context.Context, id string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Warnf("error while deleting client: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting client"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrClientNotFound
+	}
+
+	return nil
+}
+
+func (r *ClientRepository) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.config.Mongo.SocketTimeout)*time.Second)
+}
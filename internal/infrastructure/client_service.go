@@ -0,0 +1,156 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type ClientService struct {
+	app.ClientService
+	config *config.Config
+	logger logger.ILogger
+	repo   app.ClientRepository
+	pws    app.PasswordService
+}
+
+func NewClientService(config *config.Config, logger logger.ILogger, repo app.ClientRepository, pws app.PasswordService) *ClientService {
+	return &ClientService{
+		config: config,
+		logger: logger,
+		repo:   repo,
+		pws:    pws,
+	}
+}
+
+// CreateClient registers a new client with a random id. Confidential clients
+// are issued a random secret which is stored hashed.
+func (s *ClientService) CreateClient(ctx context.Context, r *app.CreateClientRequest) (*app.CreateClientResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	if !r.Confidential && len(r.RedirectUris) == 0 {
+		return nil, app.NewBadRequestError(errors.New("redirect uris are required for public clients"))
+	}
+
+	audiences := configList(s.config.Jwt.Audiences)
+	if r.Audience != "" && !contains(audiences, r.Audience) {
+		return nil, app.ErrInvalidAudience
+	}
+
+	known := append(configList(s.config.Jwt.Scopes), configList(s.config.Jwt.AdminScopes)...)
+	known = append(known, app.OpenIdScopes...)
+	for _, scope := range r.Scopes {
+		if !contains(known, scope) {
+			return nil, app.ErrInvalidScope
+		}
+	}
+
+	id, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	client := &model.OAuthClient{
+		Id:           id,
+		Name:         r.Name,
+		RedirectUris: r.RedirectUris,
+		Scopes:       app.ParseScope(strings.Join(r.Scopes, " ")),
+		Audience:     r.Audience,
+		Confidential: r.Confidential,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if client.RedirectUris == nil {
+		client.RedirectUris = []string{}
+	}
+
+	var secret string
+	if r.Confidential {
+		if secret, err = newClientSecret(); err != nil {
+			return nil, err
+		}
+
+		if client.Secret, err = s.pws.Hash(ctx, secret); err != nil {
+			s.logger.Warnf("failed to hash client secret: %s", err)
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("client %s (%s) is created", client.Id, client.Name)
+
+	return &app.CreateClientResponse{OAuthClient: *client, ClientSecret: secret}, nil
+}
+
+// Authenticate returns the client if the secret matches. A secret is required
+// for the confidential clients and not allowed for the public clients.
+func (s *ClientService) Authenticate(ctx context.Context, id string, secret string) (*model.OAuthClient, error) {
+	client, err := s.repo.GetClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, app.ErrClientNotFound) {
+			return nil, app.ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if !client.Confidential {
+		if secret != "" {
+			return nil, app.ErrInvalidClient
+		}
+
+		return client, nil
+	}
+
+	if secret == "" || s.pws.Compare(ctx, client.Secret, secret) != nil {
+		return nil, app.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// GetClient returns a client by id
+func (s *ClientService) GetClient(ctx context.Context, id string) (*model.OAuthClient, error) {
+	return s.repo.GetClient(ctx, id)
+}
+
+// GetClients returns all clients
+func (s *ClientService) GetClients(ctx context.Context) ([]*model.OAuthClient, error) {
+	return s.repo.GetClients(ctx)
+}
+
+// DeleteClient deletes a client by id
+func (s *ClientService) DeleteClient(ctx context.Context, id string) error {
+	if err := s.repo.DeleteClient(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Infof("client %s is deleted", id)
+
+	return nil
+}
+
+// newClientSecret generates a random client secret
+func newClientSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+)
+
+func TestClientService_CreateClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	c := config.New()
+	c.Jwt.Scopes = []string{"rides:read", "users:read"}
+
+	var created *model.OAuthClient
+	repo := mock.NewMockClientRepository(ctrl)
+	repo.EXPECT().CreateClient(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, client *model.OAuthClient) error {
+			created = client
+			return nil
+		}).AnyTimes()
+
+	pws := NewPasswordService(NewLoggerMock())
+	service := NewClientService(c, NewLoggerMock(), repo, pws)
+
+	tests := []struct {
+		name       string
+		req        *app.CreateClientRequest
+		wantSecret bool
+		wantErr    bool
+	}{
+		{
+			name: "should create a public client",
+			req:  &app.CreateClientRequest{Name: "Rider App", RedirectUris: []string{"https://app.example.com/callback"}, Scopes: []string{"rides:read"}},
+		},
+		{
+			name:       "should create a confidential client with a secret",
+			req:        &app.CreateClientRequest{Name: "Dispatch", Scopes: []string{"users:read"}, Confidential: true},
+			wantSecret: true,
+		},
+		{
+			name:    "should fail when public client has no redirect uris",
+			req:     &app.CreateClientRequest{Name: "Rider App"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when scope is unknown",
+			req:     &app.CreateClientRequest{Name: "Dispatch", Scopes: []string{"rides:delete"}, Confidential: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created = nil
+
+			got, err := service.CreateClient(ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClientService.CreateClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if (got.ClientSecret != "") != tt.wantSecret {
+				t.Fatalf("ClientService.CreateClient() secret = %v, wantSecret %v", got.ClientSecret, tt.wantSecret)
+			}
+
+			if !tt.wantSecret {
+				if created.Secret != "" {
+					t.Errorf("ClientService.CreateClient() stored a secret for a public client")
+				}
+				return
+			}
+
+			if created.Secret == got.ClientSecret {
+				t.Errorf("ClientService.CreateClient() stored the plain secret")
+			}
+
+			if err := pws.Compare(ctx, created.Secret, got.ClientSecret); err != nil {
+				t.Errorf("ClientService.CreateClient() stored secret does not match: %v", err)
+			}
+		})
+	}
+}
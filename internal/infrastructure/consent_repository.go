@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConsentRepository struct {
+	app.ConsentRepository
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewConsentRepository(config *config.Config, logger logger.ILogger, db *mongo.Client) *ConsentRepository {
+	return &ConsentRepository{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.ConsentCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the repository. A user has one
+// consent per client.
+func (r *ConsentRepository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		r.logger.Warnf("error while creating consent indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetConsent returns the consent of the user for the client
+func (r *ConsentRepository) GetConsent(ctx context.Context, userId string, clientId string) (*model.Consent, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	c := &model.Consent{}
+	if err := r.db.FindOne(ctx, bson.M{"user_id": userId, "client_id": clientId}).Decode(c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrConsentNotFound
+		}
+
+		r.logger.Warnf("error while finding consent: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding consent"))
+	}
+
+	return c, nil
+}
+
+// GetConsents returns the consents of the user ordered by their last use
+func (r *ConsentRepository) GetConsents(ctx context.Context, userId string) ([]*model.Consent, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+
+	res, err := r.db.Find(ctx, bson.M{"user_id": userId}, opts)
+	if err != nil {
+		r.logger.Warnf("error while getting consents: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding consents"))
+	}
+
+	consents := make([]*model.Consent, 0)
+	if err := res.All(ctx, &consents); err != nil {
+		r.logger.Warnf("error while decoding consents: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while decoding consents"))
+	}
+
+	return consents, nil
+}
+
+// GrantConsent adds the scopes to the consent of the user for the client
+func (r *ConsentRepository) GrantConsent(ctx context.Context, userId string, clientId string, scopes []string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := bson.M{"user_id": userId, "client_id": clientId}
+	update := bson.M{
+		"$addToSet":    bson.M{"scopes": bson.M{"$each": scopes}},
+		"$set":         bson.M{"last_used_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+
+	if _, err := r.db.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		r.logger.Warnf("error while granting consent: %s", err)
+		return app.NewInternalServerError(errors.New("error while granting consent"))
+	}
+
+	return nil
+}
+
+// TouchConsent updates the last use of the consent of the user for the client
+func (r *ConsentRepository) TouchConsent(ctx context.Context, userId string, clientId string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "client_id": clientId}
+	if _, err := r.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_used_at": time.Now()}}); err != nil {
+		r.logger.Warnf("error while updating consent: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating consent"))
+	}
+
+	return nil
+}
+
+// DeleteConsent deletes the consent of the user for the client
+func (r *ConsentRepository) DeleteConsent(ctx context.Context, userId string, clientId string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"user_id": userId, "client_id": clientId})
+	if err != nil {
+		r.logger.Warnf("error while deleting consent: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting consent"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrConsentNotFound
+	}
+
+	return nil
+}
+
+func (r *ConsentRepository) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.config.Mongo.SocketTimeout)*time.Second)
+}
@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type ConsentService struct {
+	app.ConsentService
+	config   *config.Config
+	logger   logger.ILogger
+	consents app.ConsentRepository
+	clients  app.ClientService
+	rts      app.RefreshTokenStore
+}
+
+func NewConsentService(config *config.Config, logger logger.ILogger, consents app.ConsentRepository, clients app.ClientService, rts app.RefreshTokenStore) *ConsentService {
+	return &ConsentService{
+		config:   config,
+		logger:   logger,
+		consents: consents,
+		clients:  clients,
+		rts:      rts,
+	}
+}
+
+// GetApplications returns the clients which the user granted access to. The
+// consents of the deleted clients are skipped.
+func (s *ConsentService) GetApplications(ctx context.Context, uid string) ([]*app.ApplicationResponse, error) {
+	consents, err := s.consents.GetConsents(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*app.ApplicationResponse, 0, len(consents))
+	for _, c := range consents {
+		client, err := s.clients.GetClient(ctx, c.ClientId)
+		if err != nil {
+			if errors.Is(err, app.ErrClientNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		res = append(res, &app.ApplicationResponse{
+			ClientId:    c.ClientId,
+			Name:        client.Name,
+			Scopes:      c.Scopes,
+			FirstUsedAt: c.CreatedAt,
+			LastUsedAt:  c.LastUsedAt,
+		})
+	}
+
+	return res, nil
+}
+
+// RevokeApplication revokes the refresh tokens issued to the client and
+// deletes the consent of the user for the client. The tokens are revoked even
+// if there is no consent, e.g. for the clients of the device flow before the
+// consent is recorded. The access tokens expire shortly after.
+func (s *ConsentService) RevokeApplication(ctx context.Context, uid string, clientId string) error {
+	if err := s.rts.RevokeClient(ctx, uid, clientId); err != nil {
+		return err
+	}
+
+	if err := s.consents.DeleteConsent(ctx, uid, clientId); err != nil && !errors.Is(err, app.ErrConsentNotFound) {
+		return err
+	}
+
+	s.logger.Infof("access of client %s is revoked by user %s", clientId, uid)
+
+	return nil
+}
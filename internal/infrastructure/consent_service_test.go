@@ -0,0 +1,240 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+)
+
+func TestConsentService_GetApplications(t *testing.T) {
+	ctx := context.Background()
+
+	rider := &model.OAuthClient{Id: "rider", Name: "Rider App"}
+	tablet := &model.OAuthClient{Id: "tablet", Name: "In-Car Tablet"}
+
+	c := config.New()
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, rider, tablet), NewPasswordService(NewLoggerMock()))
+	consents := newConsentRepositoryForTesting(t)
+	service := NewConsentService(c, NewLoggerMock(), consents, clients, NewInMemoryRefreshTokenStore())
+
+	for _, g := range []struct {
+		uid      string
+		clientId string
+		scopes   []string
+	}{
+		{"uid", "rider", []string{"rides:read"}},
+		{"uid", "tablet", []string{"rides:read"}},
+		{"uid", "rider", []string{"rides:read", "rides:write"}},
+		{"uid", "deleted", []string{"rides:read"}},
+		{"other", "rider", []string{"rides:read"}},
+	} {
+		if err := consents.GrantConsent(ctx, g.uid, g.clientId, g.scopes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		uid  string
+		want map[string][]string
+	}{
+		{
+			name: "should return the applications of the user with the granted scopes",
+			uid:  "uid",
+			want: map[string][]string{"Rider App": {"rides:read", "rides:write"}, "In-Car Tablet": {"rides:read"}},
+		},
+		{
+			name: "should return no applications when the user granted none",
+			uid:  "nobody",
+			want: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetApplications(ctx, tt.uid)
+			if err != nil {
+				t.Fatalf("ConsentService.GetApplications() error = %v", err)
+			}
+
+			apps := make(map[string][]string)
+			for _, a := range got {
+				if a.FirstUsedAt.IsZero() || a.LastUsedAt.Before(a.FirstUsedAt) {
+					t.Errorf("ConsentService.GetApplications() %s used at = %v - %v", a.ClientId, a.FirstUsedAt, a.LastUsedAt)
+				}
+
+				apps[a.Name] = a.Scopes
+			}
+
+			if !reflect.DeepEqual(apps, tt.want) {
+				t.Errorf("ConsentService.GetApplications() = %v, want %v", apps, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsentService_RevokeApplication(t *testing.T) {
+	ctx := context.Background()
+
+	c := config.New()
+	consents := newConsentRepositoryForTesting(t)
+	rts := NewInMemoryRefreshTokenStore()
+	service := NewConsentService(c, NewLoggerMock(), consents, nil, rts)
+
+	exp := time.Now().Add(time.Hour)
+	for _, rt := range []*model.RefreshToken{
+		{Id: "rider", UserId: "uid", ClientId: "rider", FamilyId: "1", ExpiresAt: exp},
+		{Id: "tablet", UserId: "uid", ClientId: "tablet", FamilyId: "2", ExpiresAt: exp},
+		{Id: "login", UserId: "uid", FamilyId: "3", ExpiresAt: exp},
+	} {
+		if err := rts.Save(ctx, rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := consents.GrantConsent(ctx, "uid", "rider", []string{"rides:read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		clientId    string
+		wantRevoked []string
+	}{
+		{
+			name:        "should delete the consent and revoke the refresh tokens of the client",
+			clientId:    "rider",
+			wantRevoked: []string{"rider"},
+		},
+		{
+			name:        "should revoke the refresh tokens of the client without a consent",
+			clientId:    "tablet",
+			wantRevoked: []string{"rider", "tablet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.RevokeApplication(ctx, "uid", tt.clientId); err != nil {
+				t.Fatalf("ConsentService.RevokeApplication() error = %v", err)
+			}
+
+			if _, err := consents.GetConsent(ctx, "uid", tt.clientId); !errors.Is(err, app.ErrConsentNotFound) {
+				t.Errorf("ConsentRepository.GetConsent() error = %v, want %v", err, app.ErrConsentNotFound)
+			}
+
+			var revoked []string
+			for _, id := range []string{"rider", "tablet", "login"} {
+				rt, err := rts.Get(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if rt.Revoked {
+					revoked = append(revoked, id)
+				}
+			}
+
+			if !reflect.DeepEqual(revoked, tt.wantRevoked) {
+				t.Errorf("revoked refresh tokens = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+// newConsentRepositoryForTesting returns a consent repository which keeps the
+// consents in memory
+func newConsentRepositoryForTesting(t *testing.T) *mock.MockConsentRepository {
+	repo := mock.NewMockConsentRepository(gomock.NewController(t))
+
+	var mu sync.Mutex
+	consents := make(map[[2]string]*model.Consent)
+
+	repo.EXPECT().GetConsent(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, uid string, clientId string) (*model.Consent, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			c, ok := consents[[2]string{uid, clientId}]
+			if !ok {
+				return nil, app.ErrConsentNotFound
+			}
+
+			cp := *c
+			return &cp, nil
+		}).AnyTimes()
+
+	repo.EXPECT().GetConsents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, uid string) ([]*model.Consent, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			res := make([]*model.Consent, 0)
+			for k, c := range consents {
+				if k[0] == uid {
+					cp := *c
+					res = append(res, &cp)
+				}
+			}
+
+			return res, nil
+		}).AnyTimes()
+
+	repo.EXPECT().GrantConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, uid string, clientId string, scopes []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			now := time.Now()
+
+			c, ok := consents[[2]string{uid, clientId}]
+			if !ok {
+				c = &model.Consent{UserId: uid, ClientId: clientId, CreatedAt: now}
+				consents[[2]string{uid, clientId}] = c
+			}
+
+			for _, s := range scopes {
+				if !contains(c.Scopes, s) {
+					c.Scopes = append(c.Scopes, s)
+				}
+			}
+			c.LastUsedAt = now
+
+			return nil
+		}).AnyTimes()
+
+	repo.EXPECT().TouchConsent(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, uid string, clientId string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if c, ok := consents[[2]string{uid, clientId}]; ok {
+				c.LastUsedAt = time.Now()
+			}
+
+			return nil
+		}).AnyTimes()
+
+	repo.EXPECT().DeleteConsent(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, uid string, clientId string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if _, ok := consents[[2]string{uid, clientId}]; !ok {
+				return app.ErrConsentNotFound
+			}
+
+			delete(consents, [2]string{uid, clientId})
+			return nil
+		}).AnyTimes()
+
+	return repo
+}
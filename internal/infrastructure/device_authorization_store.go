@@ -0,0 +1,278 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDeviceAuthorizationStore struct {
+	app.DeviceAuthorizationStore
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoDeviceAuthorizationStore(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoDeviceAuthorizationStore {
+	return &MongoDeviceAuthorizationStore{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.DeviceCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the store. Expired requests are
+// removed by mongo through the ttl index on expires_at.
+func (s *MongoDeviceAuthorizationStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		s.logger.Warnf("error while creating device authorization indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Save stores a device authorization request
+func (s *MongoDeviceAuthorizationStore) Save(ctx context.Context, d *model.DeviceAuthorization) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.InsertOne(ctx, d); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return app.ErrUserCodeExists
+		}
+
+		s.logger.Warnf("error while saving device authorization: %s", err)
+		return app.NewInternalServerError(errors.New("error while saving device authorization"))
+	}
+
+	return nil
+}
+
+// Poll records the poll of the device and returns the request as it was
+// before the poll
+func (s *MongoDeviceAuthorizationStore) Poll(ctx context.Context, id string, at time.Time) (*model.DeviceAuthorization, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	d := &model.DeviceAuthorization{}
+	err := s.db.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_polled_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(d)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrDeviceAuthorizationNotFound
+		}
+
+		s.logger.Warnf("error while polling device authorization: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while polling device authorization"))
+	}
+
+	return d, nil
+}
+
+// GetByUserCode returns the pending request of the user code
+func (s *MongoDeviceAuthorizationStore) GetByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	d := &model.DeviceAuthorization{}
+	err := s.db.FindOne(ctx, bson.M{
+		"user_code":  userCode,
+		"status":     model.DeviceAuthorizationPending,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(d)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrDeviceAuthorizationNotFound
+		}
+
+		s.logger.Warnf("error while finding device authorization: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding device authorization"))
+	}
+
+	return d, nil
+}
+
+// Decide approves or denies the pending request of the user code
+func (s *MongoDeviceAuthorizationStore) Decide(ctx context.Context, userCode string, status string, userId string, grant *app.TokenGrant) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	set := bson.M{"status": status, "user_id": userId}
+	if grant != nil {
+		set["audience"] = grant.Audience
+		set["scope"] = grant.Scope
+	}
+
+	res, err := s.db.UpdateOne(ctx, bson.M{
+		"user_code":  userCode,
+		"status":     model.DeviceAuthorizationPending,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, bson.M{"$set": set})
+	if err != nil {
+		s.logger.Warnf("error while updating device authorization: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating device authorization"))
+	}
+
+	if res.MatchedCount == 0 {
+		return app.ErrDeviceAuthorizationNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the request
+func (s *MongoDeviceAuthorizationStore) Delete(ctx context.Context, id string) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		s.logger.Warnf("error while deleting device authorization: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting device authorization"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrDeviceAuthorizationNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoDeviceAuthorizationStore) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryDeviceAuthorizationStore struct {
+	app.DeviceAuthorizationStore
+	mu       sync.Mutex
+	requests map[string]*model.DeviceAuthorization
+}
+
+func NewInMemoryDeviceAuthorizationStore() *InMemoryDeviceAuthorizationStore {
+	return &InMemoryDeviceAuthorizationStore{
+		requests: make(map[string]*model.DeviceAuthorization),
+	}
+}
+
+// Save stores a device authorization request and drops the expired ones
+func (s *InMemoryDeviceAuthorizationStore) Save(ctx context.Context, d *model.DeviceAuthorization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.requests {
+		if r.IsExpired() {
+			delete(s.requests, id)
+		} else if r.UserCode == d.UserCode {
+			return app.ErrUserCodeExists
+		}
+	}
+
+	r := *d
+	s.requests[d.Id] = &r
+
+	return nil
+}
+
+// Poll records the poll of the device and returns the request as it was
+// before the poll
+func (s *InMemoryDeviceAuthorizationStore) Poll(ctx context.Context, id string, at time.Time) (*model.DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.requests[id]
+	if !ok {
+		return nil, app.ErrDeviceAuthorizationNotFound
+	}
+
+	d := *r
+	r.LastPolledAt = at
+
+	return &d, nil
+}
+
+// GetByUserCode returns the pending request of the user code
+func (s *InMemoryDeviceAuthorizationStore) GetByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.pending(userCode)
+	if r == nil {
+		return nil, app.ErrDeviceAuthorizationNotFound
+	}
+
+	d := *r
+
+	return &d, nil
+}
+
+// Decide approves or denies the pending request of the user code
+func (s *InMemoryDeviceAuthorizationStore) Decide(ctx context.Context, userCode string, status string, userId string, grant *app.TokenGrant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.pending(userCode)
+	if r == nil {
+		return app.ErrDeviceAuthorizationNotFound
+	}
+
+	r.Status = status
+	r.UserId = userId
+	if grant != nil {
+		r.Audience = grant.Audience
+		r.Scope = grant.Scope
+	}
+
+	return nil
+}
+
+// Delete removes the request
+func (s *InMemoryDeviceAuthorizationStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.requests[id]; !ok {
+		return app.ErrDeviceAuthorizationNotFound
+	}
+
+	delete(s.requests, id)
+
+	return nil
+}
+
+// pending returns the pending request of the user code
+func (s *InMemoryDeviceAuthorizationStore) pending(userCode string) *model.DeviceAuthorization {
+	for _, r := range s.requests {
+		if r.UserCode == userCode && r.Status == model.DeviceAuthorizationPending && !r.IsExpired() {
+			return r
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoFederatedLoginStore struct {
+	app.FederatedLoginStore
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoFederatedLoginStore(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoFederatedLoginStore {
+	return &MongoFederatedLoginStore{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.FederationCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the store. Expired logins are
+// removed by mongo through the ttl index on expires_at.
+func (s *MongoFederatedLoginStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		s.logger.Warnf("error while creating federated login indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Save stores a federated login
+func (s *MongoFederatedLoginStore) Save(ctx context.Context, login *model.FederatedLogin) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.InsertOne(ctx, login); err != nil {
+		s.logger.Warnf("error while saving federated login: %s", err)
+		return app.NewInternalServerError(errors.New("error while saving federated login"))
+	}
+
+	return nil
+}
+
+// Consume returns the login and removes it in a single operation, so that
+// only one of concurrent callbacks can succeed
+func (s *MongoFederatedLoginStore) Consume(ctx context.Context, id string) (*model.FederatedLogin, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	l := &model.FederatedLogin{}
+	if err := s.db.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(l); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrFederatedLoginNotFound
+		}
+
+		s.logger.Warnf("error while consuming federated login: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while consuming federated login"))
+	}
+
+	if l.IsExpired() {
+		return nil, app.ErrFederatedLoginNotFound
+	}
+
+	return l, nil
+}
+
+func (s *MongoFederatedLoginStore) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryFederatedLoginStore struct {
+	app.FederatedLoginStore
+	mu     sync.Mutex
+	logins map[string]*model.FederatedLogin
+}
+
+func NewInMemoryFederatedLoginStore() *InMemoryFederatedLoginStore {
+	return &InMemoryFederatedLoginStore{
+		logins: make(map[string]*model.FederatedLogin),
+	}
+}
+
+// Save stores a federated login and drops the expired ones
+func (s *InMemoryFederatedLoginStore) Save(ctx context.Context, login *model.FederatedLogin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, l := range s.logins {
+		if l.IsExpired() {
+			delete(s.logins, id)
+		}
+	}
+
+	l := *login
+	s.logins[login.Id] = &l
+
+	return nil
+}
+
+// Consume returns the login and removes it
+func (s *InMemoryFederatedLoginStore) Consume(ctx context.Context, id string) (*model.FederatedLogin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logins[id]
+	if !ok {
+		return nil, app.ErrFederatedLoginNotFound
+	}
+
+	delete(s.logins, id)
+
+	if l.IsExpired() {
+		return nil, app.ErrFederatedLoginNotFound
+	}
+
+	return l, nil
+}
@@ -0,0 +1,372 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/pkg/errors"
+)
+
+// oidcProviderConfig is a provider of the providers file
+type oidcProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientId     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// oidcProviderMetadata is the part of the discovery document of the provider
+// which the login needs
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// oidcProvider is an upstream OpenID Connect provider. The discovery document
+// and the keys of the provider are cached, and the keys are fetched again
+// when an ID token is signed by an unknown key.
+type oidcProvider struct {
+	oidcProviderConfig
+	client          *http.Client
+	refreshInterval time.Duration
+	mu              sync.Mutex
+	metadata        *oidcProviderMetadata
+	keys            map[string]crypto.PublicKey
+	keysFetchedAt   time.Time
+}
+
+// loadOidcProviders reads the providers from the JSON file
+func loadOidcProviders(file string, client *http.Client, refreshInterval time.Duration) ([]*oidcProvider, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []oidcProviderConfig
+	if err := json.Unmarshal(b, &configs); err != nil {
+		return nil, errors.Wrap(err, "invalid providers file")
+	}
+
+	providers := make([]*oidcProvider, 0, len(configs))
+	for _, c := range configs {
+		if c.Name == "" || c.Issuer == "" || c.ClientId == "" {
+			return nil, errors.New("name, issuer and client_id of the providers are required")
+		}
+
+		if len(c.Scopes) == 0 {
+			c.Scopes = []string{app.ScopeOpenId, app.ScopeEmail, app.ScopeProfile}
+		}
+
+		providers = append(providers, &oidcProvider{
+			oidcProviderConfig: c,
+			client:             client,
+			refreshInterval:    refreshInterval,
+		})
+	}
+
+	return providers, nil
+}
+
+// authorizationUrl returns the url of the authorization request with PKCE
+func (p *oidcProvider) authorizationUrl(ctx context.Context, state string, nonce string, challenge string, redirectUri string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid authorization endpoint")
+	}
+
+	q := u.Query()
+	q.Set("response_type", app.ResponseTypeCode)
+	q.Set("client_id", p.ClientId)
+	q.Set("redirect_uri", redirectUri)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", app.CodeChallengeMethodS256)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// exchange exchanges the authorization code for the ID token of the user
+func (p *oidcProvider) exchange(ctx context.Context, code string, verifier string, redirectUri string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", app.GrantTypeAuthorizationCode)
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectUri)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+
+	var res struct {
+		IdToken string `json:"id_token"`
+	}
+
+	if err := p.do(req, &res); err != nil {
+		return "", errors.Wrap(err, "token request failed")
+	}
+
+	if res.IdToken == "" {
+		return "", errors.New("token response has no id token")
+	}
+
+	return res.IdToken, nil
+}
+
+// verifyIdToken verifies the signature and the claims of the ID token of the
+// provider. The nonce has to match the one of the authorization request.
+func (p *oidcProvider) verifyIdToken(ctx context.Context, token string, nonce string) (*externalIdTokenClaims, error) {
+	claims := &externalIdTokenClaims{}
+
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}}
+	if _, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}); err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != p.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if !claims.Audience.contains(p.ClientId) {
+		return nil, errors.New("invalid audience")
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientId {
+		return nil, errors.New("invalid authorized party")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("subject is empty")
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid nonce")
+	}
+
+	return claims, nil
+}
+
+// key returns the public key of the provider with the key id. The keys are
+// fetched again for an unknown key id, at most once in the refresh interval.
+func (p *oidcProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keysFetchedAt) < p.refreshInterval {
+		return nil, errors.Errorf("unknown key %s", kid)
+	}
+
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, errors.Errorf("unknown key %s", kid)
+}
+
+// fetchKeys fetches the key set of the provider. It is called with the lock
+// held.
+func (p *oidcProvider) fetchKeys(ctx context.Context) error {
+	m, err := p.discoverLocked(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.JwksUri, nil)
+	if err != nil {
+		return err
+	}
+
+	var set app.JSONWebKeySet
+	if err := p.do(req, &set); err != nil {
+		return errors.Wrap(err, "key set request failed")
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := publicKeyFromJSONWebKey(jwk)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	return nil
+}
+
+// discover returns the discovery document of the provider
+func (p *oidcProvider) discover(ctx context.Context) (*oidcProviderMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.discoverLocked(ctx)
+}
+
+// discoverLocked fetches the discovery document of the provider once it is
+// fetched successfully. It is called with the lock held.
+func (p *oidcProvider) discoverLocked(ctx context.Context) (*oidcProviderMetadata, error) {
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &oidcProviderMetadata{}
+	if err := p.do(req, m); err != nil {
+		return nil, errors.Wrap(err, "discovery request failed")
+	}
+
+	if m.Issuer != p.Issuer {
+		return nil, errors.Errorf("issuer of the discovery document %s does not match %s", m.Issuer, p.Issuer)
+	}
+
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JwksUri == "" {
+		return nil, errors.New("discovery document is incomplete")
+	}
+
+	p.metadata = m
+
+	return m, nil
+}
+
+// do sends the request and decodes the JSON response
+func (p *oidcProvider) do(req *http.Request, v interface{}) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// externalIdTokenClaims are the claims of an ID token of a provider. The
+// audience may be a string or an array, and some providers send the
+// email_verified claim as a string.
+type externalIdTokenClaims struct {
+	Issuer          string      `json:"iss"`
+	Subject         string      `json:"sub"`
+	Audience        audience    `json:"aud"`
+	AuthorizedParty string      `json:"azp,omitempty"`
+	ExpiresAt       int64       `json:"exp"`
+	IssuedAt        int64       `json:"iat"`
+	Nonce           string      `json:"nonce,omitempty"`
+	Email           string      `json:"email,omitempty"`
+	EmailVerified   lenientBool `json:"email_verified,omitempty"`
+	Name            string      `json:"name,omitempty"`
+	GivenName       string      `json:"given_name,omitempty"`
+	FamilyName      string      `json:"family_name,omitempty"`
+	Picture         string      `json:"picture,omitempty"`
+}
+
+// Valid checks the expiration of the token
+func (c *externalIdTokenClaims) Valid() error {
+	if c.ExpiresAt == 0 {
+		return errors.New("token has no expiration")
+	}
+
+	if time.Now().Unix() > c.ExpiresAt {
+		return errors.New("token expired")
+	}
+
+	return nil
+}
+
+// audience is the aud claim which can be a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+
+	*a = list
+
+	return nil
+}
+
+// contains returns true if the audience has the value
+func (a audience) contains(value string) bool {
+	return contains(a, value)
+}
+
+// lenientBool is a boolean claim which can be sent as a string as well
+type lenientBool bool
+
+func (l *lenientBool) UnmarshalJSON(b []byte) error {
+	var v bool
+	if err := json.Unmarshal(b, &v); err == nil {
+		*l = lenientBool(v)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*l = lenientBool(s == "true")
+
+	return nil
+}
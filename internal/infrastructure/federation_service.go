@@ -0,0 +1,333 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxNameLength is the maximum length of the names of the users
+const maxNameLength = 30
+
+type FederationService struct {
+	app.FederationService
+	config    *config.Config
+	logger    logger.ILogger
+	repo      app.Repository
+	ts        app.TokenService
+	logins    app.FederatedLoginStore
+	providers map[string]*oidcProvider
+	names     []string
+}
+
+func NewFederationService(config *config.Config, logger logger.ILogger, repo app.Repository, ts app.TokenService, logins app.FederatedLoginStore) (s *FederationService) {
+	s = &FederationService{
+		config:    config,
+		logger:    logger,
+		repo:      repo,
+		ts:        ts,
+		logins:    logins,
+		providers: make(map[string]*oidcProvider),
+	}
+
+	s.init()
+
+	return
+}
+
+func (s *FederationService) init() *FederationService {
+	if s.config.Federation.ProvidersFile == "" {
+		return s
+	}
+
+	client := &http.Client{Timeout: time.Duration(s.config.Federation.RequestTimeout) * time.Second}
+	refreshInterval := time.Duration(s.config.Federation.KeySetRefreshInterval) * time.Second
+
+	providers, err := loadOidcProviders(s.config.Federation.ProvidersFile, client, refreshInterval)
+	if err != nil {
+		panic(errors.Wrap(err, "failed to load federation providers"))
+	}
+
+	for _, p := range providers {
+		s.providers[p.Name] = p
+		s.names = append(s.names, p.Name)
+	}
+
+	return s
+}
+
+// Providers returns the names of the upstream providers
+func (s *FederationService) Providers() []string {
+	return append([]string{}, s.names...)
+}
+
+// AuthorizationUrl starts a login at the provider. The login is bound to the
+// state, the nonce and the PKCE verifier which are checked on the callback.
+func (s *FederationService) AuthorizationUrl(ctx context.Context, r *app.FederatedLoginRequest) (string, error) {
+	if err := app.Validate(r); err != nil {
+		return "", err
+	}
+
+	p, ok := s.providers[r.Provider]
+	if !ok {
+		return "", app.ErrFederationProviderNotFound
+	}
+
+	state, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := newCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	if err := s.logins.Save(ctx, &model.FederatedLogin{
+		Id:           hashCode(state),
+		Provider:     p.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectUri:  r.RedirectUri,
+		Audience:     r.Audience,
+		Scope:        r.Scope,
+		ExpiresAt:    now.Add(time.Duration(s.config.Federation.LoginExp) * time.Second),
+		CreatedAt:    now,
+	}); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	u, err := p.authorizationUrl(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(sum[:]), r.RedirectUri)
+	if err != nil {
+		s.logger.Warnf("failed to start login at provider %s: %s", p.Name, err)
+		return "", app.NewInternalServerError(errors.New("provider is not available"))
+	}
+
+	return u, nil
+}
+
+// Callback completes the login at the provider and issues a token pair to
+// the user of the ID token
+func (s *FederationService) Callback(ctx context.Context, r *app.FederatedCallbackRequest) (*app.LoginResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	login, err := s.logins.Consume(ctx, hashCode(r.State))
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := s.providers[r.Provider]
+	if !ok || login.Provider != p.Name {
+		return nil, app.ErrFederatedLoginNotFound
+	}
+
+	if r.Error != "" {
+		s.logger.Debugf("login at provider %s failed: %s %s", p.Name, r.Error, r.ErrorDescription)
+		return nil, app.ErrFederatedLoginFailed
+	}
+
+	if r.Code == "" {
+		return nil, app.NewBadRequestError(errors.New("code is required"))
+	}
+
+	idToken, err := p.exchange(ctx, r.Code, login.CodeVerifier, login.RedirectUri)
+	if err != nil {
+		s.logger.Warnf("failed to exchange code of provider %s: %s", p.Name, err)
+		return nil, app.ErrFederatedLoginFailed
+	}
+
+	claims, err := p.verifyIdToken(ctx, idToken, login.Nonce)
+	if err != nil {
+		s.logger.Warnf("invalid id token of provider %s: %s", p.Name, err)
+		return nil, app.ErrFederatedLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, p.Name, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cancelAccountDeletion(ctx, s.repo, s.logger, user); err != nil {
+		return nil, err
+	}
+
+	return issueLoginResponse(ctx, s.config, s.logger, s.ts, user, login.Audience, login.Scope)
+}
+
+// resolveUser returns the user which the identity is linked to. Otherwise the
+// identity is linked to the user with the same email, or a new user is
+// created. The email has to be verified by the provider for both.
+func (s *FederationService) resolveUser(ctx context.Context, provider string, claims *externalIdTokenClaims) (*model.User, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+
+	if !errors.Is(err, app.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, app.ErrFederatedEmailNotVerified
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName = claims.Name
+	}
+
+	identity := model.ExternalIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+		LinkedAt: time.Now(),
+	}
+
+	return linkOrCreateUser(ctx, s.repo, s.logger, identity, &model.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Avatar:    claims.Picture,
+	})
+}
+
+// linkOrCreateUser links the identity to the user with the email of the
+// identity. Otherwise a new user is created with the profile. The caller has
+// to make sure that the email of the identity can be trusted. Users which have
+// not verified the email are not linked, since anyone could have registered
+// them with a password to take over the account of the owner of the email,
+// unless the directory of the organization of the profile provisioned them.
+// When the profile has an organization, only the users of the organization are
+// linked. Personal accounts are not moved into the organization by a login.
+func linkOrCreateUser(ctx context.Context, repo app.Repository, logger logger.ILogger, identity model.ExternalIdentity, profile *model.User) (*model.User, error) {
+	user, err := repo.GetUserByEmail(ctx, identity.Email)
+	if err == nil {
+		provisioned := profile.Organization != "" && user.Organization == profile.Organization
+		if !user.EmailVerified && !provisioned {
+			logger.Warnf("identity of provider %s is not linked to user %s with an unverified email", identity.Provider, user.GetIdString())
+			return nil, app.ErrLinkedEmailNotVerified
+		}
+
+		if profile.Organization != "" && user.Organization != profile.Organization {
+			logger.Warnf("identity of provider %s is not linked to user %s of organization %q", identity.Provider, user.GetIdString(), user.Organization)
+			return nil, app.ErrSamlUserOfAnotherOrg
+		}
+
+		if err := repo.LinkIdentity(ctx, user.GetIdString(), &identity); err != nil {
+			return nil, err
+		}
+
+		logger.Infof("identity of provider %s is linked to user %s", identity.Provider, user.GetIdString())
+
+		user.Identities = append(user.Identities, identity)
+
+		return user, nil
+	}
+
+	if !errors.Is(err, app.ErrUserNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	user = &model.User{
+		FirstName:     truncate(profile.FirstName, maxNameLength),
+		LastName:      truncate(profile.LastName, maxNameLength),
+		Email:         identity.Email,
+		Avatar:        profile.Avatar,
+		Role:          model.RoleUser,
+		Organization:  profile.Organization,
+		EmailVerified: true,
+		Identities:    []model.ExternalIdentity{identity},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	uid, err := repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Id = objectId
+
+	logger.Infof("user %s is created by the login at provider %s", uid, identity.Provider)
+
+	return user, nil
+}
+
+// issueLoginResponse issues a token pair to the user which logged in at an
+// upstream provider
+func issueLoginResponse(ctx context.Context, config *config.Config, logger logger.ILogger, ts app.TokenService, user *model.User, audience string, scope string) (*app.LoginResponse, error) {
+	grant, err := ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: audience, Scope: scope})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := ts.GenerateAccessToken(ctx, user, grant)
+	if err != nil {
+		logger.Warnf("failed to generate access token: %s", err)
+		return nil, err
+	}
+
+	refreshToken, err := ts.GenerateRefreshToken(ctx, user, grant)
+	if err != nil {
+		logger.Warnf("failed to generate refresh token: %s", err)
+		return nil, err
+	}
+
+	return &app.LoginResponse{
+		UserDto:               *app.UserResponseFromUser(user),
+		AccessToken:           accessToken,
+		AccessTokenExpiresIn:  config.Jwt.AccessTokenExp,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresIn: config.Jwt.RefreshTokenExp,
+		Scope:                 grant.Scope,
+	}, nil
+}
+
+// newCodeVerifier generates a random PKCE code verifier (RFC 7636)
+func newCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// truncate cuts the string to the maximum number of characters
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+
+	return s
+}
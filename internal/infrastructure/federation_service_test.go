@@ -0,0 +1,394 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// stubProvider is a local OpenID Connect provider which issues ID tokens
+// with the claims of the test for the codes registered by the test
+type stubProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	mu     sync.Mutex
+	codes  map[string]url.Values
+	claims func(c jwt.MapClaims)
+}
+
+func newStubProviderForTesting(t *testing.T) *stubProvider {
+	_, file, _, _ := runtime.Caller(0)
+
+	b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(file), "../../certs/private.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &stubProvider{key: key, kid: "stub-key", codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&oidcProviderMetadata{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/authorize",
+			TokenEndpoint:         p.URL + "/token",
+			JwksUri:               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk := rsaJSONWebKey(&key.PublicKey, "RS256")
+		jwk.Kid = p.kid
+		_ = json.NewEncoder(w).Encode(&app.JSONWebKeySet{Keys: []app.JSONWebKey{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "rider-app" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		p.mu.Lock()
+		auth, ok := p.codes[r.FormValue("code")]
+		delete(p.codes, r.FormValue("code"))
+		p.mu.Unlock()
+
+		if !ok || !verifyCodeChallenge(auth.Get("code_challenge"), r.FormValue("code_verifier")) || auth.Get("redirect_uri") != r.FormValue("redirect_uri") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":            p.URL,
+			"sub":            "google-uid",
+			"aud":            "rider-app",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          auth.Get("nonce"),
+			"email":          "jane@doe.com",
+			"email_verified": true,
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+		}
+
+		if p.claims != nil {
+			p.claims(claims)
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = p.kid
+
+		idToken, err := token.SignedString(p.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// authorize registers a code for the authorization url as if the user logged
+// in at the provider
+func (p *stubProvider) authorize(t *testing.T, authorizationUrl string) (code string, state string) {
+	u, err := url.Parse(authorizationUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code = fmt.Sprintf("code-%d", time.Now().UnixNano())
+
+	p.mu.Lock()
+	p.codes[code] = u.Query()
+	p.mu.Unlock()
+
+	return code, u.Query().Get("state")
+}
+
+// newFederationConfigForTesting writes the providers file of the stub provider
+func newFederationConfigForTesting(t *testing.T, p *stubProvider) *config.Config {
+	file := filepath.Join(t.TempDir(), "providers.json")
+
+	b, err := json.Marshal([]oidcProviderConfig{{Name: "google", Issuer: p.URL, ClientId: "rider-app", ClientSecret: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.New()
+	c.Federation.ProvidersFile = file
+
+	return c
+}
+
+func TestFederationService_Callback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	provider := newStubProviderForTesting(t)
+
+	ts := mock.NewMockTokenService(ctrl)
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any(), gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any(), gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	linked := &model.User{Id: primitive.NewObjectID(), Email: "jane@doe.com", Identities: []model.ExternalIdentity{{Provider: "google", Subject: "google-uid"}}}
+	existing := &model.User{Id: primitive.NewObjectID(), Email: "jane@doe.com", EmailVerified: true}
+	unverified := &model.User{Id: primitive.NewObjectID(), Email: "jane@doe.com", Password: "hash"}
+	created := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		claims    func(c jwt.MapClaims)
+		repo      func(repo *mock.MockRepository)
+		callback  func(r *app.FederatedCallbackRequest)
+		wantUser  string
+		wantEmail string
+		wantErr   error
+	}{
+		{
+			name: "should login the user of a linked identity",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "google", "google-uid").Return(linked, nil)
+			},
+			wantUser:  linked.GetIdString(),
+			wantEmail: "jane@doe.com",
+		},
+		{
+			name: "should link the identity to the user with the verified email",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "google", "google-uid").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@doe.com").Return(existing, nil)
+				repo.EXPECT().LinkIdentity(gomock.Any(), existing.GetIdString(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, identity *model.ExternalIdentity) error {
+						if identity.Provider != "google" || identity.Subject != "google-uid" {
+							t.Errorf("Repository.LinkIdentity() identity = %+v", identity)
+						}
+						return nil
+					})
+			},
+			wantUser:  existing.GetIdString(),
+			wantEmail: "jane@doe.com",
+		},
+		{
+			name: "should fail to link the identity to the user with an unverified email",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "google", "google-uid").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@doe.com").Return(unverified, nil)
+			},
+			wantErr: app.ErrLinkedEmailNotVerified,
+		},
+		{
+			name: "should create a user on the first login",
+			claims: func(c jwt.MapClaims) {
+				c["aud"] = []string{"rider-app"}
+				c["email"] = "new@doe.com"
+				c["email_verified"] = "true"
+			},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "google", "google-uid").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "new@doe.com").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (string, error) {
+						if u.FirstName != "Jane" || u.LastName != "Doe" || !u.EmailVerified || len(u.Identities) != 1 || u.Password != "" {
+							t.Errorf("Repository.CreateUser() user = %+v", u)
+						}
+						return created.Hex(), nil
+					})
+			},
+			wantUser:  created.Hex(),
+			wantEmail: "new@doe.com",
+		},
+		{
+			name: "should fail when email is not verified",
+			claims: func(c jwt.MapClaims) {
+				c["email_verified"] = false
+			},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "google", "google-uid").Return(nil, app.ErrUserNotFound)
+			},
+			wantErr: app.ErrFederatedEmailNotVerified,
+		},
+		{
+			name: "should fail when nonce does not match",
+			claims: func(c jwt.MapClaims) {
+				c["nonce"] = "replayed"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when audience is another client",
+			claims: func(c jwt.MapClaims) {
+				c["aud"] = "driver-app"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when issuer does not match",
+			claims: func(c jwt.MapClaims) {
+				c["iss"] = "https://evil.example.com"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when id token is expired",
+			claims: func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when code is invalid",
+			callback: func(r *app.FederatedCallbackRequest) {
+				r.Code = "unknown"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when state is invalid",
+			callback: func(r *app.FederatedCallbackRequest) {
+				r.State = "unknown"
+			},
+			wantErr: app.ErrFederatedLoginNotFound,
+		},
+		{
+			name: "should fail when provider returns an error",
+			callback: func(r *app.FederatedCallbackRequest) {
+				r.Code = ""
+				r.Error = "access_denied"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mock.NewMockRepository(ctrl)
+			if tt.repo != nil {
+				tt.repo(repo)
+			}
+
+			provider.claims = tt.claims
+
+			service := NewFederationService(newFederationConfigForTesting(t, provider), NewLoggerMock(), repo, ts, NewInMemoryFederatedLoginStore())
+
+			u, err := service.AuthorizationUrl(ctx, &app.FederatedLoginRequest{Provider: "google", RedirectUri: "https://id.example.com/api/v1/federation/google/callback"})
+			if err != nil {
+				t.Fatalf("FederationService.AuthorizationUrl() error = %v", err)
+			}
+
+			code, state := provider.authorize(t, u)
+
+			r := &app.FederatedCallbackRequest{Provider: "google", Code: code, State: state}
+			if tt.callback != nil {
+				tt.callback(r)
+			}
+
+			got, err := service.Callback(ctx, r)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("FederationService.Callback() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FederationService.Callback() error = %v", err)
+			}
+
+			if got.UserDto.Id != tt.wantUser || got.UserDto.Email != tt.wantEmail || got.AccessToken != "access_token" {
+				t.Errorf("FederationService.Callback() = %+v, want user %v with email %v", got, tt.wantUser, tt.wantEmail)
+			}
+
+			if len(got.UserDto.Identities) != 1 || got.UserDto.Identities[0].Subject != "google-uid" {
+				t.Errorf("FederationService.Callback() identities = %+v", got.UserDto.Identities)
+			}
+
+			if _, err := service.Callback(ctx, r); !errors.Is(err, app.ErrFederatedLoginNotFound) {
+				t.Errorf("FederationService.Callback() error = %v on reuse, want %v", err, app.ErrFederatedLoginNotFound)
+			}
+		})
+	}
+}
+
+func TestFederationService_AuthorizationUrl(t *testing.T) {
+	ctx := context.Background()
+	provider := newStubProviderForTesting(t)
+
+	service := NewFederationService(newFederationConfigForTesting(t, provider), NewLoggerMock(), nil, nil, NewInMemoryFederatedLoginStore())
+
+	if got := service.Providers(); len(got) != 1 || got[0] != "google" {
+		t.Errorf("FederationService.Providers() = %v, want [google]", got)
+	}
+
+	t.Run("should redirect to the provider with PKCE", func(t *testing.T) {
+		got, err := service.AuthorizationUrl(ctx, &app.FederatedLoginRequest{Provider: "google", RedirectUri: "https://id.example.com/callback"})
+		if err != nil {
+			t.Fatalf("FederationService.AuthorizationUrl() error = %v", err)
+		}
+
+		u, err := url.Parse(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := u.Query()
+		if u.Path != "/authorize" || q.Get("client_id") != "rider-app" || q.Get("redirect_uri") != "https://id.example.com/callback" {
+			t.Errorf("FederationService.AuthorizationUrl() = %v", got)
+		}
+
+		if q.Get("scope") != "openid email profile" || q.Get("state") == "" || q.Get("nonce") == "" || q.Get("code_challenge_method") != "S256" {
+			t.Errorf("FederationService.AuthorizationUrl() = %v", got)
+		}
+
+		// the challenge is the base64url encoded sha256 of the verifier
+		if challenge, err := base64.RawURLEncoding.DecodeString(q.Get("code_challenge")); err != nil || len(challenge) != sha256.Size {
+			t.Errorf("FederationService.AuthorizationUrl() code_challenge = %v", q.Get("code_challenge"))
+		}
+	})
+
+	t.Run("should fail for an unknown provider", func(t *testing.T) {
+		_, err := service.AuthorizationUrl(ctx, &app.FederatedLoginRequest{Provider: "apple", RedirectUri: "https://id.example.com/callback"})
+		if !errors.Is(err, app.ErrFederationProviderNotFound) {
+			t.Errorf("FederationService.AuthorizationUrl() error = %v, want %v", err, app.ErrFederationProviderNotFound)
+		}
+	})
+}
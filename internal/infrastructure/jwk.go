@@ -0,0 +1,150 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/pkg/errors"
+)
+
+// newJSONWebKey converts the public key to JWK format, using its thumbprint
+// as key id
+func newJSONWebKey(key crypto.PublicKey, alg string) (app.JSONWebKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsaJSONWebKey(k, alg), nil
+	case *ecdsa.PublicKey:
+		return ecJSONWebKey(k, alg), nil
+	case ed25519.PublicKey:
+		return edJSONWebKey(k, alg), nil
+	}
+
+	return app.JSONWebKey{}, errors.Errorf("unsupported key type %T", key)
+}
+
+// rsaJSONWebKey converts the rsa public key to JWK format
+func rsaJSONWebKey(key *rsa.PublicKey, alg string) app.JSONWebKey {
+	jwk := app.JSONWebKey{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: alg,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+
+	jwk.Kid = thumbprint(jwk)
+
+	return jwk
+}
+
+// ecJSONWebKey converts the elliptic curve public key to JWK format
+func ecJSONWebKey(key *ecdsa.PublicKey, alg string) app.JSONWebKey {
+	size := (key.Curve.Params().BitSize + 7) / 8
+
+	jwk := app.JSONWebKey{
+		Kty: "EC",
+		Use: "sig",
+		Alg: alg,
+		Crv: key.Curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+
+	jwk.Kid = thumbprint(jwk)
+
+	return jwk
+}
+
+// edJSONWebKey converts the ed25519 public key to JWK format (RFC 8037)
+func edJSONWebKey(key ed25519.PublicKey, alg string) app.JSONWebKey {
+	jwk := app.JSONWebKey{
+		Kty: "OKP",
+		Use: "sig",
+		Alg: alg,
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(key),
+	}
+
+	jwk.Kid = thumbprint(jwk)
+
+	return jwk
+}
+
+// thumbprint computes the JWK thumbprint of the key (RFC 7638)
+func thumbprint(jwk app.JSONWebKey) string {
+	var members string
+	switch jwk.Kty {
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk.Crv, jwk.Kty, jwk.X, jwk.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.Crv, jwk.Kty, jwk.X)
+	default:
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.Kty, jwk.N)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// publicKeyFromJSONWebKey converts the RSA or elliptic curve key in JWK format
+// to a public key
+func publicKeyFromJSONWebKey(jwk app.JSONWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid modulus")
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid exponent")
+		}
+
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid rsa key")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %s", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid x coordinate")
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid y coordinate")
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid ec key")
+		}
+
+		return key, nil
+	}
+
+	return nil, errors.Errorf("unsupported key type %s", jwk.Kty)
+}
@@ -0,0 +1,88 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestRsaJSONWebKey(t *testing.T) {
+	// example key of RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+
+	got := rsaJSONWebKey(key, "RS256")
+
+	if got.E != "AQAB" {
+		t.Errorf("rsaJSONWebKey().E = %v, want %v", got.E, "AQAB")
+	}
+
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got.Kid != want {
+		t.Errorf("rsaJSONWebKey().Kid = %v, want %v", got.Kid, want)
+	}
+}
+
+func TestPublicKeyFromJSONWebKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  crypto.PublicKey
+		alg  string
+	}{
+		{
+			name: "should convert rsa keys",
+			key:  &rsaKey.PublicKey,
+			alg:  "RS256",
+		},
+		{
+			name: "should convert elliptic curve keys",
+			key:  &ecKey.PublicKey,
+			alg:  "ES256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := newJSONWebKey(tt.key, tt.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := publicKeyFromJSONWebKey(jwk)
+			if err != nil {
+				t.Fatalf("publicKeyFromJSONWebKey() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.key) {
+				t.Errorf("publicKeyFromJSONWebKey() = %v, want %v", got, tt.key)
+			}
+		})
+	}
+
+	t.Run("should fail for a point which is not on the curve", func(t *testing.T) {
+		jwk := ecJSONWebKey(&ecKey.PublicKey, "ES256")
+		jwk.Y = jwk.X
+
+		if _, err := publicKeyFromJSONWebKey(jwk); err == nil {
+			t.Errorf("publicKeyFromJSONWebKey() error = nil, want error")
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/pkg/errors"
+)
+
+// keyFile is a PEM file of a keyring
+type keyFile struct {
+	path   string
+	active bool
+}
+
+// keyringKey is a key of a keyring. Private key is nil for the keys which are
+// kept for verification only.
+type keyringKey struct {
+	jwk        app.JSONWebKey
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// keyring holds the keys of a token type. The active key signs new tokens,
+// and all keys verify tokens by their key id so that the tokens signed by a
+// retired key stay valid until they expire. All keys of a keyring are used
+// with the same signing method.
+type keyring struct {
+	method  jwt.SigningMethod
+	files   func() ([]keyFile, error)
+	mu      sync.RWMutex
+	active  *keyringKey
+	keys    map[string]*keyringKey
+	version string
+}
+
+// newDirKeyring creates a keyring from the PEM files in the directory. The
+// file named as active must hold a private key.
+func newDirKeyring(dir string, active string, method jwt.SigningMethod) *keyring {
+	return &keyring{
+		method: method,
+		files: func() ([]keyFile, error) {
+			entries, err := ioutil.ReadDir(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			var files []keyFile
+			for _, e := range entries {
+				if e.IsDir() || !strings.HasSuffix(e.Name(), ".pem") {
+					continue
+				}
+
+				files = append(files, keyFile{
+					path:   filepath.Join(dir, e.Name()),
+					active: e.Name() == active,
+				})
+			}
+
+			return files, nil
+		},
+	}
+}
+
+// newFileKeyring creates a keyring from a single key pair
+func newFileKeyring(privateKeyFile string, publicKeyFile string, method jwt.SigningMethod) *keyring {
+	return &keyring{
+		method: method,
+		files: func() ([]keyFile, error) {
+			return []keyFile{
+				{path: privateKeyFile, active: true},
+				{path: publicKeyFile},
+			}, nil
+		},
+	}
+}
+
+// load reads the key files and replaces the keys of the keyring. Unless force
+// is set, the files are read only when they changed since the last load. The
+// keyring keeps its current keys when any of the files is invalid.
+func (k *keyring) load(force bool) (bool, error) {
+	files, err := k.files()
+	if err != nil {
+		return false, err
+	}
+
+	version, err := filesVersion(files)
+	if err != nil {
+		return false, err
+	}
+
+	k.mu.RLock()
+	unchanged := version == k.version
+	k.mu.RUnlock()
+
+	if unchanged && !force {
+		return false, nil
+	}
+
+	var active *keyringKey
+	keys := make(map[string]*keyringKey)
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return false, err
+		}
+
+		key, err := parseKeyringKey(b, k.method)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid key %s", f.path)
+		}
+
+		if f.active {
+			if key.privateKey == nil {
+				return false, errors.Errorf("active key %s is not a private key", f.path)
+			}
+
+			active = key
+		}
+
+		if existing, ok := keys[key.jwk.Kid]; ok && existing.privateKey != nil {
+			continue
+		}
+
+		keys[key.jwk.Kid] = key
+	}
+
+	if active == nil {
+		return false, errors.New("no active key")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = active
+	k.keys = keys
+	k.version = version
+
+	return true, nil
+}
+
+// signingKey returns the active key
+func (k *keyring) signingKey() *keyringKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// verificationKey returns the key which signed the token. Tokens without key
+// id are issued before the keyring, so they are verified by the active key.
+func (k *keyring) verificationKey(token *jwt.Token) (*keyringKey, error) {
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errors.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return k.active, nil
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown key id %s", kid)
+	}
+
+	return key, nil
+}
+
+// keyFunc provides the public key to verify the token
+func (k *keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	key, err := k.verificationKey(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.publicKey, nil
+}
+
+// publicKeys returns all keys of the keyring in JWK format
+func (k *keyring) publicKeys() []app.JSONWebKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var retired []app.JSONWebKey
+	for kid, key := range k.keys {
+		if kid != k.active.jwk.Kid {
+			retired = append(retired, key.jwk)
+		}
+	}
+
+	sort.Slice(retired, func(i, j int) bool {
+		return retired[i].Kid < retired[j].Kid
+	})
+
+	return append([]app.JSONWebKey{k.active.jwk}, retired...)
+}
+
+// parseKeyringKey parses a PEM encoded private or public key of the signing method
+func parseKeyringKey(b []byte, method jwt.SigningMethod) (*keyringKey, error) {
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+
+	switch method {
+	case jwt.SigningMethodRS256:
+		if prk, err := jwt.ParseRSAPrivateKeyFromPEM(b); err == nil {
+			privateKey, publicKey = prk, &prk.PublicKey
+		} else if puk, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+			publicKey = puk
+		} else {
+			return nil, err
+		}
+	case jwt.SigningMethodES256:
+		var puk *ecdsa.PublicKey
+		if prk, err := jwt.ParseECPrivateKeyFromPEM(b); err == nil {
+			privateKey, puk = prk, &prk.PublicKey
+		} else if puk, err = jwt.ParseECPublicKeyFromPEM(b); err != nil {
+			return nil, err
+		}
+
+		if puk.Curve != elliptic.P256() {
+			return nil, errors.Errorf("%s requires a P-256 key", method.Alg())
+		}
+
+		publicKey = puk
+	case jwt.SigningMethodEdDSA:
+		if prk, err := jwt.ParseEdPrivateKeyFromPEM(b); err == nil {
+			privateKey, publicKey = prk, prk.(ed25519.PrivateKey).Public()
+		} else if puk, err := jwt.ParseEdPublicKeyFromPEM(b); err == nil {
+			publicKey = puk
+		} else {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported signing method %s", method.Alg())
+	}
+
+	jwk, err := newJSONWebKey(publicKey, method.Alg())
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyringKey{
+		jwk:        jwk,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// signingMethod returns the supported signing method of the algorithm
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256, nil
+	case jwt.SigningMethodES256.Alg():
+		return jwt.SigningMethodES256, nil
+	case jwt.SigningMethodEdDSA.Alg():
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, errors.Errorf("unsupported signing algorithm %s", alg)
+}
+
+// filesVersion identifies the current state of the files by their names,
+// sizes and modification times
+func filesVersion(files []keyFile) (string, error) {
+	var sb strings.Builder
+	for _, f := range files {
+		fi, err := os.Stat(f.path)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&sb, "%s:%t:%d:%d;", f.path, f.active, fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	return sb.String(), nil
+}
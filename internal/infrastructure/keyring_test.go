@@ -0,0 +1,255 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// writeKeyForTesting writes a new key of the algorithm to the file, as a
+// private key or as a public key only, and returns its key id
+func writeKeyForTesting(t *testing.T, path string, alg string, private bool) string {
+	var prk crypto.Signer
+	var err error
+
+	switch alg {
+	case "RS256":
+		prk, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		prk, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		prk, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "EdDSA":
+		_, prk, err = ed25519.GenerateKey(rand.Reader)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block *pem.Block
+	if private {
+		b, err := x509.MarshalPKCS8PrivateKey(prk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: b}
+	} else {
+		b, err := x509.MarshalPKIXPublicKey(prk.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: b}
+	}
+
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, err := newJSONWebKey(prk.Public(), alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return jwk.Kid
+}
+
+func TestKeyring_Load(t *testing.T) {
+	dir := t.TempDir()
+
+	k1 := writeKeyForTesting(t, filepath.Join(dir, "2022-01.pem"), "RS256", true)
+	k2 := writeKeyForTesting(t, filepath.Join(dir, "2022-02.pem"), "RS256", true)
+	writeKeyForTesting(t, filepath.Join(dir, "2021-12.pem"), "RS256", false)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	kr := newDirKeyring(dir, "2022-01.pem", jwt.SigningMethodRS256)
+
+	if _, err := kr.load(true); err != nil {
+		t.Fatalf("keyring.load() error = %v", err)
+	}
+
+	if got := kr.signingKey().jwk.Kid; got != k1 {
+		t.Errorf("keyring.signingKey() = %v, want %v", got, k1)
+	}
+
+	if got := len(kr.publicKeys()); got != 3 {
+		t.Errorf("keyring.publicKeys() has %d keys, want %d", got, 3)
+	}
+
+	oldToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{})
+	oldToken.Header["kid"] = kr.signingKey().jwk.Kid
+
+	t.Run("should not reload unchanged files", func(t *testing.T) {
+		reloaded, err := kr.load(false)
+		if err != nil || reloaded {
+			t.Errorf("keyring.load() = %v, %v, want false, nil", reloaded, err)
+		}
+	})
+
+	t.Run("should rotate the active key", func(t *testing.T) {
+		kr2 := newDirKeyring(dir, "2022-02.pem", jwt.SigningMethodRS256)
+		kr.files = kr2.files
+
+		reloaded, err := kr.load(false)
+		if err != nil || !reloaded {
+			t.Fatalf("keyring.load() = %v, %v, want true, nil", reloaded, err)
+		}
+
+		if got := kr.signingKey().jwk.Kid; got != k2 {
+			t.Errorf("keyring.signingKey() = %v, want %v", got, k2)
+		}
+
+		key, err := kr.verificationKey(oldToken)
+		if err != nil {
+			t.Fatalf("keyring.verificationKey() error = %v", err)
+		}
+
+		if key.jwk.Kid != k1 {
+			t.Errorf("keyring.verificationKey() = %v, want %v", key.jwk.Kid, k1)
+		}
+	})
+
+	t.Run("should reload when a file changes", func(t *testing.T) {
+		writeKeyForTesting(t, filepath.Join(dir, "2022-01.pem"), "RS256", true)
+
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, "2022-01.pem"), later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		reloaded, err := kr.load(false)
+		if err != nil || !reloaded {
+			t.Fatalf("keyring.load() = %v, %v, want true, nil", reloaded, err)
+		}
+
+		if _, err := kr.verificationKey(oldToken); err == nil {
+			t.Errorf("keyring.verificationKey() error = nil, want unknown key")
+		}
+	})
+
+	t.Run("should keep the keys when a file is invalid", func(t *testing.T) {
+		active := kr.signingKey()
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "2022-03.pem"), []byte("invalid"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := kr.load(true); err == nil {
+			t.Errorf("keyring.load() error = nil, want error")
+		}
+
+		if kr.signingKey() != active {
+			t.Errorf("keyring.signingKey() changed after failed load")
+		}
+	})
+
+	t.Run("should fail when the active key is public only", func(t *testing.T) {
+		if _, err := newDirKeyring(dir, "2021-12.pem", jwt.SigningMethodRS256).load(true); err == nil {
+			t.Errorf("keyring.load() error = nil, want error")
+		}
+	})
+}
+
+func TestKeyring_SigningMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		keyAlg  string
+		wantErr bool
+	}{
+		{
+			name:   "should load rsa keys for RS256",
+			method: jwt.SigningMethodRS256,
+			keyAlg: "RS256",
+		},
+		{
+			name:   "should load P-256 keys for ES256",
+			method: jwt.SigningMethodES256,
+			keyAlg: "ES256",
+		},
+		{
+			name:   "should load ed25519 keys for EdDSA",
+			method: jwt.SigningMethodEdDSA,
+			keyAlg: "EdDSA",
+		},
+		{
+			name:    "should fail to load P-384 keys for ES256",
+			method:  jwt.SigningMethodES256,
+			keyAlg:  "ES384",
+			wantErr: true,
+		},
+		{
+			name:    "should fail to load rsa keys for ES256",
+			method:  jwt.SigningMethodES256,
+			keyAlg:  "RS256",
+			wantErr: true,
+		},
+		{
+			name:    "should fail to load ed25519 keys for RS256",
+			method:  jwt.SigningMethodRS256,
+			keyAlg:  "EdDSA",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			kid := writeKeyForTesting(t, filepath.Join(dir, "active.pem"), tt.keyAlg, true)
+			writeKeyForTesting(t, filepath.Join(dir, "retired.pem"), tt.keyAlg, false)
+
+			kr := newDirKeyring(dir, "active.pem", tt.method)
+
+			_, err := kr.load(true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("keyring.load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if kr.signingKey().jwk.Kid != kid {
+				t.Errorf("keyring.signingKey() = %v, want %v", kr.signingKey().jwk.Kid, kid)
+			}
+
+			token := jwt.NewWithClaims(tt.method, jwt.StandardClaims{})
+			token.Header["kid"] = kid
+
+			signed, err := token.SignedString(kr.signingKey().privateKey)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			if _, err := jwt.Parse(signed, kr.keyFunc); err != nil {
+				t.Errorf("failed to verify token: %v", err)
+			}
+
+			hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{}).SignedString([]byte(kid))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := jwt.Parse(hs256, kr.keyFunc); err == nil {
+				t.Errorf("verified token of another signing method")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// unsafeFileNameChars are replaced in the names of the mail files
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._@-]`)
+
+// LogMailer writes the emails to the log. It is meant for the local
+// development, since the messages contain the verification tokens.
+type LogMailer struct {
+	app.Mailer
+	config *config.Config
+	logger logger.ILogger
+}
+
+func NewLogMailer(config *config.Config, logger logger.ILogger) *LogMailer {
+	return &LogMailer{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Send writes the message to the log
+func (m *LogMailer) Send(ctx context.Context, msg *app.MailMessage) error {
+	m.logger.Infof("mail from %s to %s: %s\n%s", m.config.Mail.From, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each email to a file in the mail directory
+type FileMailer struct {
+	app.Mailer
+	config *config.Config
+	logger logger.ILogger
+}
+
+func NewFileMailer(config *config.Config, logger logger.ILogger) *FileMailer {
+	return &FileMailer{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Send writes the message to a file named after the time and the recipient
+func (m *FileMailer) Send(ctx context.Context, msg *app.MailMessage) error {
+	if err := os.MkdirAll(m.config.Mail.Dir, 0o700); err != nil {
+		m.logger.Warnf("error while creating mail directory: %s", err)
+		return app.NewInternalServerError(errors.New("error while sending mail"))
+	}
+
+	now := time.Now()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.Mail.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), unsafeFileNameChars.ReplaceAllString(msg.To, "_"))
+	if err := os.WriteFile(filepath.Join(m.config.Mail.Dir, name), []byte(b.String()), 0o600); err != nil {
+		m.logger.Warnf("error while writing mail: %s", err)
+		return app.NewInternalServerError(errors.New("error while sending mail"))
+	}
+
+	return nil
+}
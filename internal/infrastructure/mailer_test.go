@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	c := config.New()
+	c.Mail.Dir = filepath.Join(t.TempDir(), "mails")
+	c.Mail.From = "no-reply@hey-taxi.app"
+
+	m := NewFileMailer(c, NewLoggerMock())
+
+	msg := &app.MailMessage{To: "../foo@bar.com", Subject: "Verify your email", Body: "token"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("FileMailer.Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.Mail.Dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("FileMailer.Send() files = %v, %v", files, err)
+	}
+
+	if name := filepath.Base(files[0]); !strings.HasSuffix(name, "-.._foo@bar.com.eml") {
+		t.Errorf("FileMailer.Send() file name = %v", name)
+	}
+
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"From: no-reply@hey-taxi.app\r\n", "To: ../foo@bar.com\r\n", "Subject: Verify your email\r\n", "\r\n\r\ntoken"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("FileMailer.Send() mail = %q, want to contain %q", b, want)
+		}
+	}
+}
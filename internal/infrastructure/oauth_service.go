@@ -0,0 +1,904 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+const tokenTypeBearer = "Bearer"
+
+type OAuthService struct {
+	app.OAuthService
+	config   *config.Config
+	logger   logger.ILogger
+	auth     app.AuthService
+	repo     app.Repository
+	ts       app.TokenService
+	clients  app.ClientService
+	codes    app.AuthorizationCodeStore
+	devices  app.DeviceAuthorizationStore
+	consents app.ConsentRepository
+}
+
+func NewOAuthService(config *config.Config, logger logger.ILogger, auth app.AuthService, repo app.Repository, ts app.TokenService, clients app.ClientService, codes app.AuthorizationCodeStore, devices app.DeviceAuthorizationStore, consents app.ConsentRepository) *OAuthService {
+	return &OAuthService{
+		config:   config,
+		logger:   logger,
+		auth:     auth,
+		repo:     repo,
+		ts:       ts,
+		clients:  clients,
+		codes:    codes,
+		devices:  devices,
+		consents: consents,
+	}
+}
+
+// Token issues tokens for the grant of the request (RFC 6749)
+func (s *OAuthService) Token(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, err.Error())
+	}
+
+	switch r.GrantType {
+	case app.GrantTypePassword:
+		return s.passwordGrant(ctx, r)
+	case app.GrantTypeRefreshToken:
+		return s.refreshTokenGrant(ctx, r)
+	case app.GrantTypeAuthorizationCode:
+		return s.authorizationCodeGrant(ctx, r)
+	case app.GrantTypeClientCredentials:
+		return s.clientCredentialsGrant(ctx, r)
+	case app.GrantTypeDeviceCode:
+		return s.deviceCodeGrant(ctx, r)
+	case app.GrantTypeTokenExchange:
+		return s.tokenExchangeGrant(ctx, r)
+	}
+
+	return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrUnsupportedGrantType, "")
+}
+
+// GrantTypes returns the supported grant types
+func (s *OAuthService) GrantTypes() []string {
+	return []string{app.GrantTypePassword, app.GrantTypeRefreshToken, app.GrantTypeAuthorizationCode, app.GrantTypeClientCredentials, app.GrantTypeDeviceCode, app.GrantTypeTokenExchange}
+}
+
+// ValidateAuthorizeRequest validates the client and the redirect uri of the
+// authorization request before the user is authenticated
+func (s *OAuthService) ValidateAuthorizeRequest(ctx context.Context, r *app.AuthorizeRequest) error {
+	_, err := s.authorizeClient(ctx, r)
+	return err
+}
+
+// Consent returns the scopes which the client requests from the user. The
+// consent is not required when the user already granted all of them to the
+// client.
+func (s *OAuthService) Consent(ctx context.Context, uid string, r *app.AuthorizeRequest) (*app.ConsentResponse, error) {
+	client, grant, err := s.resolveAuthorization(ctx, uid, r)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := app.ParseScope(grant.Scope)
+
+	var granted []string
+	consent, err := s.consents.GetConsent(ctx, uid, client.Id)
+	if err != nil && !errors.Is(err, app.ErrConsentNotFound) {
+		return nil, err
+	}
+
+	if consent != nil {
+		granted = consent.Scopes
+	}
+
+	return &app.ConsentResponse{
+		ClientId:      client.Id,
+		ClientName:    client.Name,
+		Scopes:        scopes,
+		GrantedScopes: granted,
+		Required:      consent == nil || !consent.HasScopes(scopes...),
+	}, nil
+}
+
+// Authorize issues an authorization code to the client for the user and
+// records the consent of the user. The code is bound to the client, the
+// redirect uri and the PKCE challenge.
+func (s *OAuthService) Authorize(ctx context.Context, uid string, r *app.AuthorizeRequest) (*app.AuthorizeResponse, error) {
+	client, grant, err := s.resolveAuthorization(ctx, uid, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.consents.GrantConsent(ctx, uid, client.Id, app.ParseScope(grant.Scope)); err != nil {
+		return nil, err
+	}
+
+	code, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if err := s.codes.Save(ctx, &model.AuthorizationCode{
+		Id:                  hashCode(code),
+		ClientId:            client.Id,
+		UserId:              uid,
+		RedirectUri:         r.RedirectUri,
+		Audience:            grant.Audience,
+		Scope:               grant.Scope,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		Nonce:               r.Nonce,
+		ExpiresAt:           now.Add(time.Duration(s.config.Oauth.AuthorizationCodeExp) * time.Second),
+		CreatedAt:           now,
+	}); err != nil {
+		return nil, err
+	}
+
+	redirectUri, err := url.Parse(r.RedirectUri)
+	if err != nil {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "invalid redirect_uri")
+	}
+
+	q := redirectUri.Query()
+	q.Set("code", code)
+	if r.State != "" {
+		q.Set("state", r.State)
+	}
+	redirectUri.RawQuery = q.Encode()
+
+	return &app.AuthorizeResponse{RedirectUri: redirectUri.String()}, nil
+}
+
+// UserInfo returns the claims of the user which the scopes of the access token
+// allow (OpenID Connect Core section 5.3)
+func (s *OAuthService) UserInfo(ctx context.Context, claims app.Claims) (*app.UserInfoResponse, error) {
+	if claims.GetTokenType() != app.AccessTokenTypeUser || !app.HasScopes(claims.GetScope(), app.ScopeOpenId) {
+		return nil, app.NewOAuthError(http.StatusForbidden, app.OAuthErrInsufficientScope, "")
+	}
+
+	user, err := s.auth.Me(ctx, claims.GetSubject())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &app.UserInfoResponse{Sub: user.Id}
+
+	if app.HasScopes(claims.GetScope(), app.ScopeProfile) {
+		res.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		res.GivenName = user.FirstName
+		res.FamilyName = user.LastName
+		res.Picture = user.Avatar
+	}
+
+	if app.HasScopes(claims.GetScope(), app.ScopeEmail) {
+		verified := user.EmailVerified
+		res.Email = user.Email
+		res.EmailVerified = &verified
+	}
+
+	return res, nil
+}
+
+// DeviceAuthorization starts the device flow of the client (RFC 8628). The
+// requested scope is granted when the user approves the request.
+func (s *OAuthService) DeviceAuthorization(ctx context.Context, r *app.DeviceAuthorizationRequest) (*app.DeviceAuthorizationResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, err.Error())
+	}
+
+	client, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	scopes := app.ParseScope(r.Scope)
+	if !client.AllowsScopes(scopes...) {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidScope, "scope is not allowed for the client")
+	}
+
+	verificationUri := s.config.Oauth.VerificationUrl
+	if verificationUri == "" {
+		s.logger.Warnf("oauth verification url is not configured")
+		return nil, app.NewOAuthError(http.StatusInternalServerError, app.OAuthErrServerError, "")
+	}
+
+	deviceCode, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	// the user codes are short, so a taken code is generated again
+	var userCode string
+	for attempt := 1; ; attempt++ {
+		userCode, err = newUserCode()
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+
+		err = s.devices.Save(ctx, &model.DeviceAuthorization{
+			Id:        hashCode(deviceCode),
+			UserCode:  userCode,
+			ClientId:  client.Id,
+			Status:    model.DeviceAuthorizationPending,
+			Scope:     strings.Join(scopes, " "),
+			Interval:  s.config.Oauth.DeviceCodeInterval,
+			ExpiresAt: now.Add(time.Duration(s.config.Oauth.DeviceCodeExp) * time.Second),
+			CreatedAt: now,
+		})
+		if err == nil {
+			break
+		}
+
+		if err != app.ErrUserCodeExists {
+			return nil, err
+		}
+
+		if attempt == maxUserCodeAttempts {
+			s.logger.Warnf("no free user code is found in %d attempts", maxUserCodeAttempts)
+			return nil, app.NewOAuthError(http.StatusInternalServerError, app.OAuthErrServerError, "")
+		}
+	}
+
+	complete, err := url.Parse(verificationUri)
+	if err != nil {
+		s.logger.Warnf("invalid oauth verification url: %s", err)
+		return nil, app.NewOAuthError(http.StatusInternalServerError, app.OAuthErrServerError, "")
+	}
+
+	q := complete.Query()
+	q.Set("user_code", formatUserCode(userCode))
+	complete.RawQuery = q.Encode()
+
+	return &app.DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationUri:         verificationUri,
+		VerificationUriComplete: complete.String(),
+		ExpiresIn:               s.config.Oauth.DeviceCodeExp,
+		Interval:                s.config.Oauth.DeviceCodeInterval,
+	}, nil
+}
+
+// GetDeviceAuthorization returns the pending request of the user code
+func (s *OAuthService) GetDeviceAuthorization(ctx context.Context, userCode string) (*app.DeviceVerificationResponse, error) {
+	d, err := s.devices.GetByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		return nil, deviceAuthorizationError(err)
+	}
+
+	client, err := s.clients.GetClient(ctx, d.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &app.DeviceVerificationResponse{
+		UserCode:   formatUserCode(d.UserCode),
+		ClientId:   client.Id,
+		ClientName: client.Name,
+		Scope:      d.Scope,
+	}, nil
+}
+
+// DecideDeviceAuthorization approves or denies the request of the user code.
+// The grant is resolved for the user on approval, so that the device gets
+// the same scopes as the user would get on login.
+func (s *OAuthService) DecideDeviceAuthorization(ctx context.Context, uid string, r *app.DeviceDecisionRequest) error {
+	if err := app.Validate(r); err != nil {
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, err.Error())
+	}
+
+	userCode := normalizeUserCode(r.UserCode)
+
+	if !r.Approve {
+		if err := s.devices.Decide(ctx, userCode, model.DeviceAuthorizationDenied, uid, nil); err != nil {
+			return deviceAuthorizationError(err)
+		}
+
+		return nil
+	}
+
+	d, err := s.devices.GetByUserCode(ctx, userCode)
+	if err != nil {
+		return deviceAuthorizationError(err)
+	}
+
+	user, err := s.repo.GetUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.clients.GetClient(ctx, d.ClientId)
+	if err != nil {
+		return err
+	}
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: client.Audience, Scope: d.Scope})
+	if err != nil {
+		return oauthError(err)
+	}
+
+	if !client.AllowsScopes(app.ParseScope(grant.Scope)...) {
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidScope, "scope is not allowed for the client")
+	}
+
+	if err := s.devices.Decide(ctx, userCode, model.DeviceAuthorizationApproved, uid, grant); err != nil {
+		return deviceAuthorizationError(err)
+	}
+
+	if err := s.consents.GrantConsent(ctx, uid, client.Id, app.ParseScope(grant.Scope)); err != nil {
+		return err
+	}
+
+	s.logger.Infof("device authorization of client %s is approved by user %s", client.Id, uid)
+
+	return nil
+}
+
+// resolveAuthorization returns the client of the authorization request and
+// the grant which the user would get
+func (s *OAuthService) resolveAuthorization(ctx context.Context, uid string, r *app.AuthorizeRequest) (*model.OAuthClient, *app.TokenGrant, error) {
+	client, err := s.authorizeClient(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repo.GetUser(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: client.Audience, Scope: r.Scope})
+	if err != nil {
+		return nil, nil, oauthError(err)
+	}
+
+	if !client.AllowsScopes(app.ParseScope(grant.Scope)...) {
+		return nil, nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidScope, "scope is not allowed for the client")
+	}
+
+	return client, grant, nil
+}
+
+// authorizeClient validates the authorization request and returns its client
+func (s *OAuthService) authorizeClient(ctx context.Context, r *app.AuthorizeRequest) (*model.OAuthClient, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, err.Error())
+	}
+
+	client, err := s.clients.GetClient(ctx, r.ClientId)
+	if err != nil {
+		if errors.Is(err, app.ErrClientNotFound) {
+			return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "unknown client")
+		}
+		return nil, err
+	}
+
+	if !client.HasRedirectUri(r.RedirectUri) {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "redirect_uri is not registered for the client")
+	}
+
+	if r.ResponseType != app.ResponseTypeCode {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrUnsupportedResponse, "")
+	}
+
+	if r.CodeChallenge == "" || r.CodeChallengeMethod != app.CodeChallengeMethodS256 {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "code_challenge with S256 method is required")
+	}
+
+	return client, nil
+}
+
+// passwordGrant issues tokens for the credentials of the user. An ID token is
+// issued when the openid scope is granted to an identified client.
+func (s *OAuthService) passwordGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.Username == "" || r.Password == "" {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "username and password are required")
+	}
+
+	var client *model.OAuthClient
+	var clientId string
+	if r.ClientId != "" {
+		c, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+		if err != nil {
+			return nil, oauthError(err)
+		}
+
+		client = c
+		clientId = c.Id
+	} else if app.HasScopes(r.Scope, app.ScopeOpenId) {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "client_id is required for the openid scope")
+	}
+
+	res, err := s.auth.Login(ctx, &app.LoginRequest{
+		Email:    r.Username,
+		Password: r.Password,
+		Audience: r.Audience,
+		Scope:    r.Scope,
+		ClientId: clientId,
+	})
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	var idToken string
+	if client != nil && app.HasScopes(res.Scope, app.ScopeOpenId) {
+		user, err := s.repo.GetUser(ctx, res.UserDto.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if idToken, err = s.idToken(ctx, user, client.Id, res.Scope, "", time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &app.TokenResponse{
+		AccessToken:  res.AccessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    res.AccessTokenExpiresIn,
+		RefreshToken: res.RefreshToken,
+		Scope:        res.Scope,
+		IdToken:      idToken,
+	}, nil
+}
+
+// refreshTokenGrant exchanges the refresh token for a new token pair. The
+// client has to authenticate to redeem the refresh tokens issued to it.
+func (s *OAuthService) refreshTokenGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.RefreshToken == "" {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "refresh_token is required")
+	}
+
+	var clientId string
+	if r.ClientId != "" {
+		client, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+		if err != nil {
+			return nil, oauthError(err)
+		}
+
+		clientId = client.Id
+	}
+
+	res, err := s.auth.RefreshToken(ctx, &app.RefreshTokenRequest{
+		Token:    r.RefreshToken,
+		Scope:    r.Scope,
+		ClientId: clientId,
+	})
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	if res.ClientId != "" {
+		s.touchConsent(ctx, res.UserId, res.ClientId)
+	}
+
+	return &app.TokenResponse{
+		AccessToken:  res.AccessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    res.AccessTokenExpiresIn,
+		RefreshToken: res.RefreshToken,
+		Scope:        res.Scope,
+	}, nil
+}
+
+// authorizationCodeGrant exchanges the authorization code for a token pair
+func (s *OAuthService) authorizationCodeGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.Code == "" || r.RedirectUri == "" || r.ClientId == "" || r.CodeVerifier == "" {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "code, redirect_uri, client_id and code_verifier are required")
+	}
+
+	client, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	code, err := s.codes.Consume(ctx, hashCode(r.Code))
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	if code.ClientId != client.Id || code.RedirectUri != r.RedirectUri {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidGrant, "")
+	}
+
+	if !verifyCodeChallenge(code.CodeChallenge, r.CodeVerifier) {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidGrant, "invalid code_verifier")
+	}
+
+	user, err := s.repo.GetUser(ctx, code.UserId)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	grant := &app.TokenGrant{Audience: code.Audience, Scope: code.Scope, ClientId: client.Id}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate access token: %s", err)
+		return nil, err
+	}
+
+	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate refresh token: %s", err)
+		return nil, err
+	}
+
+	// the user authenticated when the code was issued
+	idToken, err := s.idToken(ctx, user, client.Id, grant.Scope, code.Nonce, code.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.touchConsent(ctx, code.UserId, client.Id)
+
+	return &app.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    s.config.Jwt.AccessTokenExp,
+		RefreshToken: refreshToken,
+		Scope:        grant.Scope,
+		IdToken:      idToken,
+	}, nil
+}
+
+// clientCredentialsGrant issues an access token to the confidential client
+// itself. No refresh token is issued, since the client can authenticate again.
+func (s *OAuthService) clientCredentialsGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.ClientId == "" {
+		return nil, app.NewOAuthError(http.StatusUnauthorized, app.OAuthErrInvalidClient, "")
+	}
+
+	client, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	if !client.Confidential {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrUnauthorizedClient, "client credentials grant is only allowed for confidential clients")
+	}
+
+	grant, err := s.ts.ResolveClientGrant(ctx, client, &app.TokenGrant{Audience: r.Audience, Scope: r.Scope})
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	accessToken, err := s.ts.GenerateClientAccessToken(ctx, client, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate client access token: %s", err)
+		return nil, err
+	}
+
+	return &app.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   s.config.Jwt.AccessTokenExp,
+		Scope:       grant.Scope,
+	}, nil
+}
+
+// deviceCodeGrant issues tokens to the device once the user approves the
+// request. The device is told to slow down when it polls faster than the
+// interval.
+func (s *OAuthService) deviceCodeGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.DeviceCode == "" || r.ClientId == "" {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "device_code and client_id are required")
+	}
+
+	client, err := s.clients.Authenticate(ctx, r.ClientId, r.ClientSecret)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	now := time.Now()
+
+	d, err := s.devices.Poll(ctx, hashCode(r.DeviceCode), now)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	if d.ClientId != client.Id {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidGrant, "")
+	}
+
+	if d.IsExpired() {
+		_ = s.devices.Delete(ctx, d.Id)
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrExpiredToken, "")
+	}
+
+	switch d.Status {
+	case model.DeviceAuthorizationDenied:
+		_ = s.devices.Delete(ctx, d.Id)
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrAccessDenied, "")
+	case model.DeviceAuthorizationPending:
+		if !d.LastPolledAt.IsZero() && now.Sub(d.LastPolledAt) < time.Duration(d.Interval)*time.Second {
+			return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrSlowDown, "")
+		}
+
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrAuthorizationPending, "")
+	}
+
+	// the request is removed first, so that concurrent polls cannot get
+	// tokens for the same approval
+	if err := s.devices.Delete(ctx, d.Id); err != nil {
+		return nil, oauthError(err)
+	}
+
+	user, err := s.repo.GetUser(ctx, d.UserId)
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	grant := &app.TokenGrant{Audience: d.Audience, Scope: d.Scope, ClientId: client.Id}
+
+	accessToken, err := s.ts.GenerateAccessToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate access token: %s", err)
+		return nil, err
+	}
+
+	refreshToken, err := s.ts.GenerateRefreshToken(ctx, user, grant)
+	if err != nil {
+		s.logger.Warnf("failed to generate refresh token: %s", err)
+		return nil, err
+	}
+
+	idToken, err := s.idToken(ctx, user, client.Id, grant.Scope, "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.touchConsent(ctx, d.UserId, client.Id)
+
+	return &app.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    s.config.Jwt.AccessTokenExp,
+		RefreshToken: refreshToken,
+		Scope:        grant.Scope,
+		IdToken:      idToken,
+	}, nil
+}
+
+// tokenExchangeGrant exchanges the access token of a user for a token which
+// the actor uses on behalf of the user (RFC 8693). The new token cannot have
+// more scopes than the subject token, keeps its audience and expires with it at
+// the latest.
+func (s *OAuthService) tokenExchangeGrant(ctx context.Context, r *app.TokenRequest) (*app.TokenResponse, error) {
+	if r.SubjectToken == "" || r.ActorToken == "" {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "subject_token and actor_token are required")
+	}
+
+	if r.SubjectTokenType != app.TokenTypeUrnAccessToken || r.ActorTokenType != app.TokenTypeUrnAccessToken {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "only access tokens can be exchanged")
+	}
+
+	if r.RequestedTokenType != "" && r.RequestedTokenType != app.TokenTypeUrnAccessToken {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "only access tokens can be requested")
+	}
+
+	subject, err := s.ts.ParseToken(ctx, r.SubjectToken)
+	if err != nil || subject.GetTokenType() != app.AccessTokenTypeUser {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "invalid subject_token")
+	}
+
+	// the actor has to act for itself, so that delegated tokens cannot be
+	// used to exchange the tokens of other users
+	actor, err := s.ts.ParseToken(ctx, r.ActorToken)
+	if err != nil || actor.GetActor() != nil {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "invalid actor_token")
+	}
+
+	if !s.allowsActor(actor) {
+		s.logger.Warnf("token exchange of %s %s on behalf of user %s is denied", actor.GetTokenType(), actor.GetSubject(), subject.GetSubject())
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "actor is not allowed to exchange tokens")
+	}
+
+	user, err := s.repo.GetUser(ctx, subject.GetSubject())
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	// the audience is kept, so that the actor cannot reach the services which
+	// the user did not issue the subject token for
+	if r.Audience != "" && r.Audience != subject.GetAudience() {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidTarget, "audience differs from the audience of the subject_token")
+	}
+
+	scope := r.Scope
+	if scope == "" {
+		scope = subject.GetScope()
+	}
+
+	grant, err := s.ts.ResolveGrant(ctx, user, &app.TokenGrant{Audience: subject.GetAudience(), Scope: scope, ClientId: subject.GetClientId()})
+	if err != nil {
+		return nil, oauthError(err)
+	}
+
+	if !app.HasScopes(subject.GetScope(), app.ParseScope(grant.Scope)...) {
+		return nil, app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidScope, "scope exceeds the scope of the subject_token")
+	}
+
+	notAfter := time.Unix(subject.GetExpiresAt(), 0)
+
+	accessToken, err := s.ts.GenerateDelegatedAccessToken(ctx, user, &app.DelegatedTokenRequest{
+		Grant:    grant,
+		Actor:    &app.Actor{Subject: actor.GetSubject(), Actor: subject.GetActor()},
+		NotAfter: notAfter,
+	})
+	if err != nil {
+		s.logger.Warnf("failed to generate delegated access token: %s", err)
+		return nil, err
+	}
+
+	s.logger.Infof("token of user %s is exchanged by %s %s for audience %q and scope %q", user.GetIdString(), actor.GetTokenType(), actor.GetSubject(), grant.Audience, grant.Scope)
+
+	expiresIn := s.config.Jwt.AccessTokenExp
+	if left := int(time.Until(notAfter).Seconds()); left < expiresIn {
+		expiresIn = left
+	}
+
+	return &app.TokenResponse{
+		AccessToken:     accessToken,
+		TokenType:       tokenTypeBearer,
+		ExpiresIn:       expiresIn,
+		Scope:           grant.Scope,
+		IssuedTokenType: app.TokenTypeUrnAccessToken,
+	}, nil
+}
+
+// touchConsent records the use of the consent of the user for the client. The
+// tokens are issued anyway when it fails.
+func (s *OAuthService) touchConsent(ctx context.Context, uid string, clientId string) {
+	if err := s.consents.TouchConsent(ctx, uid, clientId); err != nil {
+		s.logger.Warnf("failed to update consent of user %s for client %s: %s", uid, clientId, err)
+	}
+}
+
+// allowsActor returns true if the actor may act on behalf of the users by
+// the token exchange policy. The users act with their own tokens only, not
+// with the tokens issued to the clients or the delegated ones.
+func (s *OAuthService) allowsActor(actor app.Claims) bool {
+	if actor.GetTokenType() == app.AccessTokenTypeClient {
+		return contains(configList(s.config.Oauth.TokenExchangeClients), actor.GetSubject())
+	}
+
+	return app.IsFirstPartyToken(actor) && contains(configList(s.config.Oauth.TokenExchangeRoles), actor.GetRole())
+}
+
+// idToken generates the ID token of the client when the openid scope is
+// granted
+func (s *OAuthService) idToken(ctx context.Context, user *model.User, clientId string, scope string, nonce string, authTime time.Time) (string, error) {
+	if !app.HasScopes(scope, app.ScopeOpenId) {
+		return "", nil
+	}
+
+	idToken, err := s.ts.GenerateIdToken(ctx, user, &app.IdTokenRequest{
+		ClientId: clientId,
+		Scope:    scope,
+		Nonce:    nonce,
+		AuthTime: authTime,
+	})
+	if err != nil {
+		s.logger.Warnf("failed to generate id token: %s", err)
+		return "", err
+	}
+
+	return idToken, nil
+}
+
+// hashCode returns the id of the authorization or device code in the store
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyCodeChallenge verifies the code verifier against the S256 challenge
+// (RFC 7636)
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// userCodeCharset is the charset of the user codes. It has no vowels to avoid
+// words and no characters which are easy to confuse (RFC 8628 section 6.1).
+const userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+// maxUserCodeAttempts is the number of the user codes generated for a device
+// authorization request before giving up
+const maxUserCodeAttempts = 5
+
+// newUserCode generates a random user code of 8 characters
+func newUserCode() (string, error) {
+	b := make([]byte, 8)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeCharset))))
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = userCodeCharset[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// normalizeUserCode removes the separators of the user code typed by the
+// user and converts it to upper case
+func normalizeUserCode(code string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(code) {
+		if strings.ContainsRune(userCodeCharset, c) {
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// formatUserCode formats the user code as XXXX-XXXX to be easier to type
+func formatUserCode(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+
+	return code[:4] + "-" + code[4:]
+}
+
+// deviceAuthorizationError converts the error of a user code lookup
+func deviceAuthorizationError(err error) error {
+	if errors.Is(err, app.ErrDeviceAuthorizationNotFound) {
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidRequest, "unknown or expired user code")
+	}
+
+	return err
+}
+
+// oauthError converts the error of a grant to an OAuth error. Internal errors
+// are returned as is.
+func oauthError(err error) error {
+	var oauthErr *app.OAuthError
+	if errors.As(err, &oauthErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, app.ErrInvalidScope):
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidScope, "")
+	case errors.Is(err, app.ErrInvalidAudience):
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidTarget, "")
+	case errors.Is(err, app.ErrInvalidClient):
+		return app.NewOAuthError(http.StatusUnauthorized, app.OAuthErrInvalidClient, "")
+	case errors.Is(err, app.ErrEmailNotVerified):
+		return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidGrant, "email is not verified")
+	}
+
+	var appErr *app.Error
+	if errors.As(err, &appErr) && appErr.Code() >= http.StatusInternalServerError {
+		return err
+	}
+
+	return app.NewOAuthError(http.StatusBadRequest, app.OAuthErrInvalidGrant, "")
+}
@@ -0,0 +1,1174 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOAuthService_Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	auth := mock.NewMockAuthService(ctrl)
+	service := NewOAuthService(config.New(), NewLoggerMock(), auth, nil, nil, nil, nil, nil, nil)
+
+	auth.EXPECT().Login(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *app.LoginRequest) (*app.LoginResponse, error) {
+			switch {
+			case r.Scope == "unknown":
+				return nil, app.ErrInvalidScope
+			case r.Password == "password":
+				return &app.LoginResponse{AccessToken: "access_token", AccessTokenExpiresIn: 60, RefreshToken: "refresh_token", Scope: r.Scope}, nil
+			case r.Password == "unavailable":
+				return nil, app.NewInternalServerError(errors.New("unavailable"))
+			}
+
+			return nil, errors.New("invalid email or password")
+		}).AnyTimes()
+
+	auth.EXPECT().RefreshToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *app.RefreshTokenRequest) (*app.RefreshTokenResponse, error) {
+			if r.Token == "refresh_token" {
+				return &app.RefreshTokenResponse{AccessToken: "access_token", AccessTokenExpiresIn: 60, RefreshToken: "rotated_refresh_token"}, nil
+			}
+
+			return nil, app.ErrRefreshTokenReused
+		}).AnyTimes()
+
+	tests := []struct {
+		name       string
+		req        *app.TokenRequest
+		want       *app.TokenResponse
+		wantErr    string
+		wantStatus int
+	}{
+		{
+			name: "should issue tokens for password grant",
+			req:  &app.TokenRequest{GrantType: "password", Username: "foo@bar.com", Password: "password", Scope: "rides:read"},
+			want: &app.TokenResponse{AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "refresh_token", Scope: "rides:read"},
+		},
+		{
+			name: "should issue tokens for refresh token grant",
+			req:  &app.TokenRequest{GrantType: "refresh_token", RefreshToken: "refresh_token"},
+			want: &app.TokenResponse{AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "rotated_refresh_token"},
+		},
+		{
+			name:    "should fail when grant type is missing",
+			req:     &app.TokenRequest{},
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when grant type is not supported",
+			req:     &app.TokenRequest{GrantType: "implicit"},
+			wantErr: app.OAuthErrUnsupportedGrantType,
+		},
+		{
+			name:    "should fail when password is missing",
+			req:     &app.TokenRequest{GrantType: "password", Username: "foo@bar.com"},
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when credentials are invalid",
+			req:     &app.TokenRequest{GrantType: "password", Username: "foo@bar.com", Password: "wrong"},
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+		{
+			name:    "should fail when scope is invalid",
+			req:     &app.TokenRequest{GrantType: "password", Username: "foo@bar.com", Password: "password", Scope: "unknown"},
+			wantErr: app.OAuthErrInvalidScope,
+		},
+		{
+			name:    "should fail when refresh token is reused",
+			req:     &app.TokenRequest{GrantType: "refresh_token", RefreshToken: "reused_refresh_token"},
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return internal errors as is",
+			req:        &app.TokenRequest{GrantType: "password", Username: "foo@bar.com", Password: "unavailable"},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Token(ctx, tt.req)
+
+			if tt.wantStatus != 0 {
+				var appErr *app.Error
+				if !errors.As(err, &appErr) || appErr.Code() != tt.wantStatus {
+					t.Errorf("OAuthService.Token() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.Token() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.Token() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OAuthService.Token() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthService_AuthorizationCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := &model.User{Role: model.RoleUser}
+
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetUser(ctx, "uid").Return(user, nil).AnyTimes()
+
+	ts := mock.NewMockTokenService(ctrl)
+	ts.EXPECT().ResolveGrant(ctx, user, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			if g.Scope == "" {
+				return &app.TokenGrant{Audience: g.Audience, Scope: "rides:read"}, nil
+			}
+			if g.Scope == "unknown" {
+				return nil, app.ErrInvalidScope
+			}
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, user, gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, user, gomock.Any()).Return("refresh_token", nil).AnyTimes()
+	ts.EXPECT().GenerateIdToken(ctx, user, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, r *app.IdTokenRequest) (string, error) {
+			return "id_token:" + r.ClientId + ":" + r.Nonce, nil
+		}).AnyTimes()
+
+	client := &model.OAuthClient{
+		Id:           "client",
+		RedirectUris: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"openid", "rides:read", "rides:write"},
+		Audience:     "rides",
+	}
+
+	c := config.New()
+	c.Jwt.AccessTokenExp = 60
+	c.Oauth.AuthorizationCodeExp = 60
+
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, client), NewPasswordService(NewLoggerMock()))
+	consents := newConsentRepositoryForTesting(t)
+	service := NewOAuthService(c, NewLoggerMock(), nil, repo, ts, clients, NewInMemoryAuthorizationCodeStore(), nil, consents)
+
+	verifier := "dBjftJeZ4CVP-mJ92K9bvbHoHxrQVLtQA2bQ2VA9U8zOZ-dnc8fUXq7OVnw0lsf8"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	authorizeRequest := func() *app.AuthorizeRequest {
+		return &app.AuthorizeRequest{
+			ResponseType:        "code",
+			ClientId:            "client",
+			RedirectUri:         "https://app.example.com/callback",
+			State:               "xyz",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: "S256",
+		}
+	}
+
+	authorize := func(t *testing.T) string {
+		res, err := service.Authorize(ctx, "uid", authorizeRequest())
+		if err != nil {
+			t.Fatalf("OAuthService.Authorize() error = %v", err)
+		}
+
+		u, err := url.Parse(res.RedirectUri)
+		if err != nil {
+			t.Fatalf("OAuthService.Authorize() redirect uri = %v", res.RedirectUri)
+		}
+
+		if u.Query().Get("state") != "xyz" {
+			t.Errorf("OAuthService.Authorize() state = %v, want xyz", u.Query().Get("state"))
+		}
+
+		return u.Query().Get("code")
+	}
+
+	authorizeTests := []struct {
+		name    string
+		modify  func(r *app.AuthorizeRequest)
+		wantErr string
+	}{
+		{
+			name:    "should fail when client is unknown",
+			modify:  func(r *app.AuthorizeRequest) { r.ClientId = "unknown" },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when redirect uri is not registered",
+			modify:  func(r *app.AuthorizeRequest) { r.RedirectUri = "https://evil.example.com/callback" },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when response type is not supported",
+			modify:  func(r *app.AuthorizeRequest) { r.ResponseType = "token" },
+			wantErr: app.OAuthErrUnsupportedResponse,
+		},
+		{
+			name:    "should fail when code challenge is missing",
+			modify:  func(r *app.AuthorizeRequest) { r.CodeChallenge = "" },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when code challenge method is plain",
+			modify:  func(r *app.AuthorizeRequest) { r.CodeChallengeMethod = "plain" },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when scope is not allowed for the client",
+			modify:  func(r *app.AuthorizeRequest) { r.Scope = "admin" },
+			wantErr: app.OAuthErrInvalidScope,
+		},
+		{
+			name:    "should fail when scope is invalid",
+			modify:  func(r *app.AuthorizeRequest) { r.Scope = "unknown" },
+			wantErr: app.OAuthErrInvalidScope,
+		},
+	}
+	for _, tt := range authorizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := authorizeRequest()
+			tt.modify(r)
+
+			_, err := service.Authorize(ctx, "uid", r)
+
+			var oauthErr *app.OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+				t.Errorf("OAuthService.Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	consentTests := []struct {
+		name         string
+		uid          string
+		scope        string
+		approve      bool
+		wantRequired bool
+	}{
+		{
+			name:         "should require consent when the user did not authorize the client",
+			uid:          "uid",
+			scope:        "rides:read",
+			approve:      true,
+			wantRequired: true,
+		},
+		{
+			name:  "should skip consent when the scopes are already granted",
+			uid:   "uid",
+			scope: "rides:read",
+		},
+		{
+			name:         "should require consent when new scopes are requested",
+			uid:          "uid",
+			scope:        "rides:read rides:write",
+			wantRequired: true,
+		},
+	}
+	for _, tt := range consentTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := authorizeRequest()
+			r.Scope = tt.scope
+
+			got, err := service.Consent(ctx, tt.uid, r)
+			if err != nil {
+				t.Fatalf("OAuthService.Consent() error = %v", err)
+			}
+
+			if got.Required != tt.wantRequired || got.ClientId != "client" || !reflect.DeepEqual(got.Scopes, app.ParseScope(tt.scope)) {
+				t.Errorf("OAuthService.Consent() = %+v, want required %v", got, tt.wantRequired)
+			}
+
+			if tt.approve {
+				if _, err := service.Authorize(ctx, tt.uid, r); err != nil {
+					t.Fatalf("OAuthService.Authorize() error = %v", err)
+				}
+			}
+		})
+	}
+
+	tokenRequest := func(code string) *app.TokenRequest {
+		return &app.TokenRequest{
+			GrantType:    "authorization_code",
+			Code:         code,
+			RedirectUri:  "https://app.example.com/callback",
+			ClientId:     "client",
+			CodeVerifier: verifier,
+		}
+	}
+
+	t.Run("should exchange code for tokens", func(t *testing.T) {
+		got, err := service.Token(ctx, tokenRequest(authorize(t)))
+		if err != nil {
+			t.Fatalf("OAuthService.Token() error = %v", err)
+		}
+
+		want := &app.TokenResponse{AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "refresh_token", Scope: "rides:read"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("OAuthService.Token() = %v, want %v", got, want)
+		}
+	})
+
+	tokenTests := []struct {
+		name    string
+		modify  func(r *app.TokenRequest)
+		wantErr string
+	}{
+		{
+			name:    "should fail when code verifier is missing",
+			modify:  func(r *app.TokenRequest) { r.CodeVerifier = "" },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when code verifier does not match",
+			modify:  func(r *app.TokenRequest) { r.CodeVerifier = "wrong" },
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+		{
+			name:    "should fail when redirect uri does not match",
+			modify:  func(r *app.TokenRequest) { r.RedirectUri = "https://app.example.com/other" },
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+		{
+			name:    "should fail when client is unknown",
+			modify:  func(r *app.TokenRequest) { r.ClientId = "other" },
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when public client sends a secret",
+			modify:  func(r *app.TokenRequest) { r.ClientSecret = "secret" },
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when code is unknown",
+			modify:  func(r *app.TokenRequest) { r.Code = "unknown" },
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+	}
+	for _, tt := range tokenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tokenRequest(authorize(t))
+			tt.modify(r)
+
+			_, err := service.Token(ctx, r)
+
+			var oauthErr *app.OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+				t.Errorf("OAuthService.Token() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("should issue an id token for the openid scope", func(t *testing.T) {
+		r := authorizeRequest()
+		r.Scope = "openid rides:read"
+		r.Nonce = "n-0S6_WzA2Mj"
+
+		res, err := service.Authorize(ctx, "uid", r)
+		if err != nil {
+			t.Fatalf("OAuthService.Authorize() error = %v", err)
+		}
+
+		u, _ := url.Parse(res.RedirectUri)
+
+		got, err := service.Token(ctx, tokenRequest(u.Query().Get("code")))
+		if err != nil {
+			t.Fatalf("OAuthService.Token() error = %v", err)
+		}
+
+		if got.IdToken != "id_token:client:n-0S6_WzA2Mj" {
+			t.Errorf("OAuthService.Token() id token = %v, want id_token:client:n-0S6_WzA2Mj", got.IdToken)
+		}
+	})
+
+	t.Run("should fail when code is reused", func(t *testing.T) {
+		code := authorize(t)
+
+		if _, err := service.Token(ctx, tokenRequest(code)); err != nil {
+			t.Fatalf("OAuthService.Token() error = %v", err)
+		}
+
+		_, err := service.Token(ctx, tokenRequest(code))
+
+		var oauthErr *app.OAuthError
+		if !errors.As(err, &oauthErr) || oauthErr.Err != app.OAuthErrInvalidGrant {
+			t.Errorf("OAuthService.Token() error = %v, want %v", err, app.OAuthErrInvalidGrant)
+		}
+	})
+
+	t.Run("should fail when code is expired", func(t *testing.T) {
+		c.Oauth.AuthorizationCodeExp = -1
+		defer func() { c.Oauth.AuthorizationCodeExp = 60 }()
+
+		_, err := service.Token(ctx, tokenRequest(authorize(t)))
+
+		var oauthErr *app.OAuthError
+		if !errors.As(err, &oauthErr) || oauthErr.Err != app.OAuthErrInvalidGrant {
+			t.Errorf("OAuthService.Token() error = %v, want %v", err, app.OAuthErrInvalidGrant)
+		}
+	})
+}
+
+func TestOAuthService_ClientCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	pws := NewPasswordService(NewLoggerMock())
+
+	secret, err := pws.Hash(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confidential := &model.OAuthClient{Id: "dispatch", Scopes: []string{"users:read"}, Secret: secret, Confidential: true}
+	public := &model.OAuthClient{Id: "rider-app", Scopes: []string{"users:read"}}
+
+	ts := mock.NewMockTokenService(ctrl)
+	ts.EXPECT().ResolveClientGrant(ctx, confidential, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.OAuthClient, g *app.TokenGrant) (*app.TokenGrant, error) {
+			if g.Scope == "" {
+				return &app.TokenGrant{Scope: "users:read"}, nil
+			}
+			if g.Scope != "users:read" {
+				return nil, app.ErrInvalidScope
+			}
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateClientAccessToken(ctx, confidential, gomock.Any()).Return("client_access_token", nil).AnyTimes()
+
+	c := config.New()
+	c.Jwt.AccessTokenExp = 60
+
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, confidential, public), pws)
+	service := NewOAuthService(c, NewLoggerMock(), nil, nil, ts, clients, nil, nil, nil)
+
+	tests := []struct {
+		name    string
+		req     *app.TokenRequest
+		want    *app.TokenResponse
+		wantErr string
+	}{
+		{
+			name: "should issue an access token to the client",
+			req:  &app.TokenRequest{GrantType: "client_credentials", ClientId: "dispatch", ClientSecret: "secret"},
+			want: &app.TokenResponse{AccessToken: "client_access_token", TokenType: "Bearer", ExpiresIn: 60, Scope: "users:read"},
+		},
+		{
+			name:    "should fail when secret is wrong",
+			req:     &app.TokenRequest{GrantType: "client_credentials", ClientId: "dispatch", ClientSecret: "wrong"},
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when secret is missing",
+			req:     &app.TokenRequest{GrantType: "client_credentials", ClientId: "dispatch"},
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when client is unknown",
+			req:     &app.TokenRequest{GrantType: "client_credentials", ClientId: "unknown", ClientSecret: "secret"},
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when client is public",
+			req:     &app.TokenRequest{GrantType: "client_credentials", ClientId: "rider-app"},
+			wantErr: app.OAuthErrUnauthorizedClient,
+		},
+		{
+			name:    "should fail when scope is not allowed",
+			req:     &app.TokenRequest{GrantType: "client_credentials", ClientId: "dispatch", ClientSecret: "secret", Scope: "rides:write"},
+			wantErr: app.OAuthErrInvalidScope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Token(ctx, tt.req)
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.Token() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.Token() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OAuthService.Token() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthService_PasswordGrantOfClient(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	pws := NewPasswordService(NewLoggerMock())
+
+	password, err := pws.Hash(ctx, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", Password: password, Role: model.RoleUser}
+	partner := &model.OAuthClient{Id: "partner", Scopes: []string{"rides:read"}}
+
+	repo := newRepositoryForTesting(t, user)
+	repo.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Return(user, nil).AnyTimes()
+
+	c := config.New()
+	c.Jwt.Scopes = []string{"rides:read"}
+
+	rts := NewInMemoryRefreshTokenStore()
+	ts := NewTokenService(c, NewLoggerMock(), repo, rts, NewInMemoryTokenDenylist())
+	auth := NewAuthService(c, NewLoggerMock(), repo, ts, pws, nil, nil)
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, partner), pws)
+	service := NewOAuthService(c, NewLoggerMock(), auth, repo, ts, clients, nil, nil, nil)
+
+	res, err := service.Token(ctx, &app.TokenRequest{GrantType: "password", Username: user.Email, Password: "password", ClientId: "partner"})
+	if err != nil {
+		t.Fatalf("OAuthService.Token() error = %v", err)
+	}
+
+	claims, err := ts.ParseToken(ctx, res.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.GetClientId() != "partner" || app.IsFirstPartyToken(claims) {
+		t.Errorf("OAuthService.Token() access token client = %q, want partner", claims.GetClientId())
+	}
+
+	if _, err := auth.ChangePassword(ctx, claims, &app.ChangePasswordRequest{CurrentPassword: "password", NewPassword: "new-password"}); !errors.Is(err, app.ErrUserTokenRequired) {
+		t.Errorf("AuthService.ChangePassword() error = %v, want %v", err, app.ErrUserTokenRequired)
+	}
+
+	grant, err := ts.RefreshTokenGrant(ctx, res.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if grant.ClientId != "partner" {
+		t.Errorf("OAuthService.Token() refresh token client = %q, want partner", grant.ClientId)
+	}
+}
+
+func TestOAuthService_RefreshTokenOfClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	pws := NewPasswordService(NewLoggerMock())
+
+	secret, err := pws.Hash(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confidential := &model.OAuthClient{Id: "dispatch", Scopes: []string{"rides:read"}, Secret: secret, Confidential: true}
+
+	auth := mock.NewMockAuthService(ctrl)
+	auth.EXPECT().RefreshToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *app.RefreshTokenRequest) (*app.RefreshTokenResponse, error) {
+			if r.ClientId != "dispatch" {
+				return nil, app.ErrInvalidToken
+			}
+
+			return &app.RefreshTokenResponse{AccessToken: "access_token", AccessTokenExpiresIn: 60, RefreshToken: "rotated_refresh_token", UserId: "uid", ClientId: r.ClientId}, nil
+		}).AnyTimes()
+
+	c := config.New()
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, confidential), pws)
+	service := NewOAuthService(c, NewLoggerMock(), auth, nil, nil, clients, nil, nil, newConsentRepositoryForTesting(t))
+
+	tests := []struct {
+		name    string
+		req     *app.TokenRequest
+		want    *app.TokenResponse
+		wantErr string
+	}{
+		{
+			name: "should refresh the token of the authenticated client",
+			req:  &app.TokenRequest{GrantType: "refresh_token", RefreshToken: "refresh_token", ClientId: "dispatch", ClientSecret: "secret"},
+			want: &app.TokenResponse{AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "rotated_refresh_token"},
+		},
+		{
+			name:    "should fail when secret is wrong",
+			req:     &app.TokenRequest{GrantType: "refresh_token", RefreshToken: "refresh_token", ClientId: "dispatch", ClientSecret: "wrong"},
+			wantErr: app.OAuthErrInvalidClient,
+		},
+		{
+			name:    "should fail when client is not sent",
+			req:     &app.TokenRequest{GrantType: "refresh_token", RefreshToken: "refresh_token"},
+			wantErr: app.OAuthErrInvalidGrant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Token(ctx, tt.req)
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.Token() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.Token() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OAuthService.Token() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthService_DeviceCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := &model.User{Role: model.RoleUser}
+
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetUser(ctx, "uid").Return(user, nil).AnyTimes()
+
+	ts := mock.NewMockTokenService(ctrl)
+	ts.EXPECT().ResolveGrant(ctx, user, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			if g.Scope == "" {
+				return &app.TokenGrant{Audience: g.Audience, Scope: "rides:read"}, nil
+			}
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, user, gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, user, gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	tablet := &model.OAuthClient{Id: "tablet", Name: "In-Car Tablet", Scopes: []string{"rides:read"}}
+	meter := &model.OAuthClient{Id: "meter", Name: "Taximeter", Scopes: []string{"rides:read"}}
+
+	c := config.New()
+	c.Jwt.AccessTokenExp = 60
+	c.Oauth.VerificationUrl = "https://hey-taxi.app/device"
+	c.Oauth.DeviceCodeExp = 600
+	c.Oauth.DeviceCodeInterval = 5
+
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, tablet, meter), NewPasswordService(NewLoggerMock()))
+	consents := newConsentRepositoryForTesting(t)
+	service := NewOAuthService(c, NewLoggerMock(), nil, repo, ts, clients, nil, NewInMemoryDeviceAuthorizationStore(), consents)
+
+	start := func(t *testing.T) *app.DeviceAuthorizationResponse {
+		res, err := service.DeviceAuthorization(ctx, &app.DeviceAuthorizationRequest{ClientId: "tablet"})
+		if err != nil {
+			t.Fatalf("OAuthService.DeviceAuthorization() error = %v", err)
+		}
+
+		return res
+	}
+
+	poll := func(deviceCode string, clientId string) (*app.TokenResponse, error) {
+		return service.Token(ctx, &app.TokenRequest{GrantType: app.GrantTypeDeviceCode, DeviceCode: deviceCode, ClientId: clientId})
+	}
+
+	wantOAuthErr := func(t *testing.T, err error, want string) {
+		t.Helper()
+
+		var oauthErr *app.OAuthError
+		if !errors.As(err, &oauthErr) || oauthErr.Err != want {
+			t.Errorf("error = %v, want %v", err, want)
+		}
+	}
+
+	t.Run("should issue tokens once the user approves", func(t *testing.T) {
+		res := start(t)
+
+		if res.VerificationUri != c.Oauth.VerificationUrl || res.Interval != 5 || res.ExpiresIn != 600 {
+			t.Errorf("OAuthService.DeviceAuthorization() = %+v", res)
+		}
+
+		if want := c.Oauth.VerificationUrl + "?user_code=" + res.UserCode; res.VerificationUriComplete != want {
+			t.Errorf("OAuthService.DeviceAuthorization() verification_uri_complete = %v, want %v", res.VerificationUriComplete, want)
+		}
+
+		_, err := poll(res.DeviceCode, "tablet")
+		wantOAuthErr(t, err, app.OAuthErrAuthorizationPending)
+
+		_, err = poll(res.DeviceCode, "tablet")
+		wantOAuthErr(t, err, app.OAuthErrSlowDown)
+
+		// the user types the code without the separator in lower case
+		typed := strings.ToLower(strings.ReplaceAll(res.UserCode, "-", ""))
+
+		info, err := service.GetDeviceAuthorization(ctx, typed)
+		if err != nil {
+			t.Fatalf("OAuthService.GetDeviceAuthorization() error = %v", err)
+		}
+
+		if info.ClientName != "In-Car Tablet" || info.UserCode != res.UserCode {
+			t.Errorf("OAuthService.GetDeviceAuthorization() = %+v", info)
+		}
+
+		if err := service.DecideDeviceAuthorization(ctx, "uid", &app.DeviceDecisionRequest{UserCode: typed, Approve: true}); err != nil {
+			t.Fatalf("OAuthService.DecideDeviceAuthorization() error = %v", err)
+		}
+
+		if consent, err := consents.GetConsent(ctx, "uid", "tablet"); err != nil || !consent.HasScopes("rides:read") {
+			t.Errorf("ConsentRepository.GetConsent() = %v, %v", consent, err)
+		}
+
+		got, err := poll(res.DeviceCode, "tablet")
+		if err != nil {
+			t.Fatalf("OAuthService.Token() error = %v", err)
+		}
+
+		want := &app.TokenResponse{AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "refresh_token", Scope: "rides:read"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("OAuthService.Token() = %v, want %v", got, want)
+		}
+
+		_, err = poll(res.DeviceCode, "tablet")
+		wantOAuthErr(t, err, app.OAuthErrInvalidGrant)
+	})
+
+	t.Run("should fail when the user denies", func(t *testing.T) {
+		res := start(t)
+
+		if err := service.DecideDeviceAuthorization(ctx, "uid", &app.DeviceDecisionRequest{UserCode: res.UserCode}); err != nil {
+			t.Fatalf("OAuthService.DecideDeviceAuthorization() error = %v", err)
+		}
+
+		_, err := poll(res.DeviceCode, "tablet")
+		wantOAuthErr(t, err, app.OAuthErrAccessDenied)
+
+		err = service.DecideDeviceAuthorization(ctx, "uid", &app.DeviceDecisionRequest{UserCode: res.UserCode, Approve: true})
+		wantOAuthErr(t, err, app.OAuthErrInvalidRequest)
+	})
+
+	t.Run("should fail when the request is expired", func(t *testing.T) {
+		c.Oauth.DeviceCodeExp = -1
+		defer func() { c.Oauth.DeviceCodeExp = 600 }()
+
+		res := start(t)
+
+		_, err := poll(res.DeviceCode, "tablet")
+		wantOAuthErr(t, err, app.OAuthErrExpiredToken)
+
+		_, err = service.GetDeviceAuthorization(ctx, res.UserCode)
+		wantOAuthErr(t, err, app.OAuthErrInvalidRequest)
+	})
+
+	t.Run("should fail when another client polls", func(t *testing.T) {
+		res := start(t)
+
+		_, err := poll(res.DeviceCode, "meter")
+		wantOAuthErr(t, err, app.OAuthErrInvalidGrant)
+	})
+
+	t.Run("should fail when scope is not allowed for the client", func(t *testing.T) {
+		_, err := service.DeviceAuthorization(ctx, &app.DeviceAuthorizationRequest{ClientId: "tablet", Scope: "rides:write"})
+		wantOAuthErr(t, err, app.OAuthErrInvalidScope)
+	})
+
+	t.Run("should fail when user code is unknown", func(t *testing.T) {
+		err := service.DecideDeviceAuthorization(ctx, "uid", &app.DeviceDecisionRequest{UserCode: "BCDF-GHJK", Approve: true})
+		wantOAuthErr(t, err, app.OAuthErrInvalidRequest)
+	})
+}
+
+func TestOAuthService_DeviceAuthorization_UserCodeCollision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	tablet := &model.OAuthClient{Id: "tablet", Scopes: []string{"rides:read"}}
+
+	c := config.New()
+	c.Oauth.VerificationUrl = "https://hey-taxi.app/device"
+
+	clients := NewClientService(c, NewLoggerMock(), newClientRepositoryForTesting(t, tablet), NewPasswordService(NewLoggerMock()))
+
+	tests := []struct {
+		name       string
+		collisions int
+		wantErr    string
+	}{
+		{
+			name:       "should generate the user code again when it is taken",
+			collisions: maxUserCodeAttempts - 1,
+		},
+		{
+			name:       "should fail when no free user code is found",
+			collisions: maxUserCodeAttempts,
+			wantErr:    app.OAuthErrServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saves := 0
+
+			devices := mock.NewMockDeviceAuthorizationStore(ctrl)
+			devices.EXPECT().Save(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ *model.DeviceAuthorization) error {
+					saves++
+					if saves <= tt.collisions {
+						return app.ErrUserCodeExists
+					}
+					return nil
+				}).MaxTimes(maxUserCodeAttempts)
+
+			service := NewOAuthService(c, NewLoggerMock(), nil, nil, nil, clients, nil, devices, nil)
+
+			got, err := service.DeviceAuthorization(ctx, &app.DeviceAuthorizationRequest{ClientId: "tablet"})
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.DeviceAuthorization() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.DeviceAuthorization() error = %v", err)
+			}
+
+			if got.UserCode == "" {
+				t.Errorf("OAuthService.DeviceAuthorization() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestOAuthService_TokenExchange(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+
+	rider := &model.User{Id: primitive.NewObjectID()}
+	agent := &model.User{Id: primitive.NewObjectID(), Role: model.RoleAdmin}
+	driver := &model.User{Id: primitive.NewObjectID()}
+
+	c := config.New()
+	c.Jwt.Audiences = []string{"rider-app", "rides-api"}
+	c.Jwt.Scopes = []string{"rides:read", "rides:write"}
+	c.Oauth.TokenExchangeClients = []string{"dispatch"}
+
+	repo := newRepositoryForTesting(t, rider, agent, driver)
+	ts := NewTokenService(c, NewLoggerMock(), repo, NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+	service := NewOAuthService(c, NewLoggerMock(), nil, repo, ts, nil, nil, nil, nil)
+
+	token := func(user *model.User, grant *app.TokenGrant) string {
+		tkn, err := ts.GenerateAccessToken(ctx, user, grant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tkn
+	}
+
+	clientToken := func(id string) string {
+		tkn, err := ts.GenerateClientAccessToken(ctx, &model.OAuthClient{Id: id, Scopes: []string{"rides:read"}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tkn
+	}
+
+	riderToken := token(rider, &app.TokenGrant{Audience: "rider-app", Scope: "rides:read rides:write"})
+
+	delegated, err := service.Token(ctx, &app.TokenRequest{
+		GrantType:        app.GrantTypeTokenExchange,
+		SubjectToken:     riderToken,
+		SubjectTokenType: app.TokenTypeUrnAccessToken,
+		ActorToken:       clientToken("dispatch"),
+		ActorTokenType:   app.TokenTypeUrnAccessToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exchange := func(subject string, actor string) *app.TokenRequest {
+		return &app.TokenRequest{
+			GrantType:        app.GrantTypeTokenExchange,
+			SubjectToken:     subject,
+			SubjectTokenType: app.TokenTypeUrnAccessToken,
+			ActorToken:       actor,
+			ActorTokenType:   app.TokenTypeUrnAccessToken,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		req          func() *app.TokenRequest
+		wantAudience string
+		wantScope    string
+		wantActor    *app.Actor
+		wantErr      string
+	}{
+		{
+			name:         "should exchange the token for an allowed role",
+			req:          func() *app.TokenRequest { return exchange(riderToken, token(agent, nil)) },
+			wantAudience: "rider-app",
+			wantScope:    "rides:read rides:write",
+			wantActor:    &app.Actor{Subject: agent.GetIdString()},
+		},
+		{
+			name: "should narrow the scope",
+			req: func() *app.TokenRequest {
+				r := exchange(riderToken, clientToken("dispatch"))
+				r.Scope = "rides:read"
+				r.Audience = "rider-app"
+				r.RequestedTokenType = app.TokenTypeUrnAccessToken
+				return r
+			},
+			wantAudience: "rider-app",
+			wantScope:    "rides:read",
+			wantActor:    &app.Actor{Subject: "dispatch"},
+		},
+		{
+			name:         "should nest the actor of a delegated subject token",
+			req:          func() *app.TokenRequest { return exchange(delegated.AccessToken, token(agent, nil)) },
+			wantAudience: "rider-app",
+			wantScope:    "rides:read rides:write",
+			wantActor:    &app.Actor{Subject: agent.GetIdString(), Actor: &app.Actor{Subject: "dispatch"}},
+		},
+		{
+			name: "should fail to widen the scope",
+			req: func() *app.TokenRequest {
+				r := exchange(token(rider, &app.TokenGrant{Scope: "rides:read"}), token(agent, nil))
+				r.Scope = "rides:read rides:write"
+				return r
+			},
+			wantErr: app.OAuthErrInvalidScope,
+		},
+		{
+			name: "should fail to change the audience",
+			req: func() *app.TokenRequest {
+				r := exchange(riderToken, clientToken("dispatch"))
+				r.Audience = "rides-api"
+				return r
+			},
+			wantErr: app.OAuthErrInvalidTarget,
+		},
+		{
+			name:    "should fail when the role is not allowed",
+			req:     func() *app.TokenRequest { return exchange(riderToken, token(driver, nil)) },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name: "should fail when the token of the allowed role is issued to a client",
+			req: func() *app.TokenRequest {
+				return exchange(riderToken, token(agent, &app.TokenGrant{ClientId: "partner"}))
+			},
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when the client is not allowed",
+			req:     func() *app.TokenRequest { return exchange(riderToken, clientToken("billing")) },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when the actor token is delegated",
+			req:     func() *app.TokenRequest { return exchange(token(driver, nil), delegated.AccessToken) },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when the subject token is a client token",
+			req:     func() *app.TokenRequest { return exchange(clientToken("dispatch"), token(agent, nil)) },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail when the subject token is invalid",
+			req:     func() *app.TokenRequest { return exchange("invalid", token(agent, nil)) },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name:    "should fail without the actor token",
+			req:     func() *app.TokenRequest { return exchange(riderToken, "") },
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name: "should fail when the token type is not supported",
+			req: func() *app.TokenRequest {
+				r := exchange(riderToken, token(agent, nil))
+				r.SubjectTokenType = "urn:ietf:params:oauth:token-type:refresh_token"
+				return r
+			},
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+		{
+			name: "should fail when the requested token type is not supported",
+			req: func() *app.TokenRequest {
+				r := exchange(riderToken, token(agent, nil))
+				r.RequestedTokenType = "urn:ietf:params:oauth:token-type:id_token"
+				return r
+			},
+			wantErr: app.OAuthErrInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Token(ctx, tt.req())
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.Token() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.Token() error = %v", err)
+			}
+
+			if got.IssuedTokenType != app.TokenTypeUrnAccessToken || got.RefreshToken != "" || got.Scope != tt.wantScope {
+				t.Errorf("OAuthService.Token() = %+v", got)
+			}
+
+			claims, err := ts.ParseToken(ctx, got.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.GetSubject() != rider.GetIdString() {
+				t.Errorf("OAuthService.Token() sub = %v, want %v", claims.GetSubject(), rider.GetIdString())
+			}
+
+			if claims.GetAudience() != tt.wantAudience || claims.GetScope() != tt.wantScope {
+				t.Errorf("OAuthService.Token() aud = %v, scope = %v, want %v, %v", claims.GetAudience(), claims.GetScope(), tt.wantAudience, tt.wantScope)
+			}
+
+			if !reflect.DeepEqual(claims.GetActor(), tt.wantActor) {
+				t.Errorf("OAuthService.Token() act = %+v, want %+v", claims.GetActor(), tt.wantActor)
+			}
+		})
+	}
+}
+
+func TestOAuthService_UserInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	auth := mock.NewMockAuthService(ctrl)
+	auth.EXPECT().Me(ctx, "uid").Return(&app.UserResponse{
+		Id:            "uid",
+		FirstName:     "Jane",
+		LastName:      "Doe",
+		Email:         "jane@doe.com",
+		Avatar:        "https://cdn.example.com/jane.png",
+		EmailVerified: true,
+	}, nil).AnyTimes()
+
+	service := NewOAuthService(config.New(), NewLoggerMock(), auth, nil, nil, nil, nil, nil, nil)
+	verified := true
+
+	tests := []struct {
+		name    string
+		claims  *Claims
+		want    *app.UserInfoResponse
+		wantErr string
+	}{
+		{
+			name:   "should only return the subject for the openid scope",
+			claims: &Claims{Scope: "openid", StandardClaims: jwt.StandardClaims{Subject: "uid"}},
+			want:   &app.UserInfoResponse{Sub: "uid"},
+		},
+		{
+			name:   "should return the profile claims for the profile scope",
+			claims: &Claims{Scope: "openid profile", StandardClaims: jwt.StandardClaims{Subject: "uid"}},
+			want:   &app.UserInfoResponse{Sub: "uid", Name: "Jane Doe", GivenName: "Jane", FamilyName: "Doe", Picture: "https://cdn.example.com/jane.png"},
+		},
+		{
+			name:   "should return the email claims for the email scope",
+			claims: &Claims{Scope: "openid email", StandardClaims: jwt.StandardClaims{Subject: "uid"}},
+			want:   &app.UserInfoResponse{Sub: "uid", Email: "jane@doe.com", EmailVerified: &verified},
+		},
+		{
+			name:    "should fail without the openid scope",
+			claims:  &Claims{Scope: "profile", StandardClaims: jwt.StandardClaims{Subject: "uid"}},
+			wantErr: app.OAuthErrInsufficientScope,
+		},
+		{
+			name:    "should fail for client tokens",
+			claims:  &Claims{Scope: "openid", TokenType: app.AccessTokenTypeClient, StandardClaims: jwt.StandardClaims{Subject: "client"}},
+			wantErr: app.OAuthErrInsufficientScope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.UserInfo(ctx, tt.claims)
+
+			if tt.wantErr != "" {
+				var oauthErr *app.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Err != tt.wantErr {
+					t.Errorf("OAuthService.UserInfo() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OAuthService.UserInfo() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OAuthService.UserInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newClientRepositoryForTesting returns a client repository which knows the
+// given clients
+func newClientRepositoryForTesting(t *testing.T, clients ...*model.OAuthClient) *mock.MockClientRepository {
+	repo := mock.NewMockClientRepository(gomock.NewController(t))
+
+	repo.EXPECT().GetClient(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.OAuthClient, error) {
+			for _, c := range clients {
+				if c.Id == id {
+					return c, nil
+				}
+			}
+
+			return nil, app.ErrClientNotFound
+		}).AnyTimes()
+
+	return repo
+}
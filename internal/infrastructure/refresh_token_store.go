@@ -0,0 +1,240 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRefreshTokenStore struct {
+	app.RefreshTokenStore
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoRefreshTokenStore(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoRefreshTokenStore {
+	return &MongoRefreshTokenStore{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.RefreshTokenCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the store. Expired tokens are
+// removed by mongo through the ttl index on expires_at.
+func (s *MongoRefreshTokenStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		s.logger.Warnf("error while creating refresh token indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Save stores a refresh token
+func (s *MongoRefreshTokenStore) Save(ctx context.Context, token *model.RefreshToken) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.InsertOne(ctx, token); err != nil {
+		s.logger.Warnf("error while saving refresh token: %s", err)
+		return app.NewInternalServerError(errors.New("error while saving refresh token"))
+	}
+
+	return nil
+}
+
+// Get returns a refresh token by id
+func (s *MongoRefreshTokenStore) Get(ctx context.Context, id string) (*model.RefreshToken, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	t := &model.RefreshToken{}
+	if err := s.db.FindOne(ctx, bson.M{"_id": id}).Decode(t); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrRefreshTokenNotFound
+		}
+
+		s.logger.Warnf("error while finding refresh token: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding refresh token"))
+	}
+
+	return t, nil
+}
+
+// Rotate marks the token as replaced by another one. The update is
+// conditional so that only one of concurrent rotations can succeed.
+func (s *MongoRefreshTokenStore) Rotate(ctx context.Context, id string, replacedBy string) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":         id,
+		"revoked":     false,
+		"replaced_by": bson.M{"$exists": false},
+	}
+
+	result, err := s.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"replaced_by": replacedBy}})
+	if err != nil {
+		s.logger.Warnf("error while rotating refresh token: %s", err)
+		return app.NewInternalServerError(errors.New("error while rotating refresh token"))
+	}
+
+	if result.MatchedCount == 0 {
+		if _, err := s.Get(ctx, id); err != nil {
+			return err
+		}
+
+		return app.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes all refresh tokens descending from the same login
+func (s *MongoRefreshTokenStore) RevokeFamily(ctx context.Context, familyId string) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.UpdateMany(ctx, bson.M{"family_id": familyId}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		s.logger.Warnf("error while revoking refresh token family: %s", err)
+		return app.NewInternalServerError(errors.New("error while revoking refresh tokens"))
+	}
+
+	return nil
+}
+
+// RevokeClient revokes the refresh tokens of the user issued to the client
+func (s *MongoRefreshTokenStore) RevokeClient(ctx context.Context, userId string, clientId string) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "client_id": clientId}
+	if _, err := s.db.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}}); err != nil {
+		s.logger.Warnf("error while revoking refresh tokens of client: %s", err)
+		return app.NewInternalServerError(errors.New("error while revoking refresh tokens"))
+	}
+
+	return nil
+}
+
+func (s *MongoRefreshTokenStore) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryRefreshTokenStore struct {
+	app.RefreshTokenStore
+	mu     sync.RWMutex
+	tokens map[string]*model.RefreshToken
+}
+
+func NewInMemoryRefreshTokenStore() *InMemoryRefreshTokenStore {
+	return &InMemoryRefreshTokenStore{
+		tokens: make(map[string]*model.RefreshToken),
+	}
+}
+
+// Save stores a refresh token and drops the expired ones
+func (s *InMemoryRefreshTokenStore) Save(ctx context.Context, token *model.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.IsExpired() {
+			delete(s.tokens, id)
+		}
+	}
+
+	t := *token
+	s.tokens[token.Id] = &t
+
+	return nil
+}
+
+// Get returns a refresh token by id
+func (s *InMemoryRefreshTokenStore) Get(ctx context.Context, id string) (*model.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[id]
+	if !ok || t.IsExpired() {
+		return nil, app.ErrRefreshTokenNotFound
+	}
+
+	c := *t
+	return &c, nil
+}
+
+// Rotate marks the token as replaced by another one
+func (s *InMemoryRefreshTokenStore) Rotate(ctx context.Context, id string, replacedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[id]
+	if !ok || t.IsExpired() {
+		return app.ErrRefreshTokenNotFound
+	}
+
+	if t.Revoked || t.IsRotated() {
+		return app.ErrRefreshTokenReused
+	}
+
+	t.ReplacedBy = replacedBy
+
+	return nil
+}
+
+// RevokeFamily revokes all refresh tokens descending from the same login
+func (s *InMemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.FamilyId == familyId {
+			t.Revoked = true
+		}
+	}
+
+	return nil
+}
+
+// RevokeClient revokes the refresh tokens of the user issued to the client
+func (s *InMemoryRefreshTokenStore) RevokeClient(ctx context.Context, userId string, clientId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.UserId == userId && t.ClientId == clientId {
+			t.Revoked = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/pkg/errors"
+)
+
+func TestInMemoryRefreshTokenStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryRefreshTokenStore()
+
+	s.Save(ctx, &model.RefreshToken{Id: "1", UserId: "u1", FamilyId: "f1", ExpiresAt: time.Now().Add(time.Hour)})
+	s.Save(ctx, &model.RefreshToken{Id: "2", UserId: "u1", FamilyId: "f1", ExpiresAt: time.Now().Add(time.Hour)})
+	s.Save(ctx, &model.RefreshToken{Id: "3", UserId: "u1", FamilyId: "f2", ExpiresAt: time.Now().Add(time.Hour)})
+	s.Save(ctx, &model.RefreshToken{Id: "4", UserId: "u1", FamilyId: "f3", ExpiresAt: time.Now().Add(-time.Hour)})
+	s.Save(ctx, &model.RefreshToken{Id: "5", UserId: "u1", FamilyId: "f4", ClientId: "c1", ExpiresAt: time.Now().Add(time.Hour)})
+	s.Save(ctx, &model.RefreshToken{Id: "6", UserId: "u2", FamilyId: "f5", ClientId: "c1", ExpiresAt: time.Now().Add(time.Hour)})
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{
+			name: "should return stored token",
+			run: func() error {
+				_, err := s.Get(ctx, "1")
+				return err
+			},
+		},
+		{
+			name: "should not return expired token",
+			run: func() error {
+				_, err := s.Get(ctx, "4")
+				return err
+			},
+			wantErr: app.ErrRefreshTokenNotFound,
+		},
+		{
+			name: "should rotate token",
+			run: func() error {
+				return s.Rotate(ctx, "1", "2")
+			},
+		},
+		{
+			name: "should not rotate token twice",
+			run: func() error {
+				return s.Rotate(ctx, "1", "5")
+			},
+			wantErr: app.ErrRefreshTokenReused,
+		},
+		{
+			name: "should revoke only the given family",
+			run: func() error {
+				if err := s.RevokeFamily(ctx, "f1"); err != nil {
+					return err
+				}
+
+				if t, _ := s.Get(ctx, "2"); !t.Revoked {
+					return errors.New("token 2 is not revoked")
+				}
+
+				if t, _ := s.Get(ctx, "3"); t.Revoked {
+					return errors.New("token 3 is revoked")
+				}
+
+				return nil
+			},
+		},
+		{
+			name: "should revoke only the tokens of the client issued for the user",
+			run: func() error {
+				if err := s.RevokeClient(ctx, "u1", "c1"); err != nil {
+					return err
+				}
+
+				if t, _ := s.Get(ctx, "5"); !t.Revoked {
+					return errors.New("token 5 is not revoked")
+				}
+
+				if t, _ := s.Get(ctx, "6"); t.Revoked {
+					return errors.New("token 6 is revoked")
+				}
+
+				if t, _ := s.Get(ctx, "3"); t.Revoked {
+					return errors.New("token 3 is revoked")
+				}
+
+				return nil
+			},
+		},
+		{
+			name: "should not rotate revoked token",
+			run: func() error {
+				return s.Rotate(ctx, "2", "5")
+			},
+			wantErr: app.ErrRefreshTokenReused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("InMemoryRefreshTokenStore error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Repository struct {
@@ -30,6 +31,48 @@ func NewRepository(config *config.Config, logger logger.ILogger, db *mongo.Clien
 	}
 }
 
+// EnsureIndexes creates the indexes used by the repository. An external
+// identity can be linked to one user only, the users of an organization are
+// looked up by their external ids, and the users to delete by the time of
+// their deletion.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		r.logger.Warnf("error while creating user indexes: %s", err)
+		return err
+	}
+
+	_, err = r.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "organization", Value: 1}, {Key: "external_id", Value: 1}},
+		Options: options.Index().
+			SetPartialFilterExpression(bson.M{"organization": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		r.logger.Warnf("error while creating user indexes: %s", err)
+		return err
+	}
+
+	_, err = r.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "delete_at", Value: 1}},
+		Options: options.Index().
+			SetPartialFilterExpression(bson.M{"delete_at": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		r.logger.Warnf("error while creating user indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
 // GetUser returns a user by id
 func (r *Repository) GetUser(ctx context.Context, id string) (*model.User, error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
@@ -128,7 +171,46 @@ func (r *Repository) CreateUser(ctx context.Context, user *model.User) (string,
 	return res.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
-func (r *Repository) UpdateUser(ctx context.Context, id string, user *model.User) error {
+// UpdateProfile updates the fields of the profile which the user edits
+func (r *Repository) UpdateProfile(ctx context.Context, id string, user *model.User) error {
+	return r.setUser(ctx, id, nil, bson.M{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"avatar":     user.Avatar,
+		"phone":      user.Phone,
+	})
+}
+
+// UpdateDirectoryUser updates the fields which the directory of the
+// organization provisions. The user is enabled and disabled by
+// SetUserDisabled.
+func (r *Repository) UpdateDirectoryUser(ctx context.Context, id string, user *model.User) error {
+	return r.setUser(ctx, id, nil, bson.M{
+		"first_name":     user.FirstName,
+		"last_name":      user.LastName,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"external_id":    user.ExternalId,
+	})
+}
+
+// SetPassword sets the hashed password of the user
+func (r *Repository) SetPassword(ctx context.Context, id string, password string) error {
+	return r.setUser(ctx, id, nil, bson.M{"password": password})
+}
+
+// VerifyEmail marks the email of the user as verified unless the email has
+// changed in the meantime
+func (r *Repository) VerifyEmail(ctx context.Context, id string, email string) error {
+	return r.setUser(ctx, id, bson.M{"email": email}, bson.M{"email_verified": true})
+}
+
+// SetUserDisabled disables or enables the user
+func (r *Repository) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	return r.setUser(ctx, id, nil, bson.M{"disabled": disabled})
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id string) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		r.logger.Warnf("invalid id: %s", id)
@@ -138,21 +220,74 @@ func (r *Repository) UpdateUser(ctx context.Context, id string, user *model.User
 	ctx, cancel := r.contextWithTimeout(ctx)
 	defer cancel()
 
-	result, err := r.db.UpdateOne(ctx, bson.M{"_id": objectId}, user)
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": objectId})
 	if err != nil {
-		r.logger.Warnf("error while updating user: %s", err)
+		r.logger.Warnf("error while deleting user: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting user"))
+
+	}
+
+	if result.DeletedCount == 0 {
+		r.logger.Warnf("user not found to delete: %s", id)
+		return app.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// IncrementTokenGeneration invalidates all tokens issued to the user
+func (r *Repository) IncrementTokenGeneration(ctx context.Context, id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		r.logger.Warnf("invalid id: %s", id)
+		return app.ErrInvalidUserId
+	}
+
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{"token_generation": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		r.logger.Warnf("error while incrementing token generation: %s", err)
 		return app.NewInternalServerError(errors.New("error while updating user"))
 	}
 
 	if result.MatchedCount == 0 {
-		r.logger.Warnf("user not found to update: %s", id)
+		r.logger.Warnf("user not found to increment token generation: %s", id)
 		return app.ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (r *Repository) DeleteUser(ctx context.Context, id string) error {
+// GetUserByIdentity returns the user which the external identity is linked to
+func (r *Repository) GetUserByIdentity(ctx context.Context, provider string, subject string) (*model.User, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	u := &model.User{}
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+
+	if err := r.db.FindOne(ctx, filter).Decode(u); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrUserNotFound
+		}
+
+		r.logger.Warnf("error while finding user by identity: %s", err)
+		return nil, app.NewInternalServerError(err)
+	}
+
+	return u, nil
+}
+
+// LinkIdentity links the external identity to the user unless the user
+// already has an identity of the provider
+func (r *Repository) LinkIdentity(ctx context.Context, id string, identity *model.ExternalIdentity) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		r.logger.Warnf("invalid id: %s", id)
@@ -162,15 +297,216 @@ func (r *Repository) DeleteUser(ctx context.Context, id string) error {
 	ctx, cancel := r.contextWithTimeout(ctx)
 	defer cancel()
 
-	result, err := r.db.DeleteOne(ctx, bson.M{"_id": objectId})
+	filter := bson.M{"_id": objectId, "identities.provider": bson.M{"$ne": identity.Provider}}
+	update := bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.db.UpdateOne(ctx, filter, update)
 	if err != nil {
-		r.logger.Warnf("error while deleting user: %s", err)
-		return app.NewInternalServerError(errors.New("error while deleting user"))
+		if mongo.IsDuplicateKeyError(err) {
+			return app.ErrIdentityAlreadyLinked
+		}
 
+		r.logger.Warnf("error while linking identity: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating user"))
 	}
 
-	if result.DeletedCount == 0 {
-		r.logger.Warnf("user not found to delete: %s", id)
+	if result.MatchedCount == 0 {
+		r.logger.Warnf("user not found or already linked to provider %s: %s", identity.Provider, id)
+		return app.ErrIdentityAlreadyLinked
+	}
+
+	return nil
+}
+
+// FindUsers returns a page of the users of the query ordered by their ids
+func (r *Repository) FindUsers(ctx context.Context, q *app.UserQuery) ([]*model.User, int, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if q.Organization != "" {
+		filter["organization"] = q.Organization
+	}
+
+	if q.Email != "" {
+		filter["email"] = q.Email
+	}
+
+	if q.ExternalId != "" {
+		filter["external_id"] = q.ExternalId
+	}
+
+	total, err := r.db.CountDocuments(ctx, filter)
+	if err != nil {
+		r.logger.Warnf("error while counting users: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while finding users"))
+	}
+
+	users := make([]*model.User, 0)
+	if q.Limit <= 0 {
+		return users, int(total), nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+
+	res, err := r.db.Find(ctx, filter, opts)
+	if err != nil {
+		r.logger.Warnf("error while finding users: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while finding users"))
+	}
+
+	if err := res.All(ctx, &users); err != nil {
+		r.logger.Warnf("error while decoding users: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while decoding users"))
+	}
+
+	return users, int(total), nil
+}
+
+// ScheduleDeletion sets the time when the user is deleted, or cancels the
+// deletion when it is nil
+func (r *Repository) ScheduleDeletion(ctx context.Context, id string, at *time.Time) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		r.logger.Warnf("invalid id: %s", id)
+		return app.ErrInvalidUserId
+	}
+
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"delete_at": at, "updated_at": time.Now()}}
+	if at == nil {
+		update = bson.M{
+			"$unset": bson.M{"delete_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": objectId, "deleted": bson.M{"$ne": true}}, update)
+	if err != nil {
+		r.logger.Warnf("error while scheduling deletion: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating user"))
+	}
+
+	if result.MatchedCount == 0 {
+		r.logger.Warnf("user not found to schedule deletion: %s", id)
+		return app.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetUsersToDelete returns up to limit users whose deletion is due at the
+// time, the earliest first
+func (r *Repository) GetUsersToDelete(ctx context.Context, at time.Time, limit int) ([]*model.User, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "delete_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	res, err := r.db.Find(ctx, bson.M{"delete_at": bson.M{"$lte": at}}, opts)
+	if err != nil {
+		r.logger.Warnf("error while finding users to delete: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding users"))
+	}
+
+	users := make([]*model.User, 0)
+	if err := res.All(ctx, &users); err != nil {
+		r.logger.Warnf("error while decoding users: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while decoding users"))
+	}
+
+	return users, nil
+}
+
+// AnonymizeUser removes the personal fields, the password and the identities
+// of the user whose deletion is due. The document is kept as a disabled
+// tombstone with the names of the deleted users, and all tokens of the user
+// are invalidated. The users whose deletion is cancelled are not found.
+func (r *Repository) AnonymizeUser(ctx context.Context, id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		r.logger.Warnf("invalid id: %s", id)
+		return app.ErrInvalidUserId
+	}
+
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"first_name": model.DeletedUserFirstName,
+			"last_name":  model.DeletedUserLastName,
+			"deleted":    true,
+			"disabled":   true,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{
+			"email":          "",
+			"password":       "",
+			"avatar":         "",
+			"phone":          "",
+			"email_verified": "",
+			"identities":     "",
+			"organization":   "",
+			"external_id":    "",
+			"delete_at":      "",
+		},
+		"$inc": bson.M{"token_generation": 1},
+	}
+
+	filter := bson.M{"_id": objectId, "delete_at": bson.M{"$lte": time.Now()}}
+
+	result, err := r.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Warnf("error while anonymizing user: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating user"))
+	}
+
+	if result.MatchedCount == 0 {
+		r.logger.Warnf("user not found to anonymize: %s", id)
+		return app.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// setUser sets only the given fields of the user, so that the concurrent
+// updates of the other fields are not overwritten
+func (r *Repository) setUser(ctx context.Context, id string, filter bson.M, set bson.M) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		r.logger.Warnf("invalid id: %s", id)
+		return app.ErrInvalidUserId
+	}
+
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	f := bson.M{"_id": objectId}
+	for k, v := range filter {
+		f[k] = v
+	}
+
+	set["updated_at"] = time.Now()
+
+	result, err := r.db.UpdateOne(ctx, f, bson.M{"$set": set})
+	if err != nil {
+		r.logger.Warnf("error while updating user: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating user"))
+	}
+
+	if result.MatchedCount == 0 {
+		r.logger.Warnf("user not found to update: %s", id)
 		return app.ErrUserNotFound
 	}
 
@@ -149,7 +149,7 @@ func TestRepository_CreateUser(t *testing.T) {
 	}
 }
 
-func TestRepository_UpdateUser(t *testing.T) {
+func TestRepository_UpdateProfile(t *testing.T) {
 	type fields struct {
 		Repository app.Repository
 		config     *config.Config
@@ -177,8 +177,8 @@ func TestRepository_UpdateUser(t *testing.T) {
 				logger:     tt.fields.logger,
 				db:         tt.fields.db,
 			}
-			if err := r.UpdateUser(tt.args.ctx, tt.args.id, tt.args.user); (err != nil) != tt.wantErr {
-				t.Errorf("Repository.UpdateUser() error = %v, wantErr %v", err, tt.wantErr)
+			if err := r.UpdateProfile(tt.args.ctx, tt.args.id, tt.args.user); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -0,0 +1,445 @@
+package infrastructure
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+const (
+	samlProtocolNamespace       = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlAssertionNamespace      = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlBindingHttpRedirect     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	samlBindingHttpPost         = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlStatusSuccess           = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlMethodBearer            = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlNameIdFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	samlTimeFormat              = "2006-01-02T15:04:05Z"
+)
+
+// samlIdpMetadata is the part of the metadata of an identity provider which
+// the login needs
+type samlIdpMetadata struct {
+	XMLName           xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityId          string   `xml:"entityID,attr"`
+	IdpSsoDescriptors []struct {
+		KeyDescriptors []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		SingleSignOnServices []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"SingleSignOnService"`
+	} `xml:"IDPSSODescriptor"`
+}
+
+// parseSamlIdpMetadata returns the entity id, the url of the HTTP-Redirect
+// single sign-on service and the signing certificates of the identity
+// provider
+func parseSamlIdpMetadata(b []byte) (entityId string, ssoUrl string, certs []string, err error) {
+	m := &samlIdpMetadata{}
+	if err := xml.Unmarshal(b, m); err != nil {
+		return "", "", nil, errors.Wrap(err, "invalid metadata")
+	}
+
+	if m.EntityId == "" || len(m.IdpSsoDescriptors) == 0 {
+		return "", "", nil, errors.New("metadata has no identity provider")
+	}
+
+	for _, d := range m.IdpSsoDescriptors {
+		for _, s := range d.SingleSignOnServices {
+			if s.Binding == samlBindingHttpRedirect && ssoUrl == "" {
+				ssoUrl = s.Location
+			}
+		}
+
+		for _, k := range d.KeyDescriptors {
+			if k.Use != "" && k.Use != "signing" {
+				continue
+			}
+
+			for _, c := range k.Certificates {
+				c = strings.Join(strings.Fields(c), "")
+
+				der, err := base64.StdEncoding.DecodeString(c)
+				if err != nil {
+					return "", "", nil, errors.Wrap(err, "invalid certificate")
+				}
+
+				if _, err := x509.ParseCertificate(der); err != nil {
+					return "", "", nil, errors.Wrap(err, "invalid certificate")
+				}
+
+				certs = append(certs, c)
+			}
+		}
+	}
+
+	if u, err := url.Parse(ssoUrl); err != nil || ssoUrl == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", "", nil, errors.New("metadata has no HTTP-Redirect single sign-on service")
+	}
+
+	if len(certs) == 0 {
+		return "", "", nil, errors.New("metadata has no signing certificate")
+	}
+
+	return m.EntityId, ssoUrl, certs, nil
+}
+
+// samlSpMetadata is the metadata of the service provider of a connection
+type samlSpMetadata struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityId        string   `xml:"entityID,attr"`
+	SpSsoDescriptor struct {
+		AuthnRequestsSigned      bool     `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned     bool     `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupport          string   `xml:"protocolSupportEnumeration,attr"`
+		NameIdFormats            []string `xml:"NameIDFormat"`
+		AssertionConsumerService struct {
+			Binding   string `xml:"Binding,attr"`
+			Location  string `xml:"Location,attr"`
+			Index     int    `xml:"index,attr"`
+			IsDefault bool   `xml:"isDefault,attr"`
+		}
+	} `xml:"SPSSODescriptor"`
+}
+
+// newSamlSpMetadata returns the metadata of the service provider
+func newSamlSpMetadata(entityId string, acsUrl string) ([]byte, error) {
+	m := &samlSpMetadata{EntityId: entityId}
+	m.SpSsoDescriptor.WantAssertionsSigned = true
+	m.SpSsoDescriptor.ProtocolSupport = samlProtocolNamespace
+	m.SpSsoDescriptor.NameIdFormats = []string{samlNameIdFormatUnspecified}
+	m.SpSsoDescriptor.AssertionConsumerService.Binding = samlBindingHttpPost
+	m.SpSsoDescriptor.AssertionConsumerService.Location = acsUrl
+	m.SpSsoDescriptor.AssertionConsumerService.IsDefault = true
+
+	b, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// samlAuthnRequest is the authentication request to the identity provider
+type samlAuthnRequest struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	Id              string   `xml:"ID,attr"`
+	Version         string   `xml:"Version,attr"`
+	IssueInstant    string   `xml:"IssueInstant,attr"`
+	Destination     string   `xml:"Destination,attr"`
+	AcsUrl          string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding string   `xml:"ProtocolBinding,attr"`
+	Issuer          struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+		Value   string   `xml:",chardata"`
+	}
+	NameIdPolicy struct {
+		XMLName     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol NameIDPolicy"`
+		AllowCreate bool     `xml:"AllowCreate,attr"`
+	}
+}
+
+// samlAuthnRequestUrl returns the url of the authentication request with the
+// HTTP-Redirect binding
+func samlAuthnRequestUrl(ssoUrl string, id string, entityId string, acsUrl string, relayState string) (string, error) {
+	r := &samlAuthnRequest{
+		Id:              id,
+		Version:         "2.0",
+		IssueInstant:    time.Now().UTC().Format(samlTimeFormat),
+		Destination:     ssoUrl,
+		AcsUrl:          acsUrl,
+		ProtocolBinding: samlBindingHttpPost,
+	}
+	r.Issuer.Value = entityId
+	r.NameIdPolicy.AllowCreate = true
+
+	b, err := xml.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(ssoUrl)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid single sign-on url")
+	}
+
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	q.Set("RelayState", relayState)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// samlResponse is the unsigned part of the response of the identity provider
+type samlResponse struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol Response"`
+	InResponseTo string   `xml:"InResponseTo,attr"`
+	Destination  string   `xml:"Destination,attr"`
+	Status       struct {
+		StatusCode struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"StatusCode"`
+	} `xml:"Status"`
+}
+
+// samlAssertion is a signed assertion of the identity provider
+type samlAssertion struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	Issuer  string   `xml:"Issuer"`
+	Subject struct {
+		NameId               string `xml:"NameID"`
+		SubjectConfirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				InResponseTo string    `xml:"InResponseTo,attr"`
+				Recipient    string    `xml:"Recipient,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions *struct {
+		NotBefore            time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter         time.Time `xml:"NotOnOrAfter,attr"`
+		AudienceRestrictions []struct {
+			Audiences []string `xml:"Audience"`
+		} `xml:"AudienceRestriction"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name   string   `xml:"Name,attr"`
+		Values []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+// attribute returns the first value of the attribute
+func (a *samlAssertion) attribute(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	for _, attr := range a.Attributes {
+		if attr.Name == name && len(attr.Values) > 0 {
+			return strings.TrimSpace(attr.Values[0])
+		}
+	}
+
+	return ""
+}
+
+// samlResponseVerifier verifies the responses posted to the assertion
+// consumer service of a connection
+type samlResponseVerifier struct {
+	connection *model.SamlConnection
+	entityId   string
+	acsUrl     string
+	requestId  string
+	skew       time.Duration
+	now        time.Time
+}
+
+// verify validates the signature of the response and returns the assertion.
+// Either the response or the assertion has to be signed by a certificate of
+// the connection, and the assertion is only read from the signed element.
+func (v *samlResponseVerifier) verify(encoded string) (*samlAssertion, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid encoding")
+	}
+
+	res := &samlResponse{}
+	if err := xml.Unmarshal(raw, res); err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+
+	if res.Status.StatusCode.Value != samlStatusSuccess {
+		return nil, errors.Errorf("unsuccessful status %s", res.Status.StatusCode.Value)
+	}
+
+	if res.Destination != "" && res.Destination != v.acsUrl {
+		return nil, errors.New("invalid destination")
+	}
+
+	if res.InResponseTo != v.requestId {
+		return nil, errors.New("response is not for the request")
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+
+	el, err := v.signedAssertion(doc.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	nsCtx, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, err
+	}
+
+	if el, err = etreeutils.NSDetatch(nsCtx, el); err != nil {
+		return nil, err
+	}
+
+	d := etree.NewDocument()
+	d.SetRoot(el)
+
+	b, err := d.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &samlAssertion{}
+	if err := xml.Unmarshal(b, a); err != nil {
+		return nil, errors.Wrap(err, "invalid assertion")
+	}
+
+	if err := v.validate(a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// signedAssertion returns the only assertion of the response from the element
+// which the signature is validated for
+func (v *samlResponseVerifier) signedAssertion(root *etree.Element) (*etree.Element, error) {
+	if root.NamespaceURI() != samlProtocolNamespace || root.Tag != "Response" {
+		return nil, errors.New("invalid response")
+	}
+
+	if len(root.FindElements("./EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(v.connection.IdpCertificates))
+	for _, c := range v.connection.IdpCertificates {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	vctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+	vctx.IdAttribute = "ID"
+
+	signed := false
+	if root.FindElement("./Signature") != nil {
+		validated, err := vctx.Validate(root)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid signature of the response")
+		}
+
+		root, signed = validated, true
+	}
+
+	assertions := root.FindElements("./Assertion")
+	if len(assertions) != 1 || assertions[0].NamespaceURI() != samlAssertionNamespace {
+		return nil, errors.New("response has to have exactly one assertion")
+	}
+
+	el := assertions[0]
+
+	if !signed {
+		if el.FindElement("./Signature") == nil {
+			return nil, errors.New("assertion is not signed")
+		}
+
+		nsCtx, err := etreeutils.NSBuildParentContext(el)
+		if err != nil {
+			return nil, err
+		}
+
+		detached, err := etreeutils.NSDetatch(nsCtx, el)
+		if err != nil {
+			return nil, err
+		}
+
+		if el, err = vctx.Validate(detached); err != nil {
+			return nil, errors.Wrap(err, "invalid signature of the assertion")
+		}
+	}
+
+	return el, nil
+}
+
+// validate checks the issuer, the conditions and the bearer confirmation of
+// the assertion
+func (v *samlResponseVerifier) validate(a *samlAssertion) error {
+	if strings.TrimSpace(a.Issuer) != v.connection.IdpEntityId {
+		return errors.New("invalid issuer")
+	}
+
+	if strings.TrimSpace(a.Subject.NameId) == "" {
+		return errors.New("subject is empty")
+	}
+
+	c := a.Conditions
+	if c == nil || len(c.AudienceRestrictions) == 0 {
+		return errors.New("assertion has no audience restriction")
+	}
+
+	if !c.NotBefore.IsZero() && v.now.Add(v.skew).Before(c.NotBefore) {
+		return errors.New("assertion is not valid yet")
+	}
+
+	if !c.NotOnOrAfter.IsZero() && !v.now.Add(-v.skew).Before(c.NotOnOrAfter) {
+		return errors.New("assertion expired")
+	}
+
+	for _, r := range c.AudienceRestrictions {
+		if !contains(r.Audiences, v.entityId) {
+			return errors.New("invalid audience")
+		}
+	}
+
+	for _, sc := range a.Subject.SubjectConfirmations {
+		if sc.Method != samlMethodBearer || sc.Data.Recipient != v.acsUrl || sc.Data.InResponseTo != v.requestId {
+			continue
+		}
+
+		if sc.Data.NotOnOrAfter.IsZero() || !v.now.Add(-v.skew).Before(sc.Data.NotOnOrAfter) {
+			continue
+		}
+
+		return nil
+	}
+
+	return errors.New("assertion has no valid bearer confirmation")
+}
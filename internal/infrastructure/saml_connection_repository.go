@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SamlConnectionRepository struct {
+	app.SamlConnectionRepository
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewSamlConnectionRepository(config *config.Config, logger logger.ILogger, db *mongo.Client) *SamlConnectionRepository {
+	return &SamlConnectionRepository{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.SamlCollectionName),
+	}
+}
+
+// GetConnection returns a saml connection by id
+func (r *SamlConnectionRepository) GetConnection(ctx context.Context, id string) (*model.SamlConnection, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	c := &model.SamlConnection{}
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrSamlConnectionNotFound
+		}
+
+		r.logger.Warnf("error while finding saml connection: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding saml connection"))
+	}
+
+	return c, nil
+}
+
+// GetConnections returns all saml connections
+func (r *SamlConnectionRepository) GetConnections(ctx context.Context) ([]*model.SamlConnection, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		r.logger.Warnf("error while getting saml connections: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding saml connections"))
+	}
+
+	connections := make([]*model.SamlConnection, 0)
+	if err := res.All(ctx, &connections); err != nil {
+		r.logger.Warnf("error while decoding saml connections: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while decoding saml connections"))
+	}
+
+	return connections, nil
+}
+
+// CreateConnection creates a new saml connection. The id of the connection
+// has to be unique.
+func (r *SamlConnectionRepository) CreateConnection(ctx context.Context, connection *model.SamlConnection) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.InsertOne(ctx, connection); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return app.ErrSamlConnectionExists
+		}
+
+		r.logger.Warnf("error while creating saml connection: %s", err)
+		return app.NewInternalServerError(errors.New("error while creating saml connection"))
+	}
+
+	return nil
+}
+
+// DeleteConnection deletes a saml connection by id
+func (r *SamlConnectionRepository) DeleteConnection(ctx context.Context, id string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Warnf("error while deleting saml connection: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting saml connection"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrSamlConnectionNotFound
+	}
+
+	return nil
+}
+
+func (r *SamlConnectionRepository) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.config.Mongo.SocketTimeout)*time.Second)
+}
@@ -0,0 +1,252 @@
+package infrastructure
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// samlConnectionIdPattern is the pattern of the slugs of the organizations
+var samlConnectionIdPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type SamlService struct {
+	app.SamlService
+	config      *config.Config
+	logger      logger.ILogger
+	repo        app.Repository
+	ts          app.TokenService
+	connections app.SamlConnectionRepository
+	logins      app.FederatedLoginStore
+}
+
+func NewSamlService(config *config.Config, logger logger.ILogger, repo app.Repository, ts app.TokenService, connections app.SamlConnectionRepository, logins app.FederatedLoginStore) *SamlService {
+	return &SamlService{
+		config:      config,
+		logger:      logger,
+		repo:        repo,
+		ts:          ts,
+		connections: connections,
+		logins:      logins,
+	}
+}
+
+// CreateConnection imports the metadata of the identity provider of an
+// organization
+func (s *SamlService) CreateConnection(ctx context.Context, r *app.CreateSamlConnectionRequest) (*model.SamlConnection, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	if !samlConnectionIdPattern.MatchString(r.Id) {
+		return nil, app.NewBadRequestError(errors.New("id has to consist of lowercase letters, digits and dashes"))
+	}
+
+	entityId, ssoUrl, certs, err := parseSamlIdpMetadata([]byte(r.Metadata))
+	if err != nil {
+		return nil, app.NewBadRequestError(err)
+	}
+
+	now := time.Now()
+
+	connection := &model.SamlConnection{
+		Id:               r.Id,
+		Name:             r.Name,
+		IdpEntityId:      entityId,
+		IdpSsoUrl:        ssoUrl,
+		IdpCertificates:  certs,
+		Domains:          r.Domains,
+		AttributeMapping: r.AttributeMapping,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if err := s.connections.CreateConnection(ctx, connection); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("saml connection %s (%s) is created", connection.Id, connection.Name)
+
+	return connection, nil
+}
+
+// GetConnection returns a connection by id
+func (s *SamlService) GetConnection(ctx context.Context, id string) (*model.SamlConnection, error) {
+	return s.connections.GetConnection(ctx, id)
+}
+
+// GetConnections returns all connections
+func (s *SamlService) GetConnections(ctx context.Context) ([]*model.SamlConnection, error) {
+	return s.connections.GetConnections(ctx)
+}
+
+// DeleteConnection deletes a connection by id
+func (s *SamlService) DeleteConnection(ctx context.Context, id string) error {
+	if err := s.connections.DeleteConnection(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Infof("saml connection %s is deleted", id)
+
+	return nil
+}
+
+// Metadata returns the metadata of the service provider of the connection
+func (s *SamlService) Metadata(ctx context.Context, r *app.SamlMetadataRequest) ([]byte, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.connections.GetConnection(ctx, r.Connection); err != nil {
+		return nil, err
+	}
+
+	return newSamlSpMetadata(r.EntityId, r.AcsUrl)
+}
+
+// AuthnRequestUrl starts a login at the identity provider. The login is bound
+// to the relay state and the id of the request which the response has to be
+// in response to.
+func (s *SamlService) AuthnRequestUrl(ctx context.Context, r *app.SamlLoginRequest) (string, error) {
+	if err := app.Validate(r); err != nil {
+		return "", err
+	}
+
+	connection, err := s.connections.GetConnection(ctx, r.Connection)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	requestId, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	// ids of the requests have to start with a letter or an underscore
+	requestId = "_" + requestId
+
+	now := time.Now()
+
+	if err := s.logins.Save(ctx, &model.FederatedLogin{
+		Id:          hashCode(state),
+		Provider:    connection.Provider(),
+		Nonce:       requestId,
+		RedirectUri: r.AcsUrl,
+		Audience:    r.Audience,
+		Scope:       r.Scope,
+		ExpiresAt:   now.Add(time.Duration(s.config.Federation.LoginExp) * time.Second),
+		CreatedAt:   now,
+	}); err != nil {
+		return "", err
+	}
+
+	u, err := samlAuthnRequestUrl(connection.IdpSsoUrl, requestId, r.EntityId, r.AcsUrl, state)
+	if err != nil {
+		s.logger.Warnf("failed to start login at saml connection %s: %s", connection.Id, err)
+		return "", app.NewInternalServerError(errors.New("identity provider is not available"))
+	}
+
+	return u, nil
+}
+
+// Acs validates the response of the identity provider and issues a token pair
+// to the user of the assertion. Unsolicited responses are not accepted, the
+// response has to be in response to a request of AuthnRequestUrl.
+func (s *SamlService) Acs(ctx context.Context, r *app.SamlAcsRequest) (*app.LoginResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	login, err := s.logins.Consume(ctx, hashCode(r.RelayState))
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := s.connections.GetConnection(ctx, r.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	if login.Provider != connection.Provider() || login.RedirectUri != r.AcsUrl {
+		return nil, app.ErrFederatedLoginNotFound
+	}
+
+	verifier := &samlResponseVerifier{
+		connection: connection,
+		entityId:   r.EntityId,
+		acsUrl:     r.AcsUrl,
+		requestId:  login.Nonce,
+		skew:       time.Duration(s.config.Saml.MaxClockSkew) * time.Second,
+		now:        time.Now(),
+	}
+
+	assertion, err := verifier.verify(r.SAMLResponse)
+	if err != nil {
+		s.logger.Warnf("invalid saml response of connection %s: %s", connection.Id, err)
+		return nil, app.ErrFederatedLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, connection, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cancelAccountDeletion(ctx, s.repo, s.logger, user); err != nil {
+		return nil, err
+	}
+
+	return issueLoginResponse(ctx, s.config, s.logger, s.ts, user, login.Audience, login.Scope)
+}
+
+// resolveUser returns the user which the NameID is linked to. Otherwise the
+// identity is linked to the user with the same email, or a new user is
+// created with the mapped attributes. The email has to belong to one of the
+// domains of the organization for both, and only the users of the
+// organization are linked.
+func (s *SamlService) resolveUser(ctx context.Context, connection *model.SamlConnection, assertion *samlAssertion) (*model.User, error) {
+	subject := strings.TrimSpace(assertion.Subject.NameId)
+
+	user, err := s.repo.GetUserByIdentity(ctx, connection.Provider(), subject)
+	if err == nil {
+		return user, nil
+	}
+
+	if !errors.Is(err, app.ErrUserNotFound) {
+		return nil, err
+	}
+
+	mapping := connection.AttributeMapping
+
+	email := subject
+	if mapping.Email != "" {
+		email = assertion.attribute(mapping.Email)
+	}
+
+	if !connection.AllowsEmail(email) {
+		return nil, app.ErrSamlEmailDomainNotAllowed
+	}
+
+	identity := model.ExternalIdentity{
+		Provider: connection.Provider(),
+		Subject:  subject,
+		Email:    email,
+		LinkedAt: time.Now(),
+	}
+
+	return linkOrCreateUser(ctx, s.repo, s.logger, identity, &model.User{
+		FirstName:    assertion.attribute(mapping.FirstName),
+		LastName:     assertion.attribute(mapping.LastName),
+		Organization: connection.Id,
+	})
+}
@@ -0,0 +1,597 @@
+package infrastructure
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	samlIdpEntityIdForTesting = "https://idp.acme.com/saml"
+	samlEntityIdForTesting    = "https://id.example.com/api/v1/saml/acme/metadata"
+	samlAcsUrlForTesting      = "https://id.example.com/api/v1/saml/acme/acs"
+)
+
+// stubIdp is an identity provider which signs the responses with a self
+// signed certificate
+type stubIdp struct {
+	key  *rsa.PrivateKey
+	cert []byte
+}
+
+func newStubIdpForTesting(t *testing.T) *stubIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &stubIdp{key: key, cert: cert}
+}
+
+// metadata returns the metadata of the identity provider
+func (p *stubIdp) metadata() string {
+	return fmt.Sprintf(`<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="%s">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo>
+        <ds:X509Data>
+          <ds:X509Certificate>
+            %s
+          </ds:X509Certificate>
+        </ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://idp.acme.com/sso/post"/>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.acme.com/sso/redirect"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`, samlIdpEntityIdForTesting, base64.StdEncoding.EncodeToString(p.cert))
+}
+
+// connection returns the connection of the identity provider
+func (p *stubIdp) connection() *model.SamlConnection {
+	return &model.SamlConnection{
+		Id:              "acme",
+		Name:            "Acme",
+		IdpEntityId:     samlIdpEntityIdForTesting,
+		IdpSsoUrl:       "https://idp.acme.com/sso/redirect",
+		IdpCertificates: []string{base64.StdEncoding.EncodeToString(p.cert)},
+		Domains:         []string{"acme.com"},
+		AttributeMapping: model.SamlAttributeMapping{
+			Email:     "mail",
+			FirstName: "givenName",
+			LastName:  "sn",
+		},
+	}
+}
+
+// samlAssertionForTesting is the content of a response of the stub
+// identity provider
+type samlAssertionForTesting struct {
+	InResponseTo string
+	Issuer       string
+	NameId       string
+	Recipient    string
+	Audience     string
+	NotBefore    time.Time
+	NotOnOrAfter time.Time
+	Attributes   map[string]string
+	// Sign is the signed element, "assertion", "response" or none
+	Sign string
+	// Extra is appended to the response after it is signed
+	Extra string
+}
+
+// response returns the encoded response of the identity provider
+func (p *stubIdp) response(t *testing.T, a *samlAssertionForTesting) string {
+	var attributes strings.Builder
+	for name, value := range a.Attributes {
+		fmt.Fprintf(&attributes, `<saml:Attribute Name="%s"><saml:AttributeValue>%s</saml:AttributeValue></saml:Attribute>`, name, value)
+	}
+
+	now := time.Now().UTC().Format(samlTimeFormat)
+
+	res := fmt.Sprintf(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response" Version="2.0" IssueInstant="%[1]s" Destination="%[2]s" InResponseTo="%[3]s">`+
+		`<saml:Issuer>%[4]s</saml:Issuer>`+
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>`+
+		`<saml:Assertion ID="_assertion" Version="2.0" IssueInstant="%[1]s">`+
+		`<saml:Issuer>%[4]s</saml:Issuer>`+
+		`<saml:Subject><saml:NameID>%[5]s</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer"><saml:SubjectConfirmationData InResponseTo="%[3]s" Recipient="%[2]s" NotOnOrAfter="%[7]s"/></saml:SubjectConfirmation>`+
+		`</saml:Subject>`+
+		`<saml:Conditions NotBefore="%[6]s" NotOnOrAfter="%[7]s"><saml:AudienceRestriction><saml:Audience>%[8]s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AttributeStatement>%[9]s</saml:AttributeStatement>`+
+		`</saml:Assertion>`+
+		`</samlp:Response>`,
+		now, a.Recipient, a.InResponseTo, a.Issuer, a.NameId,
+		a.NotBefore.UTC().Format(samlTimeFormat), a.NotOnOrAfter.UTC().Format(samlTimeFormat), a.Audience, attributes.String())
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(res); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := dsig.NewSigningContext(p.key, [][]byte{p.cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.IdAttribute = "ID"
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	switch a.Sign {
+	case "response":
+		signed, err := ctx.SignEnveloped(doc.Root())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc.SetRoot(signed)
+	case "assertion":
+		el := doc.Root().FindElement("./Assertion")
+
+		// the assertion is signed with the namespaces of the response
+		nsCtx, err := etreeutils.NSBuildParentContext(el)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		detached, err := etreeutils.NSDetatch(nsCtx, el)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signed, err := ctx.SignEnveloped(detached)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc.Root().InsertChild(el, signed)
+		doc.Root().RemoveChild(el)
+	}
+
+	b, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Extra != "" {
+		b = strings.Replace(b, "</samlp:Response>", a.Extra+"</samlp:Response>", 1)
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(b))
+}
+
+// startSamlLoginForTesting starts a login and returns the id of the
+// authentication request and the relay state
+func startSamlLoginForTesting(t *testing.T, service *SamlService) (string, string) {
+	u, err := service.AuthnRequestUrl(context.Background(), &app.SamlLoginRequest{
+		SamlMetadataRequest: app.SamlMetadataRequest{Connection: "acme", EntityId: samlEntityIdForTesting, AcsUrl: samlAcsUrlForTesting},
+	})
+	if err != nil {
+		t.Fatalf("SamlService.AuthnRequestUrl() error = %v", err)
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deflated, err := base64.StdEncoding.DecodeString(parsed.Query().Get("SAMLRequest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &samlAuthnRequest{}
+	if err := xml.Unmarshal(b, r); err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Host != "idp.acme.com" || r.Destination != "https://idp.acme.com/sso/redirect" || r.AcsUrl != samlAcsUrlForTesting || r.Issuer.Value != samlEntityIdForTesting {
+		t.Errorf("SamlService.AuthnRequestUrl() = %v with request %s", u, b)
+	}
+
+	return r.Id, parsed.Query().Get("RelayState")
+}
+
+func TestSamlService_Acs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	idp := newStubIdpForTesting(t)
+	other := newStubIdpForTesting(t)
+
+	ts := mock.NewMockTokenService(ctrl)
+	ts.EXPECT().ResolveGrant(ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *model.User, g *app.TokenGrant) (*app.TokenGrant, error) {
+			return g, nil
+		}).AnyTimes()
+	ts.EXPECT().GenerateAccessToken(ctx, gomock.Any(), gomock.Any()).Return("access_token", nil).AnyTimes()
+	ts.EXPECT().GenerateRefreshToken(ctx, gomock.Any(), gomock.Any()).Return("refresh_token", nil).AnyTimes()
+
+	connections := mock.NewMockSamlConnectionRepository(ctrl)
+	connections.EXPECT().GetConnection(gomock.Any(), "acme").Return(idp.connection(), nil).AnyTimes()
+
+	linked := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", Identities: []model.ExternalIdentity{{Provider: "saml:acme", Subject: "jane"}}}
+	existing := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", EmailVerified: true, Organization: "acme"}
+	personal := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", EmailVerified: true}
+	foreign := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", EmailVerified: true, Organization: "globex"}
+	created := primitive.NewObjectID()
+	provisioned := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		assertion func(a *samlAssertionForTesting)
+		repo      func(repo *mock.MockRepository)
+		acs       func(r *app.SamlAcsRequest)
+		wantUser  string
+		wantErr   error
+	}{
+		{
+			name: "should login the user of a linked identity",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(linked, nil)
+			},
+			wantUser: linked.GetIdString(),
+		},
+		{
+			name: "should link the identity to the user with the email",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@acme.com").Return(existing, nil)
+				repo.EXPECT().LinkIdentity(gomock.Any(), existing.GetIdString(), gomock.Any()).Return(nil)
+			},
+			wantUser: existing.GetIdString(),
+		},
+		{
+			name: "should link the identity to the user provisioned by the directory of the organization",
+			repo: func(repo *mock.MockRepository) {
+				var user *model.User
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@acme.com").
+					DoAndReturn(func(_ context.Context, _ string) (*model.User, error) {
+						if user == nil {
+							return nil, app.ErrUserNotFound
+						}
+						return user, nil
+					}).Times(2)
+				repo.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (string, error) {
+						user = u
+						user.Id = provisioned
+						return provisioned.Hex(), nil
+					})
+
+				scim := NewScimService(config.New(), NewLoggerMock(), repo, nil, nil)
+				if _, err := scim.CreateUser(ctx, "acme", &app.ScimUser{UserName: "jane@acme.com"}); err != nil {
+					t.Fatalf("ScimService.CreateUser() error = %v", err)
+				}
+
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().LinkIdentity(gomock.Any(), provisioned.Hex(), gomock.Any()).Return(nil)
+			},
+			wantUser: provisioned.Hex(),
+		},
+		{
+			name: "should fail to link the identity to a personal account",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@acme.com").Return(personal, nil)
+			},
+			wantErr: app.ErrSamlUserOfAnotherOrg,
+		},
+		{
+			name: "should fail to link the identity to the user of another organization",
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@acme.com").Return(foreign, nil)
+			},
+			wantErr: app.ErrSamlUserOfAnotherOrg,
+		},
+		{
+			name: "should create a user with the mapped attributes on the first login",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Sign = "response"
+			},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().GetUserByEmail(gomock.Any(), "jane@acme.com").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (string, error) {
+						if u.FirstName != "Jane" || u.LastName != "Doe" || u.Email != "jane@acme.com" || !u.EmailVerified || u.Role != model.RoleUser || u.Organization != "acme" {
+							t.Errorf("Repository.CreateUser() user = %+v", u)
+						}
+						return created.Hex(), nil
+					})
+			},
+			wantUser: created.Hex(),
+		},
+		{
+			name: "should fail when email is not of the domains of the organization",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Attributes["mail"] = "jane@evil.com"
+			},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByIdentity(gomock.Any(), "saml:acme", "jane").Return(nil, app.ErrUserNotFound)
+			},
+			wantErr: app.ErrSamlEmailDomainNotAllowed,
+		},
+		{
+			name: "should fail when response is not signed",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Sign = ""
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when response is signed by another certificate",
+			acs: func(r *app.SamlAcsRequest) {
+				r.SAMLResponse = ""
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when an unsigned assertion is added to the response",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Extra = `<saml:Assertion ID="_evil" Version="2.0"><saml:Issuer>` + samlIdpEntityIdForTesting + `</saml:Issuer></saml:Assertion>`
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when audience is another service provider",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Audience = "https://other.example.com"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when response is for another service",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Recipient = "https://other.example.com/acs"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when response is not for the request",
+			assertion: func(a *samlAssertionForTesting) {
+				a.InResponseTo = "_unknown"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when issuer is another identity provider",
+			assertion: func(a *samlAssertionForTesting) {
+				a.Issuer = "https://idp.evil.com"
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when assertion is expired",
+			assertion: func(a *samlAssertionForTesting) {
+				a.NotBefore = time.Now().Add(-time.Hour)
+				a.NotOnOrAfter = time.Now().Add(-10 * time.Minute)
+			},
+			wantErr: app.ErrFederatedLoginFailed,
+		},
+		{
+			name: "should fail when relay state is invalid",
+			acs: func(r *app.SamlAcsRequest) {
+				r.RelayState = "unknown"
+			},
+			wantErr: app.ErrFederatedLoginNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mock.NewMockRepository(ctrl)
+			if tt.repo != nil {
+				tt.repo(repo)
+			}
+
+			service := NewSamlService(config.New(), NewLoggerMock(), repo, ts, connections, NewInMemoryFederatedLoginStore())
+
+			requestId, relayState := startSamlLoginForTesting(t, service)
+
+			a := &samlAssertionForTesting{
+				InResponseTo: requestId,
+				Issuer:       samlIdpEntityIdForTesting,
+				NameId:       "jane",
+				Recipient:    samlAcsUrlForTesting,
+				Audience:     samlEntityIdForTesting,
+				NotBefore:    time.Now().Add(-time.Minute),
+				NotOnOrAfter: time.Now().Add(5 * time.Minute),
+				Attributes:   map[string]string{"mail": "jane@acme.com", "givenName": "Jane", "sn": "Doe"},
+				Sign:         "assertion",
+			}
+			if tt.assertion != nil {
+				tt.assertion(a)
+			}
+
+			r := &app.SamlAcsRequest{
+				SamlMetadataRequest: app.SamlMetadataRequest{Connection: "acme", EntityId: samlEntityIdForTesting, AcsUrl: samlAcsUrlForTesting},
+				SAMLResponse:        idp.response(t, a),
+				RelayState:          relayState,
+			}
+			if tt.acs != nil {
+				tt.acs(r)
+			}
+
+			if r.SAMLResponse == "" {
+				r.SAMLResponse = other.response(t, a)
+			}
+
+			got, err := service.Acs(ctx, r)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("SamlService.Acs() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("SamlService.Acs() error = %v", err)
+			}
+
+			if got.UserDto.Id != tt.wantUser || got.UserDto.Email != "jane@acme.com" || got.AccessToken != "access_token" {
+				t.Errorf("SamlService.Acs() = %+v, want user %v", got, tt.wantUser)
+			}
+
+			if _, err := service.Acs(ctx, r); !errors.Is(err, app.ErrFederatedLoginNotFound) {
+				t.Errorf("SamlService.Acs() error = %v on replay, want %v", err, app.ErrFederatedLoginNotFound)
+			}
+		})
+	}
+}
+
+func TestSamlService_CreateConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	idp := newStubIdpForTesting(t)
+
+	tests := []struct {
+		name    string
+		request func(r *app.CreateSamlConnectionRequest)
+		wantErr bool
+	}{
+		{
+			name: "should import the metadata of the identity provider",
+		},
+		{
+			name: "should fail when id is not a slug",
+			request: func(r *app.CreateSamlConnectionRequest) {
+				r.Id = "Acme Corp"
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail when domain is invalid",
+			request: func(r *app.CreateSamlConnectionRequest) {
+				r.Domains = []string{"not a domain"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail when metadata has no signing certificate",
+			request: func(r *app.CreateSamlConnectionRequest) {
+				r.Metadata = strings.Replace(r.Metadata, `use="signing"`, `use="encryption"`, 1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail when metadata has no HTTP-Redirect binding",
+			request: func(r *app.CreateSamlConnectionRequest) {
+				r.Metadata = strings.Replace(r.Metadata, "HTTP-Redirect", "SOAP", 1)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connections := mock.NewMockSamlConnectionRepository(ctrl)
+
+			r := &app.CreateSamlConnectionRequest{
+				Id:       "acme",
+				Name:     "Acme",
+				Metadata: idp.metadata(),
+				Domains:  []string{"acme.com"},
+			}
+			if tt.request != nil {
+				tt.request(r)
+			}
+
+			if !tt.wantErr {
+				connections.EXPECT().CreateConnection(ctx, gomock.Any()).Return(nil)
+			}
+
+			service := NewSamlService(config.New(), NewLoggerMock(), nil, nil, connections, NewInMemoryFederatedLoginStore())
+
+			got, err := service.CreateConnection(ctx, r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SamlService.CreateConnection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			want := idp.connection()
+			if got.IdpEntityId != want.IdpEntityId || got.IdpSsoUrl != want.IdpSsoUrl || len(got.IdpCertificates) != 1 || got.IdpCertificates[0] != want.IdpCertificates[0] {
+				t.Errorf("SamlService.CreateConnection() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestSamlService_Metadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	connections := mock.NewMockSamlConnectionRepository(ctrl)
+	connections.EXPECT().GetConnection(ctx, "acme").Return(&model.SamlConnection{Id: "acme"}, nil)
+	connections.EXPECT().GetConnection(ctx, "unknown").Return(nil, app.ErrSamlConnectionNotFound)
+
+	service := NewSamlService(config.New(), NewLoggerMock(), nil, nil, connections, NewInMemoryFederatedLoginStore())
+
+	b, err := service.Metadata(ctx, &app.SamlMetadataRequest{Connection: "acme", EntityId: samlEntityIdForTesting, AcsUrl: samlAcsUrlForTesting})
+	if err != nil {
+		t.Fatalf("SamlService.Metadata() error = %v", err)
+	}
+
+	m := &samlSpMetadata{}
+	if err := xml.Unmarshal(b, m); err != nil {
+		t.Fatalf("SamlService.Metadata() = %s, error = %v", b, err)
+	}
+
+	if m.EntityId != samlEntityIdForTesting || m.SpSsoDescriptor.AssertionConsumerService.Location != samlAcsUrlForTesting || !m.SpSsoDescriptor.WantAssertionsSigned {
+		t.Errorf("SamlService.Metadata() = %s", b)
+	}
+
+	if _, err := service.Metadata(ctx, &app.SamlMetadataRequest{Connection: "unknown", EntityId: samlEntityIdForTesting, AcsUrl: samlAcsUrlForTesting}); !errors.Is(err, app.ErrSamlConnectionNotFound) {
+		t.Errorf("SamlService.Metadata() error = %v, want %v", err, app.ErrSamlConnectionNotFound)
+	}
+}
@@ -0,0 +1,184 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScimGroupRepository struct {
+	app.ScimGroupRepository
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewScimGroupRepository(config *config.Config, logger logger.ILogger, db *mongo.Client) *ScimGroupRepository {
+	return &ScimGroupRepository{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.ScimGroupCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the repository
+func (r *ScimGroupRepository) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "organization", Value: 1}, {Key: "display_name", Value: 1}}},
+		{Keys: bson.D{{Key: "organization", Value: 1}, {Key: "members", Value: 1}}},
+	})
+	if err != nil {
+		r.logger.Warnf("error while creating scim group indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetGroup returns a group of the organization by id
+func (r *ScimGroupRepository) GetGroup(ctx context.Context, organization string, id string) (*model.ScimGroup, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	g := &model.ScimGroup{}
+	if err := r.db.FindOne(ctx, bson.M{"_id": id, "organization": organization}).Decode(g); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrScimGroupNotFound
+		}
+
+		r.logger.Warnf("error while finding scim group: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding group"))
+	}
+
+	return g, nil
+}
+
+// FindGroups returns a page of the groups of the query ordered by their
+// creation
+func (r *ScimGroupRepository) FindGroups(ctx context.Context, q *app.GroupQuery) ([]*model.ScimGroup, int, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"organization": q.Organization}
+	if q.DisplayName != "" {
+		filter["display_name"] = q.DisplayName
+	}
+
+	if q.ExternalId != "" {
+		filter["external_id"] = q.ExternalId
+	}
+
+	total, err := r.db.CountDocuments(ctx, filter)
+	if err != nil {
+		r.logger.Warnf("error while counting scim groups: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while finding groups"))
+	}
+
+	groups := make([]*model.ScimGroup, 0)
+	if q.Limit <= 0 {
+		return groups, int(total), nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+
+	res, err := r.db.Find(ctx, filter, opts)
+	if err != nil {
+		r.logger.Warnf("error while finding scim groups: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while finding groups"))
+	}
+
+	if err := res.All(ctx, &groups); err != nil {
+		r.logger.Warnf("error while decoding scim groups: %s", err)
+		return nil, 0, app.NewInternalServerError(errors.New("error while decoding groups"))
+	}
+
+	return groups, int(total), nil
+}
+
+// CreateGroup creates a new group
+func (r *ScimGroupRepository) CreateGroup(ctx context.Context, group *model.ScimGroup) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.InsertOne(ctx, group); err != nil {
+		r.logger.Warnf("error while creating scim group: %s", err)
+		return app.NewInternalServerError(errors.New("error while creating group"))
+	}
+
+	return nil
+}
+
+// UpdateGroup replaces the group
+func (r *ScimGroupRepository) UpdateGroup(ctx context.Context, group *model.ScimGroup) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": group.Id, "organization": group.Organization}
+	res, err := r.db.ReplaceOne(ctx, filter, group)
+	if err != nil {
+		r.logger.Warnf("error while updating scim group: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating group"))
+	}
+
+	if res.MatchedCount == 0 {
+		return app.ErrScimGroupNotFound
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a group of the organization by id
+func (r *ScimGroupRepository) DeleteGroup(ctx context.Context, organization string, id string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": id, "organization": organization})
+	if err != nil {
+		r.logger.Warnf("error while deleting scim group: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting group"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrScimGroupNotFound
+	}
+
+	return nil
+}
+
+// RemoveMember removes the user from the groups of the organization
+func (r *ScimGroupRepository) RemoveMember(ctx context.Context, organization string, userId string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"organization": organization, "members": userId}
+	update := bson.M{
+		"$pull": bson.M{"members": userId},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	if _, err := r.db.UpdateMany(ctx, filter, update); err != nil {
+		r.logger.Warnf("error while removing member from scim groups: %s", err)
+		return app.NewInternalServerError(errors.New("error while updating groups"))
+	}
+
+	return nil
+}
+
+func (r *ScimGroupRepository) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.config.Mongo.SocketTimeout)*time.Second)
+}
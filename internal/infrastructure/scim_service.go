@@ -0,0 +1,928 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scimFilterPattern matches the filters of `attribute eq "value"` form
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9.]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// scimMemberPathPattern matches the paths of the members of a group by value
+var scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+("(?:[^"\\]|\\.)*")\s*\]$`)
+
+// scimUserAttributes are the attributes of the user resource. The attribute
+// names of the patch requests are case insensitive.
+var scimUserAttributes = []string{"externalId", "userName", "name", "formatted", "givenName", "familyName", "displayName", "emails", "active"}
+
+type ScimService struct {
+	app.ScimService
+	config *config.Config
+	logger logger.ILogger
+	repo   app.Repository
+	tokens app.ScimTokenRepository
+	groups app.ScimGroupRepository
+}
+
+func NewScimService(config *config.Config, logger logger.ILogger, repo app.Repository, tokens app.ScimTokenRepository, groups app.ScimGroupRepository) *ScimService {
+	return &ScimService{
+		config: config,
+		logger: logger,
+		repo:   repo,
+		tokens: tokens,
+		groups: groups,
+	}
+}
+
+// Authenticate returns the organization of the token. Tokens are given in
+// "id.secret" format.
+func (s *ScimService) Authenticate(ctx context.Context, token string) (string, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return "", app.ErrInvalidScimToken
+	}
+
+	t, err := s.tokens.GetToken(ctx, id)
+	if err != nil {
+		if errors.Is(err, app.ErrScimTokenNotFound) {
+			return "", app.ErrInvalidScimToken
+		}
+
+		return "", err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(secret)), []byte(t.Secret)) != 1 {
+		return "", app.ErrInvalidScimToken
+	}
+
+	return t.Organization, nil
+}
+
+// CreateToken creates a bearer token of an organization. Only the hash of
+// the secret is stored.
+func (s *ScimService) CreateToken(ctx context.Context, r *app.CreateScimTokenRequest) (*app.CreateScimTokenResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	if !samlConnectionIdPattern.MatchString(r.Organization) {
+		return nil, app.NewBadRequestError(errors.New("organization has to consist of lowercase letters, digits and dashes"))
+	}
+
+	id, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.ScimToken{
+		Id:           id,
+		Organization: r.Organization,
+		Name:         r.Name,
+		Secret:       hashCode(secret),
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.tokens.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("scim token %s is created for organization %s", token.Id, token.Organization)
+
+	return &app.CreateScimTokenResponse{
+		ScimToken: *token,
+		Token:     id + "." + secret,
+	}, nil
+}
+
+// GetTokens returns the tokens of all organizations
+func (s *ScimService) GetTokens(ctx context.Context) ([]*model.ScimToken, error) {
+	return s.tokens.GetTokens(ctx)
+}
+
+// DeleteToken deletes a token by id
+func (s *ScimService) DeleteToken(ctx context.Context, id string) error {
+	if err := s.tokens.DeleteToken(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Infof("scim token %s is deleted", id)
+
+	return nil
+}
+
+// GetUsers returns a page of the users of the organization. The users can be
+// filtered by userName or externalId.
+func (s *ScimService) GetUsers(ctx context.Context, organization string, r *app.ScimListRequest) (*app.ScimListResponse, error) {
+	attr, value, err := parseScimFilter(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &app.UserQuery{Organization: organization}
+	switch strings.ToLower(attr) {
+	case "":
+	case "username", "emails", "emails.value":
+		q.Email = value
+	case "externalid":
+		q.ExternalId = value
+	default:
+		return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidFilter, fmt.Sprintf("filtering by %s is not supported", attr))
+	}
+
+	q.Offset, q.Limit = s.page(r)
+
+	users, total, err := s.repo.FindUsers(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*app.ScimUser, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, toScimUser(u))
+	}
+
+	return scimListResponse(total, q.Offset, len(resources), resources), nil
+}
+
+// GetUser returns a user of the organization
+func (s *ScimService) GetUser(ctx context.Context, organization string, id string) (*app.ScimUser, error) {
+	user, err := s.getUser(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toScimUser(user), nil
+}
+
+// CreateUser creates a user of the organization. The user has no password
+// and logs in at the identity provider of the organization.
+func (s *ScimService) CreateUser(ctx context.Context, organization string, u *app.ScimUser) (*app.ScimUser, error) {
+	now := time.Now()
+
+	user := &model.User{
+		Role:         model.RoleUser,
+		Organization: organization,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.applyUser(ctx, user, u); err != nil {
+		return nil, err
+	}
+
+	uid, err := s.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Id = objectId
+
+	s.logger.Infof("user %s is provisioned by organization %s", uid, organization)
+
+	return toScimUser(user), nil
+}
+
+// ReplaceUser replaces the attributes of a user of the organization
+func (s *ScimService) ReplaceUser(ctx context.Context, organization string, id string, u *app.ScimUser) (*app.ScimUser, error) {
+	user, err := s.getUser(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateUser(ctx, user, u)
+}
+
+// PatchUser applies the operations to the user resource. The filtered paths
+// of the multi-valued attributes and the extension attributes are ignored,
+// since the email of the user is its userName.
+func (s *ScimService) PatchUser(ctx context.Context, organization string, id string, r *app.ScimPatchRequest) (*app.ScimUser, error) {
+	user, err := s.getUser(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(toScimUser(user))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, op := range r.Operations {
+		if err := patchScimDocument(doc, op); err != nil {
+			return nil, err
+		}
+	}
+
+	// some providers send the active attribute as a string
+	if active, ok := doc["active"].(string); ok {
+		v, err := strconv.ParseBool(active)
+		if err != nil {
+			return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "active has to be a boolean")
+		}
+
+		doc["active"] = v
+	}
+
+	if b, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+
+	patched := &app.ScimUser{}
+	if err := json.Unmarshal(b, patched); err != nil {
+		return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, err.Error())
+	}
+
+	return s.updateUser(ctx, user, patched)
+}
+
+// DeleteUser deletes a user of the organization at once and removes the user
+// from the groups. The user is anonymized like the deleted accounts, so the
+// tombstone is left instead of the document.
+func (s *ScimService) DeleteUser(ctx context.Context, organization string, id string) error {
+	if _, err := s.getUser(ctx, organization, id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.repo.ScheduleDeletion(ctx, id, &now); err != nil {
+		return err
+	}
+
+	if err := s.repo.AnonymizeUser(ctx, id); err != nil {
+		s.logger.Warnf("failed to anonymize user %s: %s", id, err)
+		return err
+	}
+
+	if err := s.groups.RemoveMember(ctx, organization, id); err != nil {
+		return err
+	}
+
+	s.logger.Infof("user %s is deprovisioned by organization %s", id, organization)
+
+	return nil
+}
+
+// GetGroups returns a page of the groups of the organization. The groups can
+// be filtered by displayName or externalId.
+func (s *ScimService) GetGroups(ctx context.Context, organization string, r *app.ScimListRequest) (*app.ScimListResponse, error) {
+	attr, value, err := parseScimFilter(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &app.GroupQuery{Organization: organization}
+	switch strings.ToLower(attr) {
+	case "":
+	case "displayname":
+		q.DisplayName = value
+	case "externalid":
+		q.ExternalId = value
+	default:
+		return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidFilter, fmt.Sprintf("filtering by %s is not supported", attr))
+	}
+
+	q.Offset, q.Limit = s.page(r)
+
+	groups, total, err := s.groups.FindGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*app.ScimGroup, 0, len(groups))
+	for _, g := range groups {
+		resources = append(resources, toScimGroup(g))
+	}
+
+	return scimListResponse(total, q.Offset, len(resources), resources), nil
+}
+
+// GetGroup returns a group of the organization
+func (s *ScimService) GetGroup(ctx context.Context, organization string, id string) (*app.ScimGroup, error) {
+	group, err := s.groups.GetGroup(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toScimGroup(group), nil
+}
+
+// CreateGroup creates a group of the users of the organization
+func (s *ScimService) CreateGroup(ctx context.Context, organization string, g *app.ScimGroup) (*app.ScimGroup, error) {
+	id, err := newTokenId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	group := &model.ScimGroup{
+		Id:           id,
+		Organization: organization,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.applyGroup(ctx, group, g); err != nil {
+		return nil, err
+	}
+
+	if err := s.groups.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("group %s is provisioned by organization %s", group.Id, organization)
+
+	return toScimGroup(group), nil
+}
+
+// ReplaceGroup replaces the attributes and the members of a group of the
+// organization
+func (s *ScimService) ReplaceGroup(ctx context.Context, organization string, id string, g *app.ScimGroup) (*app.ScimGroup, error) {
+	group, err := s.groups.GetGroup(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyGroup(ctx, group, g); err != nil {
+		return nil, err
+	}
+
+	group.UpdatedAt = time.Now()
+
+	if err := s.groups.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return toScimGroup(group), nil
+}
+
+// PatchGroup applies the operations to the group resource
+func (s *ScimService) PatchGroup(ctx context.Context, organization string, id string, r *app.ScimPatchRequest) (*app.ScimGroup, error) {
+	group, err := s.groups.GetGroup(ctx, organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range r.Operations {
+		if err := patchScimGroup(group, op); err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.TrimSpace(group.DisplayName) == "" {
+		return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "displayName is required")
+	}
+
+	if err := s.verifyMembers(ctx, organization, group.Members); err != nil {
+		return nil, err
+	}
+
+	group.UpdatedAt = time.Now()
+
+	if err := s.groups.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return toScimGroup(group), nil
+}
+
+// DeleteGroup deletes a group of the organization
+func (s *ScimService) DeleteGroup(ctx context.Context, organization string, id string) error {
+	if err := s.groups.DeleteGroup(ctx, organization, id); err != nil {
+		return err
+	}
+
+	s.logger.Infof("group %s is deleted by organization %s", id, organization)
+
+	return nil
+}
+
+// getUser returns the user unless it belongs to another organization
+func (s *ScimService) getUser(ctx context.Context, organization string, id string) (*model.User, error) {
+	user, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, app.ErrUserNotFound) || errors.Is(err, app.ErrInvalidUserId) {
+			return nil, app.NewScimError(http.StatusNotFound, "", app.ErrUserNotFound.Error())
+		}
+
+		return nil, err
+	}
+
+	if user.Organization != organization {
+		return nil, app.NewScimError(http.StatusNotFound, "", app.ErrUserNotFound.Error())
+	}
+
+	return user, nil
+}
+
+// updateUser applies the resource to the user. Deactivating the user revokes
+// the issued tokens, so they stay invalid after a reactivation.
+func (s *ScimService) updateUser(ctx context.Context, user *model.User, u *app.ScimUser) (*app.ScimUser, error) {
+	disabled := user.Disabled
+
+	if err := s.applyUser(ctx, user, u); err != nil {
+		return nil, err
+	}
+
+	id := user.GetIdString()
+	user.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateDirectoryUser(ctx, id, user); err != nil {
+		return nil, err
+	}
+
+	if user.Disabled != disabled {
+		if err := s.repo.SetUserDisabled(ctx, id, user.Disabled); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Disabled && !disabled {
+		if err := s.repo.IncrementTokenGeneration(ctx, id); err != nil {
+			return nil, err
+		}
+
+		s.logger.Infof("user %s is deactivated by organization %s", id, user.Organization)
+	}
+
+	return toScimUser(user), nil
+}
+
+// applyUser sets the attributes of the resource to the user. The email has to
+// be unique across all users, so that an organization can not take over an
+// existing account.
+func (s *ScimService) applyUser(ctx context.Context, user *model.User, u *app.ScimUser) error {
+	email := strings.TrimSpace(u.UserName)
+
+	if err := app.Validate(&struct {
+		UserName string `validate:"required,email,lte=100"`
+	}{email}); err != nil {
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, err.Error())
+	}
+
+	if email != user.Email {
+		existing, err := s.repo.GetUserByEmail(ctx, email)
+		if err == nil && existing.Id != user.Id {
+			return app.NewScimError(http.StatusConflict, app.ScimErrUniqueness, "userName is already in use")
+		}
+
+		if err != nil && !errors.Is(err, app.ErrUserNotFound) {
+			return err
+		}
+
+		user.Email = email
+		user.EmailVerified = false
+	}
+
+	if u.Name != nil {
+		user.FirstName = truncate(u.Name.GivenName, maxNameLength)
+		user.LastName = truncate(u.Name.FamilyName, maxNameLength)
+	}
+
+	if u.Active != nil {
+		user.Disabled = !*u.Active
+	}
+
+	user.ExternalId = u.ExternalId
+
+	return nil
+}
+
+// applyGroup sets the attributes and the members of the resource to the group
+func (s *ScimService) applyGroup(ctx context.Context, group *model.ScimGroup, g *app.ScimGroup) error {
+	if strings.TrimSpace(g.DisplayName) == "" {
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "displayName is required")
+	}
+
+	members := make([]string, 0, len(g.Members))
+	for _, m := range g.Members {
+		members = appendUnique(members, m.Value)
+	}
+
+	if err := s.verifyMembers(ctx, group.Organization, members); err != nil {
+		return err
+	}
+
+	group.DisplayName = g.DisplayName
+	group.ExternalId = g.ExternalId
+	group.Members = members
+
+	return nil
+}
+
+// verifyMembers checks that the members are the users of the organization
+func (s *ScimService) verifyMembers(ctx context.Context, organization string, members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	users, err := s.repo.GetUsersByIds(ctx, members)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool, len(users))
+	for _, u := range users {
+		if u.Organization == organization {
+			found[u.GetIdString()] = true
+		}
+	}
+
+	for _, m := range members {
+		if !found[m] {
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, fmt.Sprintf("member %s is not a user of the organization", m))
+		}
+	}
+
+	return nil
+}
+
+// page returns the offset and the limit of the request
+func (s *ScimService) page(r *app.ScimListRequest) (int, int) {
+	offset := 0
+	if r.StartIndex > 1 {
+		offset = r.StartIndex - 1
+	}
+
+	limit := s.config.Scim.MaxResults
+	if r.Count != nil && *r.Count < limit {
+		limit = *r.Count
+	}
+
+	if limit < 0 {
+		limit = 0
+	}
+
+	return offset, limit
+}
+
+// parseScimFilter parses a filter of `attribute eq "value"` form. The other
+// operators and the logical expressions are not supported.
+func parseScimFilter(filter string) (string, string, error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+
+	m := scimFilterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidFilter, `only the filters of attribute eq "value" form are supported`)
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(m[2]), &value); err != nil {
+		return "", "", app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidFilter, "invalid value in filter")
+	}
+
+	return m[1], value, nil
+}
+
+// patchScimDocument applies an operation to the JSON document of a user
+func patchScimDocument(doc map[string]interface{}, op app.ScimPatchOperation) error {
+	value, err := scimPatchValue(op)
+	if err != nil {
+		return err
+	}
+
+	path := scimPatchPath(op.Path, app.ScimSchemaUser)
+	if strings.ContainsAny(path, "[:") {
+		return nil
+	}
+
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if path != "" {
+			setScimAttribute(doc, path, value)
+			return nil
+		}
+
+		attrs, ok := value.(map[string]interface{})
+		if !ok {
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "value of an operation without a path has to be an object")
+		}
+
+		for k, v := range attrs {
+			if p := scimPatchPath(k, app.ScimSchemaUser); !strings.ContainsAny(p, "[:") {
+				setScimAttribute(doc, p, v)
+			}
+		}
+	case "remove":
+		if path == "" {
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrNoTarget, "path is required to remove an attribute")
+		}
+
+		removeScimAttribute(doc, path)
+	default:
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidSyntax, fmt.Sprintf("unsupported operation: %s", op.Op))
+	}
+
+	return nil
+}
+
+// patchScimGroup applies an operation to the group
+func patchScimGroup(group *model.ScimGroup, op app.ScimPatchOperation) error {
+	value, err := scimPatchValue(op)
+	if err != nil {
+		return err
+	}
+
+	path := scimPatchPath(op.Path, app.ScimSchemaGroup)
+	name := strings.ToLower(op.Op)
+
+	switch name {
+	case "add", "replace":
+		if path != "" {
+			return setScimGroupAttribute(group, name, path, value)
+		}
+
+		attrs, ok := value.(map[string]interface{})
+		if !ok {
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "value of an operation without a path has to be an object")
+		}
+
+		for k, v := range attrs {
+			if err := setScimGroupAttribute(group, name, scimPatchPath(k, app.ScimSchemaGroup), v); err != nil {
+				return err
+			}
+		}
+	case "remove":
+		if m := scimMemberPathPattern.FindStringSubmatch(path); m != nil {
+			var id string
+			if err := json.Unmarshal([]byte(m[1]), &id); err != nil {
+				return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidPath, "invalid member in path")
+			}
+
+			group.Members = removeAll(group.Members, id)
+			return nil
+		}
+
+		switch strings.ToLower(path) {
+		case "":
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrNoTarget, "path is required to remove an attribute")
+		case "members":
+			if value == nil {
+				group.Members = []string{}
+				return nil
+			}
+
+			ids, err := scimMemberIds(value)
+			if err != nil {
+				return err
+			}
+
+			group.Members = removeAll(group.Members, ids...)
+		case "externalid":
+			group.ExternalId = ""
+		case "displayname":
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "displayName is required")
+		default:
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidPath, fmt.Sprintf("unsupported path: %s", op.Path))
+		}
+	default:
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidSyntax, fmt.Sprintf("unsupported operation: %s", op.Op))
+	}
+
+	return nil
+}
+
+// setScimGroupAttribute adds or replaces an attribute of the group. The
+// members are appended by the add operation.
+func setScimGroupAttribute(group *model.ScimGroup, op string, attr string, value interface{}) error {
+	switch strings.ToLower(attr) {
+	case "id":
+	case "displayname", "externalid":
+		v, ok := value.(string)
+		if !ok {
+			return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, fmt.Sprintf("%s has to be a string", attr))
+		}
+
+		if strings.EqualFold(attr, "displayName") {
+			group.DisplayName = v
+		} else {
+			group.ExternalId = v
+		}
+	case "members":
+		ids, err := scimMemberIds(value)
+		if err != nil {
+			return err
+		}
+
+		if op == "replace" {
+			group.Members = make([]string, 0, len(ids))
+		}
+
+		group.Members = appendUnique(group.Members, ids...)
+	default:
+		return app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidPath, fmt.Sprintf("unsupported path: %s", attr))
+	}
+
+	return nil
+}
+
+// scimPatchValue decodes the value of the operation
+func scimPatchValue(op app.ScimPatchOperation) (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "invalid value")
+	}
+
+	return value, nil
+}
+
+// scimPatchPath trims the schema prefix of the path
+func scimPatchPath(path string, schema string) string {
+	path = strings.TrimSpace(path)
+	if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+		return path[len(schema)+1:]
+	}
+
+	return path
+}
+
+// scimMemberIds returns the ids of the members in the value
+func scimMemberIds(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		m, _ := item.(map[string]interface{})
+		id, ok := m["value"].(string)
+		if !ok || id == "" {
+			return nil, app.NewScimError(http.StatusBadRequest, app.ScimErrInvalidValue, "members have to have a value")
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// setScimAttribute sets the attribute at the dotted path. Objects are merged
+// into the existing ones.
+func setScimAttribute(doc map[string]interface{}, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		key := scimAttributeKey(doc, p)
+
+		next, ok := doc[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			doc[key] = next
+		}
+
+		doc = next
+	}
+
+	key := scimAttributeKey(doc, parts[len(parts)-1])
+	if attrs, ok := value.(map[string]interface{}); ok {
+		if current, ok := doc[key].(map[string]interface{}); ok {
+			for k, v := range attrs {
+				setScimAttribute(current, k, v)
+			}
+
+			return
+		}
+	}
+
+	doc[key] = value
+}
+
+// removeScimAttribute removes the attribute at the dotted path
+func removeScimAttribute(doc map[string]interface{}, path string) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := doc[scimAttributeKey(doc, p)].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		doc = next
+	}
+
+	delete(doc, scimAttributeKey(doc, parts[len(parts)-1]))
+}
+
+// scimAttributeKey returns the key of the attribute in the document
+func scimAttributeKey(doc map[string]interface{}, name string) string {
+	for k := range doc {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+
+	for _, a := range scimUserAttributes {
+		if strings.EqualFold(a, name) {
+			return a
+		}
+	}
+
+	return name
+}
+
+func toScimUser(u *model.User) *app.ScimUser {
+	active := !u.Disabled
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+
+	return &app.ScimUser{
+		Schemas:     []string{app.ScimSchemaUser},
+		Id:          u.GetIdString(),
+		ExternalId:  u.ExternalId,
+		UserName:    u.Email,
+		Name:        &app.ScimName{Formatted: name, GivenName: u.FirstName, FamilyName: u.LastName},
+		DisplayName: name,
+		Emails:      []app.ScimEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &app.ScimMeta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+		},
+	}
+}
+
+func toScimGroup(g *model.ScimGroup) *app.ScimGroup {
+	members := make([]app.ScimMember, 0, len(g.Members))
+	for _, m := range g.Members {
+		members = append(members, app.ScimMember{Value: m})
+	}
+
+	return &app.ScimGroup{
+		Schemas:     []string{app.ScimSchemaGroup},
+		Id:          g.Id,
+		ExternalId:  g.ExternalId,
+		DisplayName: g.DisplayName,
+		Members:     members,
+		Meta: &app.ScimMeta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+		},
+	}
+}
+
+func scimListResponse(total int, offset int, count int, resources interface{}) *app.ScimListResponse {
+	return &app.ScimListResponse{
+		Schemas:      []string{app.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: count,
+		Resources:    resources,
+	}
+}
+
+// appendUnique appends the values which are not in the list
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// removeAll returns the list without the values
+func removeAll(list []string, values ...string) []string {
+	res := make([]string, 0, len(list))
+	for _, v := range list {
+		if !contains(values, v) {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,551 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scimErrorCode returns the status code of a SCIM error, or zero
+func scimErrorCode(err error) int {
+	var e *app.ScimError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return 0
+}
+
+func scimPatchForTesting(t *testing.T, ops ...map[string]interface{}) *app.ScimPatchRequest {
+	b, err := json.Marshal(map[string]interface{}{
+		"schemas":    []string{app.ScimSchemaPatchOp},
+		"Operations": ops,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &app.ScimPatchRequest{}
+	if err := json.Unmarshal(b, r); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestScimService_Authenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	var created *model.ScimToken
+	tokens := mock.NewMockScimTokenRepository(ctrl)
+	tokens.EXPECT().CreateToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, token *model.ScimToken) error {
+			created = token
+			return nil
+		})
+	tokens.EXPECT().GetToken(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.ScimToken, error) {
+			if created != nil && created.Id == id {
+				return created, nil
+			}
+			return nil, app.ErrScimTokenNotFound
+		}).AnyTimes()
+
+	service := NewScimService(config.New(), NewLoggerMock(), nil, tokens, nil)
+
+	res, err := service.CreateToken(ctx, &app.CreateScimTokenRequest{Organization: "acme", Name: "Okta"})
+	if err != nil {
+		t.Fatalf("ScimService.CreateToken() error = %v", err)
+	}
+
+	if created.Secret == "" || created.Secret == res.Token {
+		t.Fatalf("ScimService.CreateToken() stored secret = %q", created.Secret)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantOrg string
+		wantErr bool
+	}{
+		{
+			name:    "should return the organization of the token",
+			token:   res.Token,
+			wantOrg: "acme",
+		},
+		{
+			name:    "should fail when secret is wrong",
+			token:   created.Id + ".secret",
+			wantErr: true,
+		},
+		{
+			name:    "should fail when token is unknown",
+			token:   "unknown." + created.Id,
+			wantErr: true,
+		},
+		{
+			name:    "should fail when token is malformed",
+			token:   created.Id,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Authenticate(ctx, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScimService.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.wantOrg {
+				t.Errorf("ScimService.Authenticate() = %v, want %v", got, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestScimService_CreateToken(t *testing.T) {
+	service := NewScimService(config.New(), NewLoggerMock(), nil, nil, nil)
+
+	for _, r := range []*app.CreateScimTokenRequest{
+		{Organization: "acme"},
+		{Organization: "Acme Corp", Name: "Okta"},
+	} {
+		if _, err := service.CreateToken(context.Background(), r); err == nil {
+			t.Errorf("ScimService.CreateToken(%+v) error = nil, want error", r)
+		}
+	}
+}
+
+func TestScimService_CreateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	uid := primitive.NewObjectID()
+	active := false
+
+	tests := []struct {
+		name       string
+		user       *app.ScimUser
+		repo       func(repo *mock.MockRepository)
+		wantActive bool
+		wantCode   int
+	}{
+		{
+			name: "should provision the user of the organization",
+			user: &app.ScimUser{UserName: "jane@acme.com", ExternalId: "00u1", Name: &app.ScimName{GivenName: "Jane", FamilyName: "Doe"}},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByEmail(ctx, "jane@acme.com").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().CreateUser(ctx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (string, error) {
+						if u.Organization != "acme" || u.ExternalId != "00u1" || u.FirstName != "Jane" || u.LastName != "Doe" ||
+							u.EmailVerified || u.Password != "" || u.Role != model.RoleUser || u.Disabled {
+							t.Errorf("Repository.CreateUser() user = %+v", u)
+						}
+						return uid.Hex(), nil
+					})
+			},
+			wantActive: true,
+		},
+		{
+			name: "should provision an inactive user as disabled",
+			user: &app.ScimUser{UserName: "jane@acme.com", Active: &active},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByEmail(ctx, "jane@acme.com").Return(nil, app.ErrUserNotFound)
+				repo.EXPECT().CreateUser(ctx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (string, error) {
+						if !u.Disabled {
+							t.Errorf("Repository.CreateUser() user is not disabled")
+						}
+						return uid.Hex(), nil
+					})
+			},
+		},
+		{
+			name: "should fail when email is in use",
+			user: &app.ScimUser{UserName: "jane@acme.com"},
+			repo: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetUserByEmail(ctx, "jane@acme.com").Return(&model.User{Id: primitive.NewObjectID()}, nil)
+			},
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "should fail when userName is not an email",
+			user:     &app.ScimUser{UserName: "jane"},
+			repo:     func(repo *mock.MockRepository) {},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mock.NewMockRepository(ctrl)
+			tt.repo(repo)
+
+			service := NewScimService(config.New(), NewLoggerMock(), repo, nil, nil)
+
+			got, err := service.CreateUser(ctx, "acme", tt.user)
+			if code := scimErrorCode(err); code != tt.wantCode {
+				t.Fatalf("ScimService.CreateUser() error = %v, want code %d", err, tt.wantCode)
+			}
+
+			if tt.wantCode != 0 {
+				return
+			}
+
+			if got.Id != uid.Hex() || got.UserName != tt.user.UserName || *got.Active != tt.wantActive {
+				t.Errorf("ScimService.CreateUser() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestScimService_GetUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	c := config.New()
+	c.Scim.MaxResults = 10
+
+	count := 50
+	zero := 0
+
+	tests := []struct {
+		name      string
+		req       *app.ScimListRequest
+		wantQuery *app.UserQuery
+		wantCode  int
+	}{
+		{
+			name:      "should return the first page of the users of the organization",
+			req:       &app.ScimListRequest{},
+			wantQuery: &app.UserQuery{Organization: "acme", Limit: 10},
+		},
+		{
+			name:      "should filter by userName",
+			req:       &app.ScimListRequest{Filter: `userName eq "jane@acme.com"`},
+			wantQuery: &app.UserQuery{Organization: "acme", Email: "jane@acme.com", Limit: 10},
+		},
+		{
+			name:      "should filter by externalId case insensitively",
+			req:       &app.ScimListRequest{Filter: `externalid EQ "00u\"1"`, StartIndex: 3, Count: &zero},
+			wantQuery: &app.UserQuery{Organization: "acme", ExternalId: `00u"1`, Offset: 2},
+		},
+		{
+			name:      "should limit the count to the maximum number of results",
+			req:       &app.ScimListRequest{StartIndex: 11, Count: &count},
+			wantQuery: &app.UserQuery{Organization: "acme", Offset: 10, Limit: 10},
+		},
+		{
+			name:     "should fail when operator is not supported",
+			req:      &app.ScimListRequest{Filter: `userName sw "jane"`},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "should fail when attribute is not supported",
+			req:      &app.ScimListRequest{Filter: `title eq "Driver"`},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{Id: primitive.NewObjectID(), Email: "jane@acme.com", Organization: "acme"}
+
+			repo := mock.NewMockRepository(ctrl)
+			if tt.wantQuery != nil {
+				repo.EXPECT().FindUsers(ctx, tt.wantQuery).Return([]*model.User{user}, 21, nil)
+			}
+
+			service := NewScimService(c, NewLoggerMock(), repo, nil, nil)
+
+			got, err := service.GetUsers(ctx, "acme", tt.req)
+			if code := scimErrorCode(err); code != tt.wantCode {
+				t.Fatalf("ScimService.GetUsers() error = %v, want code %d", err, tt.wantCode)
+			}
+
+			if tt.wantCode != 0 {
+				return
+			}
+
+			resources := got.Resources.([]*app.ScimUser)
+			if got.TotalResults != 21 || got.StartIndex != tt.wantQuery.Offset+1 || got.ItemsPerPage != 1 || resources[0].Id != user.GetIdString() {
+				t.Errorf("ScimService.GetUsers() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestScimService_PatchUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		user           *model.User
+		ops            []map[string]interface{}
+		want           func(u *model.User) bool
+		wantRevocation bool
+		wantCode       int
+	}{
+		{
+			name: "should deactivate the user and revoke the tokens",
+			user: &model.User{Email: "jane@acme.com", Organization: "acme"},
+			ops:  []map[string]interface{}{{"op": "Replace", "path": "active", "value": "False"}},
+			want: func(u *model.User) bool {
+				return u.Disabled && u.Email == "jane@acme.com"
+			},
+			wantRevocation: true,
+		},
+		{
+			name: "should deactivate the user by a value without a path",
+			user: &model.User{Email: "jane@acme.com", Organization: "acme"},
+			ops:  []map[string]interface{}{{"op": "replace", "value": map[string]interface{}{"active": false}}},
+			want: func(u *model.User) bool {
+				return u.Disabled
+			},
+			wantRevocation: true,
+		},
+		{
+			name: "should reactivate the user",
+			user: &model.User{Email: "jane@acme.com", Organization: "acme", Disabled: true},
+			ops:  []map[string]interface{}{{"op": "replace", "path": "active", "value": true}},
+			want: func(u *model.User) bool {
+				return !u.Disabled
+			},
+		},
+		{
+			name: "should update the name and ignore the unmapped attributes",
+			user: &model.User{Email: "jane@acme.com", FirstName: "Jane", LastName: "Doe", ExternalId: "00u1", Organization: "acme"},
+			ops: []map[string]interface{}{
+				{"op": "replace", "path": "name.familyName", "value": "Roe"},
+				{"op": "add", "value": map[string]interface{}{"name.givenName": "Janet", "title": "Driver"}},
+				{"op": "replace", "path": `emails[type eq "work"].value`, "value": "janet@acme.com"},
+				{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Ops"},
+				{"op": "remove", "path": "externalId"},
+			},
+			want: func(u *model.User) bool {
+				return u.FirstName == "Janet" && u.LastName == "Roe" && u.Email == "jane@acme.com" && u.ExternalId == "" && !u.Disabled
+			},
+		},
+		{
+			name:     "should fail when user belongs to another organization",
+			user:     &model.User{Email: "john@globex.com", Organization: "globex"},
+			ops:      []map[string]interface{}{{"op": "replace", "path": "active", "value": false}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "should fail when operation is unknown",
+			user:     &model.User{Email: "jane@acme.com", Organization: "acme"},
+			ops:      []map[string]interface{}{{"op": "move", "path": "active"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "should fail when userName is removed",
+			user:     &model.User{Email: "jane@acme.com", Organization: "acme"},
+			ops:      []map[string]interface{}{{"op": "remove", "path": "userName"}},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.user.Id = primitive.NewObjectID()
+			id := tt.user.GetIdString()
+
+			// stored is the user in the repository, which is updated only
+			// by the fields of the update operations
+			stored := *tt.user
+
+			repo := mock.NewMockRepository(ctrl)
+			repo.EXPECT().GetUser(ctx, id).Return(tt.user, nil)
+
+			if tt.want != nil {
+				repo.EXPECT().UpdateDirectoryUser(ctx, id, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, u *model.User) error {
+						stored.FirstName, stored.LastName = u.FirstName, u.LastName
+						stored.Email, stored.EmailVerified, stored.ExternalId = u.Email, u.EmailVerified, u.ExternalId
+						return nil
+					})
+				repo.EXPECT().SetUserDisabled(ctx, id, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, disabled bool) error {
+						stored.Disabled = disabled
+						return nil
+					}).MaxTimes(1)
+			}
+
+			if tt.wantRevocation {
+				repo.EXPECT().IncrementTokenGeneration(ctx, id).Return(nil)
+			}
+
+			service := NewScimService(config.New(), NewLoggerMock(), repo, nil, nil)
+
+			got, err := service.PatchUser(ctx, "acme", id, scimPatchForTesting(t, tt.ops...))
+			if code := scimErrorCode(err); code != tt.wantCode {
+				t.Fatalf("ScimService.PatchUser() error = %v, want code %d", err, tt.wantCode)
+			}
+
+			if tt.wantCode != 0 {
+				return
+			}
+
+			if got.Id != id || *got.Active == tt.user.Disabled {
+				t.Errorf("ScimService.PatchUser() = %+v", got)
+			}
+
+			if !tt.want(&stored) {
+				t.Errorf("ScimService.PatchUser() stored user = %+v", stored)
+			}
+		})
+	}
+}
+
+func TestScimService_DeleteUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	user := &model.User{Id: primitive.NewObjectID(), Organization: "acme"}
+	other := &model.User{Id: primitive.NewObjectID(), Organization: "globex"}
+
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetUser(ctx, user.GetIdString()).Return(user, nil)
+	repo.EXPECT().GetUser(ctx, other.GetIdString()).Return(other, nil)
+	repo.EXPECT().GetUser(ctx, "invalid").Return(nil, app.ErrInvalidUserId)
+	gomock.InOrder(
+		repo.EXPECT().ScheduleDeletion(ctx, user.GetIdString(), gomock.Not(gomock.Nil())).Return(nil),
+		repo.EXPECT().AnonymizeUser(ctx, user.GetIdString()).Return(nil),
+	)
+
+	groups := mock.NewMockScimGroupRepository(ctrl)
+	groups.EXPECT().RemoveMember(ctx, "acme", user.GetIdString()).Return(nil)
+
+	service := NewScimService(config.New(), NewLoggerMock(), repo, nil, groups)
+
+	if err := service.DeleteUser(ctx, "acme", user.GetIdString()); err != nil {
+		t.Errorf("ScimService.DeleteUser() error = %v", err)
+	}
+
+	for _, id := range []string{other.GetIdString(), "invalid"} {
+		if err := service.DeleteUser(ctx, "acme", id); scimErrorCode(err) != http.StatusNotFound {
+			t.Errorf("ScimService.DeleteUser(%s) error = %v, want not found", id, err)
+		}
+	}
+}
+
+func TestScimService_PatchGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	jane := &model.User{Id: primitive.NewObjectID(), Organization: "acme"}
+	john := &model.User{Id: primitive.NewObjectID(), Organization: "acme"}
+	other := &model.User{Id: primitive.NewObjectID(), Organization: "globex"}
+
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetUsersByIds(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, ids []string) ([]*model.User, error) {
+			var users []*model.User
+			for _, u := range []*model.User{jane, john, other} {
+				if contains(ids, u.GetIdString()) {
+					users = append(users, u)
+				}
+			}
+			return users, nil
+		}).AnyTimes()
+
+	tests := []struct {
+		name        string
+		ops         []map[string]interface{}
+		wantName    string
+		wantMembers []string
+		wantCode    int
+	}{
+		{
+			name: "should add the members",
+			ops: []map[string]interface{}{
+				{"op": "Add", "path": "members", "value": []map[string]string{{"value": john.GetIdString()}, {"value": jane.GetIdString()}}},
+			},
+			wantName:    "Drivers",
+			wantMembers: []string{jane.GetIdString(), john.GetIdString()},
+		},
+		{
+			name: "should remove a member by the path",
+			ops: []map[string]interface{}{
+				{"op": "remove", "path": `members[value eq "` + jane.GetIdString() + `"]`},
+			},
+			wantName:    "Drivers",
+			wantMembers: []string{},
+		},
+		{
+			name: "should replace the attributes without a path",
+			ops: []map[string]interface{}{
+				{"op": "replace", "value": map[string]interface{}{"id": "g1", "displayName": "Dispatchers", "members": []map[string]string{{"value": john.GetIdString()}}}},
+			},
+			wantName:    "Dispatchers",
+			wantMembers: []string{john.GetIdString()},
+		},
+		{
+			name: "should fail when member is a user of another organization",
+			ops: []map[string]interface{}{
+				{"op": "add", "path": "members", "value": []map[string]string{{"value": other.GetIdString()}}},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "should fail when path is not supported",
+			ops: []map[string]interface{}{
+				{"op": "replace", "path": "owner", "value": "jane"},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &model.ScimGroup{Id: "g1", Organization: "acme", DisplayName: "Drivers", Members: []string{jane.GetIdString()}}
+
+			groups := mock.NewMockScimGroupRepository(ctrl)
+			groups.EXPECT().GetGroup(ctx, "acme", "g1").Return(group, nil)
+			if tt.wantCode == 0 {
+				groups.EXPECT().UpdateGroup(ctx, group).Return(nil)
+			}
+
+			service := NewScimService(config.New(), NewLoggerMock(), repo, nil, groups)
+
+			got, err := service.PatchGroup(ctx, "acme", "g1", scimPatchForTesting(t, tt.ops...))
+			if code := scimErrorCode(err); code != tt.wantCode {
+				t.Fatalf("ScimService.PatchGroup() error = %v, want code %d", err, tt.wantCode)
+			}
+
+			if tt.wantCode != 0 {
+				return
+			}
+
+			members := make([]string, 0, len(got.Members))
+			for _, m := range got.Members {
+				members = append(members, m.Value)
+			}
+
+			if got.DisplayName != tt.wantName || !reflect.DeepEqual(members, tt.wantMembers) {
+				t.Errorf("ScimService.PatchGroup() = %+v, want name %s and members %v", got, tt.wantName, tt.wantMembers)
+			}
+		})
+	}
+}
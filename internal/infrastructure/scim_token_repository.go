@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ScimTokenRepository struct {
+	app.ScimTokenRepository
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewScimTokenRepository(config *config.Config, logger logger.ILogger, db *mongo.Client) *ScimTokenRepository {
+	return &ScimTokenRepository{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.ScimTokenCollectionName),
+	}
+}
+
+// GetToken returns a scim token by id
+func (r *ScimTokenRepository) GetToken(ctx context.Context, id string) (*model.ScimToken, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	t := &model.ScimToken{}
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(t); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrScimTokenNotFound
+		}
+
+		r.logger.Warnf("error while finding scim token: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding scim token"))
+	}
+
+	return t, nil
+}
+
+// GetTokens returns all scim tokens
+func (r *ScimTokenRepository) GetTokens(ctx context.Context) ([]*model.ScimToken, error) {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		r.logger.Warnf("error while getting scim tokens: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while finding scim tokens"))
+	}
+
+	tokens := make([]*model.ScimToken, 0)
+	if err := res.All(ctx, &tokens); err != nil {
+		r.logger.Warnf("error while decoding scim tokens: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while decoding scim tokens"))
+	}
+
+	return tokens, nil
+}
+
+// CreateToken creates a new scim token
+func (r *ScimTokenRepository) CreateToken(ctx context.Context, token *model.ScimToken) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.InsertOne(ctx, token); err != nil {
+		r.logger.Warnf("error while creating scim token: %s", err)
+		return app.NewInternalServerError(errors.New("error while creating scim token"))
+	}
+
+	return nil
+}
+
+// DeleteToken deletes a scim token by id
+func (r *ScimTokenRepository) DeleteToken(ctx context.Context, id string) error {
+	ctx, cancel := r.contextWithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Warnf("error while deleting scim token: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting scim token"))
+	}
+
+	if res.DeletedCount == 0 {
+		return app.ErrScimTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *ScimTokenRepository) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.config.Mongo.SocketTimeout)*time.Second)
+}
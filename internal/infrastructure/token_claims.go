@@ -1,9 +1,97 @@
 package infrastructure
 
-import "github.com/golang-jwt/jwt"
+import (
+	"encoding/json"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+)
+
+// registeredClaims are the claims which are set by the token service and
+// cannot be overridden by the custom claims
+var registeredClaims = map[string]bool{
+	"aud": true, "exp": true, "jti": true, "iat": true, "iss": true, "nbf": true, "sub": true,
+	"role": true, "gen": true, "scope": true, "token_type": true, "act": true, "email_verified": true,
+	"client_id": true,
+}
 
 type Claims struct {
-	Role string `json:"role,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Generation int    `json:"gen,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	// TokenType is only set for the client tokens
+	TokenType string `json:"token_type,omitempty"`
+	// Actor is only set for the delegated tokens
+	Actor *app.Actor `json:"act,omitempty"`
+	// ClientId is the OAuth client which the token is issued to, if any
+	ClientId string `json:"client_id,omitempty"`
+	// EmailVerified is only set for the users with an email
+	EmailVerified *bool `json:"email_verified,omitempty"`
+	// Custom holds the claims added by the claims enrichers
+	Custom map[string]interface{} `json:"-"`
+	jwt.StandardClaims
+}
+
+// plainClaims is used to encode Claims without its custom claims
+type plainClaims Claims
+
+// MarshalJSON encodes the custom claims next to the registered claims
+func (c Claims) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(plainClaims(c))
+	if err != nil || len(c.Custom) == 0 {
+		return b, err
+	}
+
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	for k, v := range c.Custom {
+		if !registeredClaims[k] {
+			m[k] = v
+		}
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the claims which are not registered into the custom claims
+func (c *Claims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*plainClaims)(c)); err != nil {
+		return err
+	}
+
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	for k, v := range m {
+		if registeredClaims[k] {
+			continue
+		}
+
+		if c.Custom == nil {
+			c.Custom = make(map[string]interface{})
+		}
+
+		c.Custom[k] = v
+	}
+
+	return nil
+}
+
+// IdTokenClaims are the claims of an OpenID Connect ID token
+type IdTokenClaims struct {
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -23,6 +111,45 @@ func (c *Claims) GetAudience() string {
 	return c.StandardClaims.Audience
 }
 
+func (c *Claims) GetScope() string {
+	return c.Scope
+}
+
+// GetTokenType returns the type of the token. Tokens without the claim are
+// user tokens.
+func (c *Claims) GetTokenType() string {
+	if c.TokenType == "" {
+		return app.AccessTokenTypeUser
+	}
+
+	return c.TokenType
+}
+
+// GetExpiresAt returns the expiration time of the token
+func (c *Claims) GetExpiresAt() int64 {
+	return c.ExpiresAt
+}
+
+// GetActor returns the actor of a delegated token
+func (c *Claims) GetActor() *app.Actor {
+	return c.Actor
+}
+
+// GetClientId returns the client which the token is issued to. The subject
+// of a client token is the client itself.
+func (c *Claims) GetClientId() string {
+	if c.ClientId == "" && c.GetTokenType() == app.AccessTokenTypeClient {
+		return c.Subject
+	}
+
+	return c.ClientId
+}
+
+// GetClaim returns a custom claim
+func (c *Claims) GetClaim(name string) interface{} {
+	return c.Custom[name]
+}
+
 func (c *Claims) GetTokenId() string {
 	return c.StandardClaims.Id
 }
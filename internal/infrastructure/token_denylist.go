@@ -0,0 +1,130 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoTokenDenylist struct {
+	app.TokenDenylist
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoTokenDenylist(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoTokenDenylist {
+	return &MongoTokenDenylist{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.DenylistCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the denylist. Expired tokens are
+// removed by mongo through the ttl index on expires_at.
+func (d *MongoTokenDenylist) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := d.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := d.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		d.logger.Warnf("error while creating token denylist indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Add denies the token until it expires. Adding a token twice is not an error.
+func (d *MongoTokenDenylist) Add(ctx context.Context, token *model.RevokedToken) error {
+	ctx, cancel := d.contextWithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$setOnInsert": bson.M{
+		"expires_at": token.ExpiresAt,
+		"revoked_at": token.RevokedAt,
+	}}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := d.db.UpdateOne(ctx, bson.M{"_id": token.Id}, update, opts); err != nil {
+		d.logger.Warnf("error while adding token to denylist: %s", err)
+		return app.NewInternalServerError(errors.New("error while revoking token"))
+	}
+
+	return nil
+}
+
+// Contains returns true if the token is denied
+func (d *MongoTokenDenylist) Contains(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := d.contextWithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}
+
+	n, err := d.db.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		d.logger.Warnf("error while checking token denylist: %s", err)
+		return false, app.NewInternalServerError(errors.New("error while checking token"))
+	}
+
+	return n > 0, nil
+}
+
+func (d *MongoTokenDenylist) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(d.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryTokenDenylist struct {
+	app.TokenDenylist
+	mu     sync.RWMutex
+	tokens map[string]*model.RevokedToken
+}
+
+func NewInMemoryTokenDenylist() *InMemoryTokenDenylist {
+	return &InMemoryTokenDenylist{
+		tokens: make(map[string]*model.RevokedToken),
+	}
+}
+
+// Add denies the token until it expires and drops the expired ones
+func (d *InMemoryTokenDenylist) Add(ctx context.Context, token *model.RevokedToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, t := range d.tokens {
+		if t.IsExpired() {
+			delete(d.tokens, id)
+		}
+	}
+
+	if _, ok := d.tokens[token.Id]; !ok {
+		t := *token
+		d.tokens[token.Id] = &t
+	}
+
+	return nil
+}
+
+// Contains returns true if the token is denied
+func (d *InMemoryTokenDenylist) Contains(ctx context.Context, id string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	t, ok := d.tokens[id]
+
+	return ok && !t.IsExpired(), nil
+}
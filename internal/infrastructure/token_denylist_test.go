@@ -0,0 +1,57 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+)
+
+func TestInMemoryTokenDenylist(t *testing.T) {
+	ctx := context.Background()
+	d := NewInMemoryTokenDenylist()
+
+	d.Add(ctx, &model.RevokedToken{Id: "1", ExpiresAt: time.Now().Add(time.Hour)})
+	d.Add(ctx, &model.RevokedToken{Id: "2", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{
+			name: "should contain revoked token",
+			id:   "1",
+			want: true,
+		},
+		{
+			name: "should not contain expired token",
+			id:   "2",
+		},
+		{
+			name: "should not contain unknown token",
+			id:   "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.Contains(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("InMemoryTokenDenylist.Contains() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("InMemoryTokenDenylist.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("should drop expired tokens on add", func(t *testing.T) {
+		d.Add(ctx, &model.RevokedToken{Id: "3", ExpiresAt: time.Now().Add(time.Hour)})
+
+		if _, ok := d.tokens["2"]; ok {
+			t.Errorf("InMemoryTokenDenylist.Add() kept expired token")
+		}
+	})
+}
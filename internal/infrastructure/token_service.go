@@ -2,9 +2,15 @@ package infrastructure
 
 import (
 	"context"
-	"crypto/rsa"
-	"io/ioutil"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -15,19 +21,33 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Header types of the issued tokens. Refresh tokens are typed so that they are
+// never accepted as access tokens, even when both keyrings share a key. ID
+// tokens are signed with the access token keys, so they are typed as well.
+const (
+	accessTokenHeaderType  = "JWT"
+	refreshTokenHeaderType = "rt+jwt"
+	idTokenHeaderType      = "id+jwt"
+)
+
 type TokenService struct {
-	config                 *config.Config
-	logger                 logger.ILogger
-	accessTokenPrivateKey  *rsa.PrivateKey
-	accessTokenPublicKey   *rsa.PublicKey
-	refreshTokenPrivateKey *rsa.PrivateKey
-	refreshTokenPublicKey  *rsa.PublicKey
+	config              *config.Config
+	logger              logger.ILogger
+	accessTokenKeyring  *keyring
+	refreshTokenKeyring *keyring
+	repo                app.Repository
+	refreshTokenStore   app.RefreshTokenStore
+	denylist            app.TokenDenylist
+	claimsEnrichers     func() []app.ClaimsEnricher
 }
 
-func NewTokenService(config *config.Config, logger logger.ILogger) (s *TokenService) {
+func NewTokenService(config *config.Config, logger logger.ILogger, repo app.Repository, rts app.RefreshTokenStore, dl app.TokenDenylist) (s *TokenService) {
 	s = &TokenService{
-		config: config,
-		logger: logger,
+		config:            config,
+		logger:            logger,
+		repo:              repo,
+		refreshTokenStore: rts,
+		denylist:          dl,
 	}
 
 	s.init()
@@ -36,63 +56,161 @@ func NewTokenService(config *config.Config, logger logger.ILogger) (s *TokenServ
 }
 
 func (s *TokenService) init() *TokenService {
-	// get private key from file
-	b, err := ioutil.ReadFile(s.config.Jwt.AccessTokenPrivateKeyFile)
+	atm, err := signingMethod(s.config.Jwt.AccessTokenAlgorithm)
 	if err != nil {
-		panic(err)
+		panic(errors.Wrap(err, "invalid access token algorithm"))
 	}
 
-	atprk, err := jwt.ParseRSAPrivateKeyFromPEM(b)
+	rtm, err := signingMethod(s.config.Jwt.RefreshTokenAlgorithm)
 	if err != nil {
-		panic(err)
+		panic(errors.Wrap(err, "invalid refresh token algorithm"))
 	}
 
-	s.accessTokenPrivateKey = atprk
+	if s.config.Jwt.AccessTokenKeyDir != "" {
+		s.accessTokenKeyring = newDirKeyring(s.config.Jwt.AccessTokenKeyDir, s.config.Jwt.AccessTokenActiveKey, atm)
+	} else {
+		s.accessTokenKeyring = newFileKeyring(s.config.Jwt.AccessTokenPrivateKeyFile, s.config.Jwt.AccessTokenPublicKeyFile, atm)
+	}
 
-	// get public key from file
-	b, err = ioutil.ReadFile(s.config.Jwt.AccessTokenPublicKeyFile)
-	if err != nil {
-		panic(err)
+	if s.config.Jwt.RefreshTokenKeyDir != "" {
+		s.refreshTokenKeyring = newDirKeyring(s.config.Jwt.RefreshTokenKeyDir, s.config.Jwt.RefreshTokenActiveKey, rtm)
+	} else {
+		s.refreshTokenKeyring = newFileKeyring(s.config.Jwt.RefreshTokenPrivateKeyFile, s.config.Jwt.RefreshTokenPublicKeyFile, rtm)
 	}
 
-	atpuk, err := jwt.ParseRSAPublicKeyFromPEM(b)
-	if err != nil {
-		panic(err)
+	if _, err := s.accessTokenKeyring.load(true); err != nil {
+		panic(errors.Wrap(err, "failed to load access token keys"))
 	}
 
-	s.accessTokenPublicKey = atpuk
+	if _, err := s.refreshTokenKeyring.load(true); err != nil {
+		panic(errors.Wrap(err, "failed to load refresh token keys"))
+	}
 
-	// get private key from file
-	b, err = ioutil.ReadFile(s.config.Jwt.RefreshTokenPrivateKeyFile)
-	if err != nil {
-		panic(err)
+	return s
+}
+
+// WatchKeys reloads the keys on SIGHUP and when the key files change, so
+// that the signing keys can be rotated without a restart
+func (t *TokenService) WatchKeys(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if t.config.Jwt.KeyReloadInterval > 0 {
+		ticker := time.NewTicker(time.Duration(t.config.Jwt.KeyReloadInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
 	}
 
-	rtprk, err := jwt.ParseRSAPrivateKeyFromPEM(b)
-	if err != nil {
-		panic(err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			t.reloadKeys(true)
+		case <-tick:
+			t.reloadKeys(false)
+		}
+	}
+}
+
+// reloadKeys reloads the keyrings and keeps the current keys on failure
+func (t *TokenService) reloadKeys(force bool) {
+	keyrings := map[string]*keyring{
+		"access token":  t.accessTokenKeyring,
+		"refresh token": t.refreshTokenKeyring,
+	}
+
+	for name, kr := range keyrings {
+		reloaded, err := kr.load(force)
+		if err != nil {
+			t.logger.Errorf("failed to reload %s keys: %s", name, err)
+			continue
+		}
+
+		if reloaded {
+			t.logger.Infof("reloaded %s keys, signing with key %s", name, kr.signingKey().jwk.Kid)
+		}
+	}
+}
+
+// GenerateAccessToken generates a new access token
+func (t *TokenService) GenerateAccessToken(ctx context.Context, user *model.User, grant *app.TokenGrant) (string, error) {
+	return t.generateAccessToken(ctx, user, grant, nil, time.Time{})
+}
+
+// GenerateDelegatedAccessToken generates an access token of the user for the
+// actor. The token expires with the token it is exchanged for at the latest.
+func (t *TokenService) GenerateDelegatedAccessToken(ctx context.Context, user *model.User, r *app.DelegatedTokenRequest) (string, error) {
+	if r.Actor == nil || r.Actor.Subject == "" {
+		return "", errors.New("actor is empty")
 	}
 
-	s.refreshTokenPrivateKey = rtprk
+	return t.generateAccessToken(ctx, user, r.Grant, r.Actor, r.NotAfter)
+}
+
+// generateAccessToken generates an access token of the user with the optional
+// actor. The expiration is capped by notAfter unless it is zero.
+func (t *TokenService) generateAccessToken(ctx context.Context, user *model.User, grant *app.TokenGrant, act *app.Actor, notAfter time.Time) (string, error) {
+	sub := user.GetIdString()
 
-	// get public key from file
-	b, err = ioutil.ReadFile(s.config.Jwt.RefreshTokenPublicKeyFile)
+	if sub == "" {
+		return "", errors.New("user id is empty")
+	}
+
+	g, err := t.ResolveGrant(ctx, user, grant)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
-	rtpuk, err := jwt.ParseRSAPublicKeyFromPEM(b)
+	custom, err := t.enrichClaims(ctx, user)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
-	s.refreshTokenPublicKey = rtpuk
+	jti, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
 
-	return s
+	now := time.Now().UTC()
+
+	exp := now.Add(time.Duration(t.config.Jwt.AccessTokenExp) * time.Second)
+	if !notAfter.IsZero() && notAfter.Before(exp) {
+		exp = notAfter
+	}
+
+	claims := Claims{
+		Role:       user.GetRole(),
+		Generation: user.TokenGeneration,
+		Scope:      g.Scope,
+		Actor:      act,
+		ClientId:   g.ClientId,
+		Custom:     custom,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    t.config.Jwt.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: exp.Unix(),
+			Subject:   sub,
+			Id:        jti,
+			Audience:  g.Audience,
+		},
+	}
+
+	// the resource servers decide what the users with an unverified email
+	// may do, unless the login is refused for them
+	if user.Email != "" {
+		verified := user.EmailVerified
+		claims.EmailVerified = &verified
+	}
+
+	return t.sign(claims, t.accessTokenKeyring, accessTokenHeaderType)
 }
 
-// GenerateAccessToken generates a new access token
-func (t *TokenService) GenerateAccessToken(ctx context.Context, user *model.User) (string, error) {
+// GenerateIdToken generates an OpenID Connect ID token. The user claims are
+// added by the profile and email scopes.
+func (t *TokenService) GenerateIdToken(ctx context.Context, user *model.User, r *app.IdTokenRequest) (string, error) {
 	sub := user.GetIdString()
 
 	if sub == "" {
@@ -101,17 +219,68 @@ func (t *TokenService) GenerateAccessToken(ctx context.Context, user *model.User
 
 	now := time.Now().UTC()
 
-	claims := Claims{
-		Role: user.GetRole(),
+	claims := IdTokenClaims{
+		AuthTime: r.AuthTime.Unix(),
+		Nonce:    r.Nonce,
 		StandardClaims: jwt.StandardClaims{
 			Issuer:    t.config.Jwt.Issuer,
 			IssuedAt:  now.Unix(),
 			ExpiresAt: now.Add(time.Duration(t.config.Jwt.AccessTokenExp) * time.Second).Unix(),
 			Subject:   sub,
+			Audience:  r.ClientId,
 		},
 	}
 
-	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(t.accessTokenPrivateKey)
+	if app.HasScopes(r.Scope, app.ScopeProfile) {
+		claims.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims.GivenName = user.FirstName
+		claims.FamilyName = user.LastName
+		claims.Picture = user.Avatar
+	}
+
+	if app.HasScopes(r.Scope, app.ScopeEmail) {
+		verified := user.EmailVerified
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+
+	return t.sign(claims, t.accessTokenKeyring, idTokenHeaderType)
+}
+
+// GenerateClientAccessToken generates a new access token for the client.
+// Client tokens have no role and no custom claims.
+func (t *TokenService) GenerateClientAccessToken(ctx context.Context, client *model.OAuthClient, grant *app.TokenGrant) (string, error) {
+	if client.Id == "" {
+		return "", errors.New("client id is empty")
+	}
+
+	g, err := t.ResolveClientGrant(ctx, client, grant)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+
+	claims := Claims{
+		Scope:     g.Scope,
+		TokenType: app.AccessTokenTypeClient,
+		ClientId:  client.Id,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    t.config.Jwt.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Duration(t.config.Jwt.AccessTokenExp) * time.Second).Unix(),
+			Subject:   client.Id,
+			Id:        jti,
+			Audience:  g.Audience,
+		},
+	}
+
+	return t.sign(claims, t.accessTokenKeyring, accessTokenHeaderType)
 }
 
 // ValidateAccessTokenFromRequest validates access token from request
@@ -121,6 +290,10 @@ func (t *TokenService) ValidateAccessTokenFromRequest(ctx context.Context, r *ht
 		return nil, errors.New("token is empty")
 	}
 
+	if len(token) < 7 || !strings.EqualFold(token[:7], "bearer ") {
+		return nil, errors.New("token is not a bearer token")
+	}
+
 	// remove bearer prefix
 	token = token[7:]
 
@@ -132,70 +305,353 @@ func (t *TokenService) ValidateAccessTokenFromRequest(ctx context.Context, r *ht
 	return claims, nil
 }
 
-// GenerateRefreshToken generates a new refresh token
-func (t *TokenService) GenerateRefreshToken(ctx context.Context, user *model.User) (string, error) {
-	sub := user.GetIdString()
+// GenerateRefreshToken generates a new refresh token which starts a new token
+// family. The grant is kept for the access tokens issued by the refresh token.
+func (t *TokenService) GenerateRefreshToken(ctx context.Context, user *model.User, grant *app.TokenGrant) (string, error) {
+	g, err := t.ResolveGrant(ctx, user, grant)
+	if err != nil {
+		return "", err
+	}
 
-	if sub == "" {
-		return "", errors.New("user id is empty")
+	familyId, err := newTokenId()
+	if err != nil {
+		return "", err
 	}
 
-	now := time.Now().UTC()
+	token, rt, err := t.signRefreshToken(user, familyId, g)
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.refreshTokenStore.Save(ctx, rt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RefreshTokenGrant returns the audience, the scope and the client which the
+// refresh token was issued for
+func (t *TokenService) RefreshTokenGrant(ctx context.Context, token string) (*app.TokenGrant, error) {
+	_, rt, err := t.verifyRefreshToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &app.TokenGrant{Audience: rt.Audience, Scope: rt.Scope, ClientId: rt.ClientId}, nil
+}
+
+// RotateRefreshToken exchanges a valid refresh token for a new one in the same
+// token family. Presenting a token which was already rotated revokes the
+// whole family, since it means that the token has been leaked. The new token
+// is only stored once the rotation succeeds, and it is revoked as well when
+// the family is revoked in the meantime.
+func (t *TokenService) RotateRefreshToken(ctx context.Context, token string, user *model.User) (string, error) {
+	claims, err := t.parseRefreshToken(token)
+	if err != nil {
+		return "", err
+	}
 
-	claims := jwt.StandardClaims{
-		Issuer:    t.config.Jwt.Issuer,
-		IssuedAt:  now.Unix(),
-		ExpiresAt: now.Add(time.Duration(t.config.Jwt.RefreshTokenExp) * time.Second).Unix(),
-		Subject:   sub,
-		Id:        "custom-id",
+	if claims.Generation != user.TokenGeneration {
+		return "", app.ErrTokenRevoked
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(t.refreshTokenPrivateKey)
+	jti := claims.GetTokenId()
+
+	rt, err := t.refreshTokenStore.Get(ctx, jti)
+	if err != nil {
+		return "", err
+	}
+
+	if rt.UserId != user.GetIdString() {
+		return "", app.ErrInvalidToken
+	}
+
+	grant := &app.TokenGrant{Audience: rt.Audience, Scope: rt.Scope, ClientId: rt.ClientId}
+
+	newToken, newRt, err := t.signRefreshToken(user, rt.FamilyId, grant)
 	if err != nil {
 		return "", err
 	}
 
-	//TODO add to redis
+	if err := t.refreshTokenStore.Rotate(ctx, jti, newRt.Id); err != nil {
+		if errors.Is(err, app.ErrRefreshTokenReused) {
+			t.revokeRefreshTokenFamily(ctx, rt)
+		}
+
+		return "", err
+	}
+
+	if err := t.refreshTokenStore.Save(ctx, newRt); err != nil {
+		return "", err
+	}
+
+	// a revocation between the rotation and the save does not see the new
+	// token, so the rotated one is checked again once the new one is stored
+	rotated, err := t.refreshTokenStore.Get(ctx, jti)
+	if err != nil {
+		return "", err
+	}
 
-	return token, err
+	if rotated.Revoked {
+		if err := t.refreshTokenStore.RevokeFamily(ctx, rt.FamilyId); err != nil {
+			return "", err
+		}
+
+		return "", app.ErrRefreshTokenRevoked
+	}
+
+	return newToken, nil
 }
 
+// ValidateRefreshToken validates the refresh token and returns its subject
 func (t *TokenService) ValidateRefreshToken(ctx context.Context, token string) (string, error) {
-	c, err := jwt.Parse(token, t.provideRefreshTokenPublicKey)
+	claims, rt, err := t.verifyRefreshToken(ctx, token)
 	if err != nil {
+		if errors.Is(err, app.ErrRefreshTokenReused) {
+			t.revokeRefreshTokenFamily(ctx, rt)
+		}
+
 		return "", err
 	}
 
-	claims, ok := c.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", errors.New("claims is not jwt.MapClaims")
+	return claims.GetSubject(), nil
+}
+
+// Introspect returns the state of the access or refresh token (RFC 7662).
+// Tokens which are invalid, expired or revoked are reported as inactive.
+func (t *TokenService) Introspect(ctx context.Context, r *app.IntrospectionRequest) (*app.IntrospectionResponse, error) {
+	if err := app.Validate(r); err != nil {
+		return nil, err
+	}
+
+	introspectors := []func(context.Context, string) (*app.IntrospectionResponse, error){
+		t.introspectAccessToken,
+		t.introspectRefreshToken,
+	}
+
+	if r.TokenTypeHint == app.TokenTypeRefreshToken {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		if res, err := introspect(ctx, r.Token); err == nil {
+			return res, nil
+		}
+	}
+
+	return &app.IntrospectionResponse{Active: false}, nil
+}
+
+// introspectAccessToken returns the state of a valid access token
+func (t *TokenService) introspectAccessToken(ctx context.Context, token string) (*app.IntrospectionResponse, error) {
+	c, err := t.ParseToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := c.(*Claims)
+
+	res := &app.IntrospectionResponse{
+		Active:    true,
+		Sub:       claims.GetSubject(),
+		Role:      claims.GetRole(),
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Iss:       claims.GetIssuer(),
+		Jti:       claims.GetTokenId(),
+		Aud:       claims.GetAudience(),
+		Scope:     claims.GetScope(),
+		TokenType: app.TokenTypeAccessToken,
+		Act:       claims.GetActor(),
+		ClientId:  claims.GetClientId(),
+	}
+
+	return res, nil
+}
+
+// introspectRefreshToken returns the state of a valid refresh token. Unlike
+// ValidateRefreshToken, it does not revoke the token family on reuse.
+func (t *TokenService) introspectRefreshToken(ctx context.Context, token string) (*app.IntrospectionResponse, error) {
+	claims, rt, err := t.verifyRefreshToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &app.IntrospectionResponse{
+		Active:    true,
+		Sub:       claims.GetSubject(),
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Iss:       claims.GetIssuer(),
+		Jti:       claims.GetTokenId(),
+		Aud:       rt.Audience,
+		Scope:     rt.Scope,
+		TokenType: app.TokenTypeRefreshToken,
+	}, nil
+}
+
+// verifyRefreshToken verifies the refresh token against its stored state. The
+// stored token is returned along with ErrRefreshTokenReused when the token
+// was already rotated.
+func (t *TokenService) verifyRefreshToken(ctx context.Context, token string) (*Claims, *model.RefreshToken, error) {
+	claims, err := t.parseRefreshToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := claims.GetSubject()
+	if sub == "" {
+		return nil, nil, errors.New("subject is empty")
+	}
+
+	jti := claims.GetTokenId()
+	if jti == "" {
+		return nil, nil, errors.New("jti is empty")
+	}
+
+	if err := t.verifyGeneration(ctx, claims); err != nil {
+		return nil, nil, err
+	}
+
+	rt, err := t.refreshTokenStore.Get(ctx, jti)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rt.UserId != sub {
+		return nil, nil, app.ErrInvalidToken
+	}
+
+	if rt.Revoked {
+		return nil, nil, app.ErrRefreshTokenRevoked
+	}
+
+	if rt.IsRotated() {
+		return nil, rt, app.ErrRefreshTokenReused
+	}
+
+	return claims, rt, nil
+}
+
+// RevokeRefreshToken revokes the refresh token together with the tokens
+// rotated from the same login
+func (t *TokenService) RevokeRefreshToken(ctx context.Context, token string) error {
+	claims, err := t.parseRefreshToken(token)
+	if err != nil {
+		return err
+	}
+
+	rt, err := t.refreshTokenStore.Get(ctx, claims.GetTokenId())
+	if err != nil {
+		return err
+	}
+
+	if rt.UserId != claims.GetSubject() {
+		return app.ErrInvalidToken
+	}
+
+	return t.refreshTokenStore.RevokeFamily(ctx, rt.FamilyId)
+}
+
+// signRefreshToken signs a new refresh token of the given family and returns
+// it along with its state to store, which keeps the resolved grant
+func (t *TokenService) signRefreshToken(user *model.User, familyId string, grant *app.TokenGrant) (string, *model.RefreshToken, error) {
+	sub := user.GetIdString()
+
+	if sub == "" {
+		return "", nil, errors.New("user id is empty")
+	}
+
+	jti, err := newTokenId()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	exp := now.Add(time.Duration(t.config.Jwt.RefreshTokenExp) * time.Second)
+
+	claims := Claims{
+		Generation: user.TokenGeneration,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    t.config.Jwt.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: exp.Unix(),
+			Subject:   sub,
+			Id:        jti,
+		},
+	}
+
+	token, err := t.sign(claims, t.refreshTokenKeyring, refreshTokenHeaderType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rt := &model.RefreshToken{
+		Id:        jti,
+		UserId:    sub,
+		FamilyId:  familyId,
+		Audience:  grant.Audience,
+		Scope:     grant.Scope,
+		ClientId:  grant.ClientId,
+		ExpiresAt: exp,
+		CreatedAt: now,
+	}
+
+	return token, rt, nil
+}
+
+// parseRefreshToken verifies the signature, issuer and expiration of the refresh token
+func (t *TokenService) parseRefreshToken(token string) (*Claims, error) {
+	claims := &Claims{}
+
+	c, err := jwt.ParseWithClaims(token, claims, t.provideRefreshTokenPublicKey)
+	if err != nil {
+		return nil, err
 	}
 
 	if !c.Valid {
-		return "", errors.New("token is not valid")
+		return nil, errors.New("token is not valid")
 	}
 
 	if !claims.VerifyIssuer(t.config.Jwt.Issuer, true) {
-		return "", errors.New("token issuer is not valid")
+		return nil, errors.New("token issuer is not valid")
 	}
 
 	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
-		return "", errors.New("token expired")
+		return nil, errors.New("token expired")
 	}
 
-	sub := claims["sub"].(string)
-	if sub == "" {
-		return "", errors.New("subject is empty")
+	return claims, nil
+}
+
+// verifyGeneration checks that the token was issued after the last time the
+// user logged out from all devices, and that the user is not disabled since.
+// Client tokens have no generation.
+func (t *TokenService) verifyGeneration(ctx context.Context, claims *Claims) error {
+	if claims.GetTokenType() == app.AccessTokenTypeClient {
+		return nil
 	}
 
-	jti := claims["jti"].(string)
-	if jti == "" {
-		return "", errors.New("jti is empty")
+	user, err := t.repo.GetUser(ctx, claims.GetSubject())
+	if err != nil {
+		return err
+	}
+
+	if user.TokenGeneration != claims.Generation || user.Disabled {
+		return app.ErrTokenRevoked
 	}
 
-	//TODO check in redis
+	return nil
+}
+
+// revokeRefreshTokenFamily revokes every token issued from the same login
+func (t *TokenService) revokeRefreshTokenFamily(ctx context.Context, rt *model.RefreshToken) {
+	t.logger.Warnf("refresh token reuse detected, revoking token family %s of user %s", rt.FamilyId, rt.UserId)
 
-	return sub, nil
+	if err := t.refreshTokenStore.RevokeFamily(ctx, rt.FamilyId); err != nil {
+		t.logger.Errorf("failed to revoke refresh token family %s: %s", rt.FamilyId, err)
+	}
 }
 
 // ParseToken parses a token
@@ -208,10 +664,14 @@ func (t *TokenService) ParseToken(ctx context.Context, token string) (app.Claims
 		return nil, err
 	}
 
-	if tkn.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+	if tkn.Method.Alg() != t.accessTokenKeyring.method.Alg() {
 		return nil, errors.New("invalid algorithm")
 	}
 
+	if typ, _ := tkn.Header["typ"].(string); typ == refreshTokenHeaderType || typ == idTokenHeaderType {
+		return nil, errors.New("invalid token type")
+	}
+
 	if !tkn.Valid {
 		return nil, errors.New("token is invalid")
 	}
@@ -220,15 +680,322 @@ func (t *TokenService) ParseToken(ctx context.Context, token string) (app.Claims
 		return nil, errors.New("invalid issuer")
 	}
 
+	if err := t.verifyGeneration(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	denied, err := t.denylist.Contains(ctx, accessTokenId(token, claims))
+	if err != nil {
+		return nil, err
+	}
+
+	if denied {
+		return nil, app.ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
+// Revoke revokes the access or refresh token (RFC 7009). Invalid tokens are
+// not reported as errors, since the client cannot do anything about them.
+func (t *TokenService) Revoke(ctx context.Context, r *app.RevocationRequest) error {
+	if err := app.Validate(r); err != nil {
+		return err
+	}
+
+	revokers := []func(context.Context, string) error{
+		t.revokeAccessToken,
+		t.RevokeRefreshToken,
+	}
+
+	if r.TokenTypeHint == app.TokenTypeRefreshToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		err := revoke(ctx, r.Token)
+		if err == nil {
+			return nil
+		}
+
+		var appErr *app.Error
+		if errors.As(err, &appErr) && appErr.Code() >= http.StatusInternalServerError {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessToken denies the access token until it expires
+func (t *TokenService) revokeAccessToken(ctx context.Context, token string) error {
+	c, err := t.ParseToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	claims := c.(*Claims)
+
+	return t.denylist.Add(ctx, &model.RevokedToken{
+		Id:        accessTokenId(token, claims),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		RevokedAt: time.Now(),
+	})
+}
+
+// DenyAccessToken denies the access token by its jti, without the token
+// itself. The token is denied for the longest lifetime an access token can
+// have, since its expiration is unknown.
+func (t *TokenService) DenyAccessToken(ctx context.Context, r *app.DenyTokenRequest) error {
+	if err := app.Validate(r); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	return t.denylist.Add(ctx, &model.RevokedToken{
+		Id:        r.Jti,
+		ExpiresAt: now.Add(time.Duration(t.config.Jwt.AccessTokenExp) * time.Second),
+		RevokedAt: now,
+	})
+}
+
+// KeySet returns the public keys to verify the issued tokens
+func (t *TokenService) KeySet(ctx context.Context) *app.JSONWebKeySet {
+	keys := t.accessTokenKeyring.publicKeys()
+
+	for _, rk := range t.refreshTokenKeyring.publicKeys() {
+		if !containsKey(keys, rk.Kid) {
+			keys = append(keys, rk)
+		}
+	}
+
+	return &app.JSONWebKeySet{Keys: keys}
+}
+
+// sign signs the claims with the active key of the keyring and stamps its key
+// id and the token type to the header
+func (t *TokenService) sign(claims jwt.Claims, kr *keyring, typ string) (string, error) {
+	key := kr.signingKey()
+
+	token := jwt.NewWithClaims(kr.method, claims)
+	token.Header["kid"] = key.jwk.Kid
+	token.Header["typ"] = typ
+
+	return token.SignedString(key.privateKey)
+}
+
 // provideAccessTokenPublicKey provides access token public key to veriy token
-func (t *TokenService) provideAccessTokenPublicKey(_ *jwt.Token) (interface{}, error) {
-	return t.accessTokenPublicKey, nil
+func (t *TokenService) provideAccessTokenPublicKey(token *jwt.Token) (interface{}, error) {
+	return t.accessTokenKeyring.keyFunc(token)
 }
 
-// provideRefreshTokenPublicKey provides access token public key to veriy token
-func (t *TokenService) provideRefreshTokenPublicKey(_ *jwt.Token) (interface{}, error) {
-	return t.refreshTokenPublicKey, nil
+// provideRefreshTokenPublicKey provides refresh token public key to veriy token
+func (t *TokenService) provideRefreshTokenPublicKey(token *jwt.Token) (interface{}, error) {
+	return t.refreshTokenKeyring.keyFunc(token)
+}
+
+// UseClaimsEnrichers sets the provider of the enrichers which add custom
+// claims to the access tokens. The provider is called on every access token,
+// so that the enrichers registered later are used as well.
+func (t *TokenService) UseClaimsEnrichers(enrichers func() []app.ClaimsEnricher) {
+	t.claimsEnrichers = enrichers
+}
+
+// enrichClaims collects the custom claims of the enrichers. The claims of an
+// enricher are dropped if they collide with the registered claims or do not
+// fit in the remaining size budget.
+func (t *TokenService) enrichClaims(ctx context.Context, user *model.User) (map[string]interface{}, error) {
+	if t.claimsEnrichers == nil {
+		return nil, nil
+	}
+
+	custom := make(map[string]interface{})
+	size := 0
+
+	for _, e := range t.claimsEnrichers() {
+		c, err := e.EnrichClaims(ctx, user)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to enrich claims")
+		}
+
+		if len(c) == 0 {
+			continue
+		}
+
+		if name, ok := collidingClaim(custom, c); ok {
+			t.logger.Warnf("dropped claims of enricher %T, claim %s is already set", e, name)
+			continue
+		}
+
+		b, err := json.Marshal(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode custom claims")
+		}
+
+		if size+len(b) > t.config.Jwt.CustomClaimsMaxSize {
+			t.logger.Warnf("dropped claims of enricher %T, size budget of %d bytes is exceeded", e, t.config.Jwt.CustomClaimsMaxSize)
+			continue
+		}
+
+		size += len(b)
+		for k, v := range c {
+			custom[k] = v
+		}
+	}
+
+	return custom, nil
+}
+
+// collidingClaim returns the first claim which is either registered or
+// already set by another enricher
+func collidingClaim(custom map[string]interface{}, claims map[string]interface{}) (string, bool) {
+	for k := range claims {
+		if _, ok := custom[k]; ok || registeredClaims[k] {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// ResolveGrant validates the requested audience and scope against the config,
+// falling back to the first audience and the default scopes. Admin scopes are
+// granted to the admins only. Disabled users are not granted any tokens.
+func (t *TokenService) ResolveGrant(ctx context.Context, user *model.User, grant *app.TokenGrant) (*app.TokenGrant, error) {
+	if user.Disabled {
+		return nil, app.ErrUserDisabled
+	}
+
+	if grant == nil {
+		grant = &app.TokenGrant{}
+	}
+
+	audiences := configList(t.config.Jwt.Audiences)
+
+	audience := grant.Audience
+	if audience == "" && len(audiences) > 0 {
+		audience = audiences[0]
+	} else if audience != "" && !contains(audiences, audience) {
+		return nil, app.ErrInvalidAudience
+	}
+
+	scopes := app.ParseScope(grant.Scope)
+	if len(scopes) == 0 {
+		scopes = app.ParseScope(strings.Join(t.config.Jwt.DefaultScopes, " "))
+	}
+
+	for _, s := range scopes {
+		if contains(configList(t.config.Jwt.AdminScopes), s) {
+			if !user.IsAdmin() {
+				return nil, app.ErrInvalidScope
+			}
+		} else if !contains(configList(t.config.Jwt.Scopes), s) && !contains(app.OpenIdScopes, s) {
+			return nil, app.ErrInvalidScope
+		}
+	}
+
+	return &app.TokenGrant{Audience: audience, Scope: strings.Join(scopes, " "), ClientId: grant.ClientId}, nil
+}
+
+// ResolveClientGrant validates the requested audience and scope against the
+// client, falling back to the audience and all of the scopes of the client
+// except the OpenID Connect ones. The scopes must still be configured, since
+// the config may change after the client is registered.
+func (t *TokenService) ResolveClientGrant(ctx context.Context, client *model.OAuthClient, grant *app.TokenGrant) (*app.TokenGrant, error) {
+	if grant == nil {
+		grant = &app.TokenGrant{}
+	}
+
+	audiences := configList(t.config.Jwt.Audiences)
+
+	audience := grant.Audience
+	if audience == "" {
+		audience = client.Audience
+	}
+
+	if audience == "" && len(audiences) > 0 {
+		audience = audiences[0]
+	} else if audience != "" && !contains(audiences, audience) {
+		return nil, app.ErrInvalidAudience
+	}
+
+	if client.Audience != "" && audience != client.Audience {
+		return nil, app.ErrInvalidAudience
+	}
+
+	scopes := app.ParseScope(grant.Scope)
+	if len(scopes) == 0 {
+		// the scopes of OpenID Connect need a user
+		for _, s := range app.ParseScope(strings.Join(client.Scopes, " ")) {
+			if !contains(app.OpenIdScopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	known := append(configList(t.config.Jwt.Scopes), configList(t.config.Jwt.AdminScopes)...)
+	for _, s := range scopes {
+		if !contains(known, s) || !client.AllowsScopes(s) {
+			return nil, app.ErrInvalidScope
+		}
+	}
+
+	return &app.TokenGrant{Audience: audience, Scope: strings.Join(scopes, " ")}, nil
+}
+
+// accessTokenId identifies the access token in the denylist by its jti, or by
+// its hash when the token has no jti
+func accessTokenId(token string, claims *Claims) string {
+	if jti := claims.GetTokenId(); jti != "" {
+		return jti
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// configList returns the non empty values of a list config
+func configList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// contains returns true if the list has the value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsKey returns true if the key set has a key with the given id
+func containsKey(keys []app.JSONWebKey, kid string) bool {
+	for _, k := range keys {
+		if k.Kid == kid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newTokenId generates a random token id
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
 }
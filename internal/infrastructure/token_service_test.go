@@ -6,10 +6,15 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
 	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app/mock"
 	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
 	. "github.com/orkungursel/hey-taxi-identity-api/pkg/logger/mock"
 	"github.com/pkg/errors"
@@ -39,32 +44,48 @@ func SetTokenServiceEnvForTesting(t *testing.T) {
 	t.Setenv("JWT_ISSUER", issuer)
 }
 
-func TestNewTokenService(t *testing.T) {
-	SetTokenServiceEnvForTesting(t)
+// newRepositoryForTesting returns a repository which knows every user with a
+// valid id, using the given users when their ids match
+func newRepositoryForTesting(t *testing.T, users ...*model.User) *mock.MockRepository {
+	repo := mock.NewMockRepository(gomock.NewController(t))
+
+	repo.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.User, error) {
+			for _, u := range users {
+				if u.GetIdString() == id {
+					return u, nil
+				}
+			}
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+			oid, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return nil, app.ErrInvalidUserId
+			}
 
-	if ts.accessTokenPrivateKey == nil {
-		t.Errorf("access token privateKey is empty")
-	}
+			return &model.User{Id: oid}, nil
+		}).AnyTimes()
 
-	if ts.accessTokenPublicKey == nil {
-		t.Errorf("access token publicKey is empty")
-	}
+	return repo
+}
+
+func TestNewTokenService(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
 
-	if ts.refreshTokenPrivateKey == nil {
-		t.Errorf("refresh token privateKey is empty")
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	if ts.accessTokenKeyring.signingKey() == nil {
+		t.Errorf("access token signing key is empty")
 	}
 
-	if ts.refreshTokenPublicKey == nil {
-		t.Errorf("refresh token publicKey is empty")
+	if ts.refreshTokenKeyring.signingKey() == nil {
+		t.Errorf("refresh token signing key is empty")
 	}
 }
 
 func TestTokenService_GenerateAccessToken(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
 
 	type args struct {
 		ctx  context.Context
@@ -96,7 +117,7 @@ func TestTokenService_GenerateAccessToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ts.GenerateAccessToken(tt.args.ctx, tt.args.user)
+			got, err := ts.GenerateAccessToken(tt.args.ctx, tt.args.user, nil)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("TokenService.GenerateAccessToken() error = %v, wantErr %v", err, tt.wantErr)
@@ -110,10 +131,140 @@ func TestTokenService_GenerateAccessToken(t *testing.T) {
 	}
 }
 
+func TestTokenService_GenerateDelegatedAccessToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	act := &app.Actor{Subject: "dispatch"}
+	notAfter := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	token, err := ts.GenerateDelegatedAccessToken(ctx, u, &app.DelegatedTokenRequest{Actor: act, NotAfter: notAfter})
+	if err != nil {
+		t.Fatalf("TokenService.GenerateDelegatedAccessToken() error = %v", err)
+	}
+
+	claims, err := ts.ParseToken(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.GetExpiresAt() != notAfter.Unix() {
+		t.Errorf("TokenService.GenerateDelegatedAccessToken() exp = %v, want %v", claims.GetExpiresAt(), notAfter.Unix())
+	}
+
+	if !reflect.DeepEqual(claims.GetActor(), act) {
+		t.Errorf("TokenService.GenerateDelegatedAccessToken() act = %+v, want %+v", claims.GetActor(), act)
+	}
+
+	res, err := ts.Introspect(ctx, &app.IntrospectionRequest{Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res.Act, act) {
+		t.Errorf("TokenService.Introspect() act = %+v, want %+v", res.Act, act)
+	}
+
+	t.Run("should not extend the expiration", func(t *testing.T) {
+		token, err := ts.GenerateDelegatedAccessToken(ctx, u, &app.DelegatedTokenRequest{Actor: act, NotAfter: time.Now().Add(time.Hour * 24 * 365)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := ts.ParseToken(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if max := time.Now().Add(time.Duration(ts.config.Jwt.AccessTokenExp) * time.Second).Unix(); claims.GetExpiresAt() > max {
+			t.Errorf("TokenService.GenerateDelegatedAccessToken() exp = %v, want <= %v", claims.GetExpiresAt(), max)
+		}
+	})
+
+	t.Run("should fail without an actor", func(t *testing.T) {
+		if _, err := ts.GenerateDelegatedAccessToken(ctx, u, &app.DelegatedTokenRequest{}); err == nil {
+			t.Errorf("TokenService.GenerateDelegatedAccessToken() error = nil, want error")
+		}
+	})
+}
+
+func TestTokenService_GenerateIdToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID(), FirstName: "Jane", LastName: "Doe", Email: "jane@doe.com", Avatar: "https://cdn.example.com/jane.png", EmailVerified: true}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	verified := true
+
+	tests := []struct {
+		name  string
+		scope string
+		want  IdTokenClaims
+	}{
+		{
+			name:  "should only have the standard claims for the openid scope",
+			scope: "openid",
+			want:  IdTokenClaims{},
+		},
+		{
+			name:  "should have the profile claims for the profile scope",
+			scope: "openid profile",
+			want:  IdTokenClaims{Name: "Jane Doe", GivenName: "Jane", FamilyName: "Doe", Picture: "https://cdn.example.com/jane.png"},
+		},
+		{
+			name:  "should have the email claims for the email scope",
+			scope: "email openid",
+			want:  IdTokenClaims{Email: "jane@doe.com", EmailVerified: &verified},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ts.GenerateIdToken(ctx, u, &app.IdTokenRequest{ClientId: "client", Scope: tt.scope, Nonce: "n-0S6_WzA2Mj", AuthTime: authTime})
+			if err != nil {
+				t.Fatalf("TokenService.GenerateIdToken() error = %v", err)
+			}
+
+			claims := &IdTokenClaims{}
+			if _, err := jwt.ParseWithClaims(token, claims, ts.provideAccessTokenPublicKey); err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.Subject != u.GetIdString() || claims.Audience != "client" || claims.Issuer != issuer {
+				t.Errorf("TokenService.GenerateIdToken() sub = %v, aud = %v, iss = %v", claims.Subject, claims.Audience, claims.Issuer)
+			}
+
+			if claims.Nonce != "n-0S6_WzA2Mj" || claims.AuthTime != authTime.Unix() {
+				t.Errorf("TokenService.GenerateIdToken() nonce = %v, auth_time = %v", claims.Nonce, claims.AuthTime)
+			}
+
+			tt.want.AuthTime, tt.want.Nonce, tt.want.StandardClaims = claims.AuthTime, claims.Nonce, claims.StandardClaims
+			if !reflect.DeepEqual(*claims, tt.want) {
+				t.Errorf("TokenService.GenerateIdToken() = %+v, want %+v", *claims, tt.want)
+			}
+		})
+	}
+
+	t.Run("should not accept the id token as an access token", func(t *testing.T) {
+		token, err := ts.GenerateIdToken(ctx, u, &app.IdTokenRequest{ClientId: "client", Scope: "openid", AuthTime: authTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.ParseToken(ctx, token); err == nil {
+			t.Errorf("TokenService.ParseToken() accepted an id token")
+		}
+	})
+}
+
 func TestTokenService_GenerateRefreshToken(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
 
 	type args struct {
 		ctx  context.Context
@@ -129,7 +280,7 @@ func TestTokenService_GenerateRefreshToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ts.GenerateRefreshToken(tt.args.ctx, tt.args.user)
+			got, err := ts.GenerateRefreshToken(tt.args.ctx, tt.args.user, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("TokenService.GenerateRefreshToken() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -144,7 +295,21 @@ func TestTokenService_GenerateRefreshToken(t *testing.T) {
 func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	u := &model.User{Id: primitive.NewObjectID()}
+	validToken, err := ts.GenerateAccessToken(context.Background(), u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(authorization string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		return r
+	}
 
 	type args struct {
 		ctx context.Context
@@ -153,10 +318,39 @@ func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    map[string]interface{}
+		want    string
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "should return claims with a bearer token",
+			args: args{ctx: context.Background(), r: request("Bearer " + validToken)},
+			want: u.GetIdString(),
+		},
+		{
+			name: "should accept the scheme in any case",
+			args: args{ctx: context.Background(), r: request("bearer " + validToken)},
+			want: u.GetIdString(),
+		},
+		{
+			name:    "should fail without authorization header",
+			args:    args{ctx: context.Background(), r: request("")},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with a header shorter than the scheme",
+			args:    args{ctx: context.Background(), r: request("Bear")},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with another scheme",
+			args:    args{ctx: context.Background(), r: request("Basic " + validToken)},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with an invalid token",
+			args:    args{ctx: context.Background(), r: request("Bearer invalid")},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,8 +359,8 @@ func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 				t.Errorf("TokenService.ValidateAccessTokenFromRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("TokenService.ValidateAccessTokenFromRequest() = %v, want %v", got, tt.want)
+			if err == nil && got.GetSubject() != tt.want {
+				t.Errorf("TokenService.ValidateAccessTokenFromRequest() subject = %v, want %v", got.GetSubject(), tt.want)
 			}
 		})
 	}
@@ -175,12 +369,12 @@ func TestTokenService_ValidateAccessTokenFromRequest(t *testing.T) {
 func TestTokenService_parseToken(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
 
 	u := &model.User{
 		Id: primitive.NewObjectID(),
 	}
-	validToken, err := ts.GenerateAccessToken(context.Background(), u)
+	validToken, err := ts.GenerateAccessToken(context.Background(), u, nil)
 	if err != nil {
 		t.Error(errors.Wrapf(err, "failed to generate access token"))
 	}
@@ -305,7 +499,37 @@ func TestTokenService_parseToken(t *testing.T) {
 func TestTokenService_ValidateRefreshToken(t *testing.T) {
 	SetTokenServiceEnvForTesting(t)
 
-	ts := NewTokenService(config.New(), NewLoggerMock())
+	ctx := context.Background()
+	rts := NewInMemoryRefreshTokenStore()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), rts, NewInMemoryTokenDenylist())
+
+	rts.Save(ctx, &model.RefreshToken{
+		Id:        "custom-id",
+		UserId:    "623b3d2c2da9e50a7486de7f",
+		FamilyId:  "custom-family-id",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	u := &model.User{Id: primitive.NewObjectID()}
+
+	rotatedToken, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(errors.Wrapf(err, "failed to generate refresh token"))
+	}
+
+	if _, err := ts.RotateRefreshToken(ctx, rotatedToken, u); err != nil {
+		t.Fatal(errors.Wrapf(err, "failed to rotate refresh token"))
+	}
+
+	unknownToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		Issuer:    issuer,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		Subject:   u.GetIdString(),
+		Id:        "unknown-id",
+	}).SignedString(ts.refreshTokenKeyring.signingKey().privateKey)
+	if err != nil {
+		t.Fatal(errors.Wrapf(err, "failed to sign refresh token"))
+	}
 	type args struct {
 		ctx   context.Context
 		token string
@@ -340,6 +564,22 @@ func TestTokenService_ValidateRefreshToken(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "should fail because token is not in the store",
+			args: args{
+				ctx:   context.Background(),
+				token: unknownToken,
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail because token is already rotated",
+			args: args{
+				ctx:   context.Background(),
+				token: rotatedToken,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -354,3 +594,1004 @@ func TestTokenService_ValidateRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenService_RotateRefreshToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	u := &model.User{Id: primitive.NewObjectID()}
+	u2 := &model.User{Id: primitive.NewObjectID()}
+
+	t.Run("should rotate refresh token", func(t *testing.T) {
+		token, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ts.RotateRefreshToken(ctx, token, u)
+		if err != nil {
+			t.Fatalf("TokenService.RotateRefreshToken() error = %v", err)
+		}
+
+		if got == "" || got == token {
+			t.Errorf("TokenService.RotateRefreshToken() = %v, want a new token", got)
+		}
+
+		if sub, err := ts.ValidateRefreshToken(ctx, got); err != nil || sub != u.GetIdString() {
+			t.Errorf("TokenService.ValidateRefreshToken() = %v, %v, want %v", sub, err, u.GetIdString())
+		}
+	})
+
+	t.Run("should revoke token family when rotated token is reused", func(t *testing.T) {
+		token, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := ts.RotateRefreshToken(ctx, token, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.RotateRefreshToken(ctx, token, u); !errors.Is(err, app.ErrRefreshTokenReused) {
+			t.Errorf("TokenService.RotateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenReused)
+		}
+
+		if _, err := ts.ValidateRefreshToken(ctx, rotated); !errors.Is(err, app.ErrRefreshTokenRevoked) {
+			t.Errorf("TokenService.ValidateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenRevoked)
+		}
+	})
+
+	t.Run("should not store a new token when the rotation fails", func(t *testing.T) {
+		store := &countingRefreshTokenStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+		ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), store, NewInMemoryTokenDenylist())
+
+		token, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.RotateRefreshToken(ctx, token, u); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.RotateRefreshToken(ctx, token, u); !errors.Is(err, app.ErrRefreshTokenReused) {
+			t.Fatalf("TokenService.RotateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenReused)
+		}
+
+		if store.saved != 2 {
+			t.Errorf("RefreshTokenStore.Save() calls = %d, want 2", store.saved)
+		}
+	})
+
+	t.Run("should revoke the new token when the family is revoked during the rotation", func(t *testing.T) {
+		store := &revokingRefreshTokenStore{InMemoryRefreshTokenStore: NewInMemoryRefreshTokenStore()}
+		ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), store, NewInMemoryTokenDenylist())
+
+		token, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.RotateRefreshToken(ctx, token, u); !errors.Is(err, app.ErrRefreshTokenRevoked) {
+			t.Fatalf("TokenService.RotateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenRevoked)
+		}
+
+		rt, err := store.Get(ctx, store.replacedBy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !rt.Revoked {
+			t.Errorf("RefreshToken.Revoked = false, want true")
+		}
+	})
+
+	t.Run("should fail when token belongs to another user", func(t *testing.T) {
+		token, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.RotateRefreshToken(ctx, token, u2); err == nil {
+			t.Errorf("TokenService.RotateRefreshToken() error = nil, want error")
+		}
+	})
+}
+
+// countingRefreshTokenStore counts the stored refresh tokens
+type countingRefreshTokenStore struct {
+	*InMemoryRefreshTokenStore
+	saved int
+}
+
+func (s *countingRefreshTokenStore) Save(ctx context.Context, token *model.RefreshToken) error {
+	s.saved++
+	return s.InMemoryRefreshTokenStore.Save(ctx, token)
+}
+
+// revokingRefreshTokenStore revokes the family of the rotated token right
+// after the rotation, like a logout running at the same time
+type revokingRefreshTokenStore struct {
+	*InMemoryRefreshTokenStore
+	replacedBy string
+}
+
+func (s *revokingRefreshTokenStore) Rotate(ctx context.Context, id string, replacedBy string) error {
+	if err := s.InMemoryRefreshTokenStore.Rotate(ctx, id, replacedBy); err != nil {
+		return err
+	}
+
+	s.replacedBy = replacedBy
+
+	rt, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.RevokeFamily(ctx, rt.FamilyId)
+}
+
+func TestTokenService_RevokeRefreshToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	u := &model.User{Id: primitive.NewObjectID()}
+
+	token, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := ts.RotateRefreshToken(ctx, token, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ts.RevokeRefreshToken(ctx, rotated); err != nil {
+		t.Fatalf("TokenService.RevokeRefreshToken() error = %v", err)
+	}
+
+	if _, err := ts.ValidateRefreshToken(ctx, rotated); !errors.Is(err, app.ErrRefreshTokenRevoked) {
+		t.Errorf("TokenService.ValidateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenRevoked)
+	}
+
+	if err := ts.RevokeRefreshToken(ctx, invalidIssuerRefreshToken); err == nil {
+		t.Errorf("TokenService.RevokeRefreshToken() error = nil, want error")
+	}
+}
+
+func TestTokenService_Introspect(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID(), Role: "driver"}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	accessToken, err := ts.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedRefreshToken, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.RotateRefreshToken(ctx, rotatedRefreshToken, u); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		hint          string
+		wantActive    bool
+		wantTokenType string
+		wantErr       bool
+	}{
+		{
+			name:          "should introspect access token",
+			token:         accessToken,
+			wantActive:    true,
+			wantTokenType: app.TokenTypeAccessToken,
+		},
+		{
+			name:          "should introspect refresh token",
+			token:         refreshToken,
+			wantActive:    true,
+			wantTokenType: app.TokenTypeRefreshToken,
+		},
+		{
+			name:          "should introspect access token with refresh token hint",
+			token:         accessToken,
+			hint:          app.TokenTypeRefreshToken,
+			wantActive:    true,
+			wantTokenType: app.TokenTypeAccessToken,
+		},
+		{
+			name:  "should report rotated refresh token as inactive",
+			token: rotatedRefreshToken,
+		},
+		{
+			name:  "should report token of another issuer as inactive",
+			token: invalidIssuerRefreshToken,
+		},
+		{
+			name:  "should report malformed token as inactive",
+			token: "invalid",
+		},
+		{
+			name:    "should fail when token is empty",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.Introspect(ctx, &app.IntrospectionRequest{Token: tt.token, TokenTypeHint: tt.hint})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenService.Introspect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got.Active != tt.wantActive {
+				t.Errorf("TokenService.Introspect() active = %v, want %v", got.Active, tt.wantActive)
+			}
+
+			if got.TokenType != tt.wantTokenType {
+				t.Errorf("TokenService.Introspect() token_type = %v, want %v", got.TokenType, tt.wantTokenType)
+			}
+
+			if tt.wantActive && got.Sub != u.GetIdString() {
+				t.Errorf("TokenService.Introspect() sub = %v, want %v", got.Sub, u.GetIdString())
+			}
+		})
+	}
+
+	t.Run("should not revoke the family of a rotated refresh token", func(t *testing.T) {
+		rotated, err := ts.GenerateRefreshToken(ctx, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		next, err := ts.RotateRefreshToken(ctx, rotated, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.Introspect(ctx, &app.IntrospectionRequest{Token: rotated}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.ValidateRefreshToken(ctx, next); err != nil {
+			t.Errorf("TokenService.ValidateRefreshToken() error = %v, want nil", err)
+		}
+	})
+}
+
+func TestTokenService_Revoke(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	accessToken, err := ts.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherAccessToken, err := ts.GenerateAccessToken(ctx, &model.User{Id: primitive.NewObjectID()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		req     *app.RevocationRequest
+		wantErr bool
+	}{
+		{
+			name: "should revoke access token",
+			req:  &app.RevocationRequest{Token: accessToken},
+		},
+		{
+			name: "should revoke refresh token with hint",
+			req:  &app.RevocationRequest{Token: refreshToken, TokenTypeHint: app.TokenTypeRefreshToken},
+		},
+		{
+			name: "should ignore revoked token",
+			req:  &app.RevocationRequest{Token: accessToken},
+		},
+		{
+			name: "should ignore invalid token",
+			req:  &app.RevocationRequest{Token: invalidIssuerRefreshToken},
+		},
+		{
+			name:    "should fail when token is empty",
+			req:     &app.RevocationRequest{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ts.Revoke(ctx, tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("TokenService.Revoke() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ts.ParseToken(ctx, accessToken); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.ParseToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+
+	if _, err := ts.ParseToken(ctx, otherAccessToken); err != nil {
+		t.Errorf("TokenService.ParseToken() error = %v, want nil", err)
+	}
+
+	if _, err := ts.ValidateRefreshToken(ctx, refreshToken); !errors.Is(err, app.ErrRefreshTokenRevoked) {
+		t.Errorf("TokenService.ValidateRefreshToken() error = %v, want %v", err, app.ErrRefreshTokenRevoked)
+	}
+}
+
+func TestTokenService_DenyAccessToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	leaked, err := ts.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := ts.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ts.ParseToken(ctx, leaked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherClaims, err := ts.ParseToken(ctx, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.GetTokenId() == "" || claims.GetTokenId() == otherClaims.GetTokenId() {
+		t.Fatalf("access tokens have jti %q and %q, want unique ids", claims.GetTokenId(), otherClaims.GetTokenId())
+	}
+
+	if err := ts.DenyAccessToken(ctx, &app.DenyTokenRequest{}); err == nil {
+		t.Errorf("TokenService.DenyAccessToken() error = nil, want error")
+	}
+
+	if err := ts.DenyAccessToken(ctx, &app.DenyTokenRequest{Jti: claims.GetTokenId()}); err != nil {
+		t.Fatalf("TokenService.DenyAccessToken() error = %v", err)
+	}
+
+	if _, err := ts.ParseToken(ctx, leaked); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.ParseToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+
+	if _, err := ts.ParseToken(ctx, other); err != nil {
+		t.Errorf("TokenService.ParseToken() error = %v, want nil", err)
+	}
+}
+
+func TestTokenService_Grant(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+	t.Setenv("JWT_AUDIENCES", "driver-app,fleet-admin")
+	t.Setenv("JWT_SCOPES", "rides:read,rides:write")
+	t.Setenv("JWT_DEFAULT_SCOPES", "rides:read")
+	t.Setenv("JWT_ADMIN_SCOPES", "fleet:admin")
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID(), Role: model.RoleUser}
+	admin := &model.User{Id: primitive.NewObjectID(), Role: model.RoleAdmin}
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u, admin), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	tests := []struct {
+		name         string
+		user         *model.User
+		grant        *app.TokenGrant
+		wantAudience string
+		wantScope    string
+		wantErr      bool
+	}{
+		{
+			name:         "should use the defaults",
+			user:         u,
+			wantAudience: "driver-app",
+			wantScope:    "rides:read",
+		},
+		{
+			name:         "should grant the requested audience and scopes",
+			user:         u,
+			grant:        &app.TokenGrant{Audience: "fleet-admin", Scope: "rides:write rides:read rides:write"},
+			wantAudience: "fleet-admin",
+			wantScope:    "rides:read rides:write",
+		},
+		{
+			name:         "should grant admin scopes to admins",
+			user:         admin,
+			grant:        &app.TokenGrant{Scope: "fleet:admin"},
+			wantAudience: "driver-app",
+			wantScope:    "fleet:admin",
+		},
+		{
+			name:    "should fail to grant admin scopes to users",
+			user:    u,
+			grant:   &app.TokenGrant{Scope: "fleet:admin"},
+			wantErr: true,
+		},
+		{
+			name:         "should grant the openid scopes without config",
+			user:         u,
+			grant:        &app.TokenGrant{Scope: "openid profile email"},
+			wantAudience: "driver-app",
+			wantScope:    "email openid profile",
+		},
+		{
+			name:    "should fail to grant unknown scopes",
+			user:    u,
+			grant:   &app.TokenGrant{Scope: "rides:delete"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail to grant unknown audiences",
+			user:    u,
+			grant:   &app.TokenGrant{Audience: "billing"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ts.GenerateAccessToken(ctx, tt.user, tt.grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenService.GenerateAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			claims, err := ts.ParseToken(ctx, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.GetAudience() != tt.wantAudience {
+				t.Errorf("TokenService.GenerateAccessToken() aud = %v, want %v", claims.GetAudience(), tt.wantAudience)
+			}
+
+			if claims.GetScope() != tt.wantScope {
+				t.Errorf("TokenService.GenerateAccessToken() scope = %v, want %v", claims.GetScope(), tt.wantScope)
+			}
+
+			if claims.GetTokenType() != app.AccessTokenTypeUser {
+				t.Errorf("TokenService.GenerateAccessToken() token type = %v, want %v", claims.GetTokenType(), app.AccessTokenTypeUser)
+			}
+		})
+	}
+
+	t.Run("should keep the grant of the refresh token on rotation", func(t *testing.T) {
+		grant := &app.TokenGrant{Audience: "fleet-admin", Scope: "rides:write"}
+
+		token, err := ts.GenerateRefreshToken(ctx, u, grant)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := ts.RotateRefreshToken(ctx, token, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ts.RefreshTokenGrant(ctx, rotated)
+		if err != nil {
+			t.Fatalf("TokenService.RefreshTokenGrant() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, grant) {
+			t.Errorf("TokenService.RefreshTokenGrant() = %v, want %v", got, grant)
+		}
+	})
+}
+
+func TestTokenService_ClientAccessToken(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+	t.Setenv("JWT_AUDIENCES", "driver-app,pricing")
+	t.Setenv("JWT_SCOPES", "rides:read,rides:write,users:read")
+
+	ctx := context.Background()
+	client := &model.OAuthClient{Id: "dispatch", Scopes: []string{"rides:read", "users:read"}, Confidential: true}
+	restricted := &model.OAuthClient{Id: "pricing", Scopes: []string{"rides:read"}, Audience: "pricing", Confidential: true}
+
+	// client tokens must not be checked against the users
+	repo := mock.NewMockRepository(gomock.NewController(t))
+	ts := NewTokenService(config.New(), NewLoggerMock(), repo, NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	tests := []struct {
+		name         string
+		client       *model.OAuthClient
+		grant        *app.TokenGrant
+		wantAudience string
+		wantScope    string
+		wantErr      bool
+	}{
+		{
+			name:         "should grant all of the client scopes by default",
+			client:       client,
+			wantAudience: "driver-app",
+			wantScope:    "rides:read users:read",
+		},
+		{
+			name:         "should narrow the scopes to the requested ones",
+			client:       client,
+			grant:        &app.TokenGrant{Scope: "users:read"},
+			wantAudience: "driver-app",
+			wantScope:    "users:read",
+		},
+		{
+			name:         "should use the audience of the client",
+			client:       restricted,
+			wantAudience: "pricing",
+			wantScope:    "rides:read",
+		},
+		{
+			name:    "should fail to grant scopes which are not allowed for the client",
+			client:  client,
+			grant:   &app.TokenGrant{Scope: "rides:write"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail to grant other audiences to a client with an audience",
+			client:  restricted,
+			grant:   &app.TokenGrant{Audience: "driver-app"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ts.GenerateClientAccessToken(ctx, tt.client, tt.grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenService.GenerateClientAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			claims, err := ts.ParseToken(ctx, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.GetSubject() != tt.client.Id {
+				t.Errorf("TokenService.GenerateClientAccessToken() sub = %v, want %v", claims.GetSubject(), tt.client.Id)
+			}
+
+			if claims.GetTokenType() != app.AccessTokenTypeClient {
+				t.Errorf("TokenService.GenerateClientAccessToken() token type = %v, want %v", claims.GetTokenType(), app.AccessTokenTypeClient)
+			}
+
+			if claims.GetRole() != "" {
+				t.Errorf("TokenService.GenerateClientAccessToken() role = %v, want empty", claims.GetRole())
+			}
+
+			if claims.GetAudience() != tt.wantAudience {
+				t.Errorf("TokenService.GenerateClientAccessToken() aud = %v, want %v", claims.GetAudience(), tt.wantAudience)
+			}
+
+			if claims.GetScope() != tt.wantScope {
+				t.Errorf("TokenService.GenerateClientAccessToken() scope = %v, want %v", claims.GetScope(), tt.wantScope)
+			}
+		})
+	}
+
+	t.Run("should report the client id on introspection", func(t *testing.T) {
+		token, err := ts.GenerateClientAccessToken(ctx, client, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := ts.Introspect(ctx, &app.IntrospectionRequest{Token: token})
+		if err != nil {
+			t.Fatalf("TokenService.Introspect() error = %v", err)
+		}
+
+		if !res.Active || res.ClientId != client.Id {
+			t.Errorf("TokenService.Introspect() = %+v, want active with client id %v", res, client.Id)
+		}
+	})
+}
+
+func TestTokenService_ClaimsEnrichers(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+	t.Setenv("JWT_CUSTOM_CLAIMS_MAX_SIZE", "64")
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+
+	enricher := func(claims map[string]interface{}, err error) app.ClaimsEnricher {
+		e := mock.NewMockClaimsEnricher(gomock.NewController(t))
+		e.EXPECT().EnrichClaims(gomock.Any(), u).Return(claims, err).AnyTimes()
+		return e
+	}
+
+	tests := []struct {
+		name       string
+		enrichers  []app.ClaimsEnricher
+		wantClaims map[string]interface{}
+		wantErr    bool
+	}{
+		{
+			name: "should add custom claims",
+			enrichers: []app.ClaimsEnricher{
+				enricher(map[string]interface{}{"tenant": "acme"}, nil),
+				enricher(map[string]interface{}{"verified": true}, nil),
+			},
+			wantClaims: map[string]interface{}{"tenant": "acme", "verified": true},
+		},
+		{
+			name: "should drop claims colliding with registered claims",
+			enrichers: []app.ClaimsEnricher{
+				enricher(map[string]interface{}{"sub": "other", "tenant": "acme"}, nil),
+				enricher(map[string]interface{}{"verified": true}, nil),
+			},
+			wantClaims: map[string]interface{}{"verified": true},
+		},
+		{
+			name: "should drop claims colliding with other enrichers",
+			enrichers: []app.ClaimsEnricher{
+				enricher(map[string]interface{}{"tenant": "acme"}, nil),
+				enricher(map[string]interface{}{"tenant": "other"}, nil),
+			},
+			wantClaims: map[string]interface{}{"tenant": "acme"},
+		},
+		{
+			name: "should drop claims exceeding the size budget",
+			enrichers: []app.ClaimsEnricher{
+				enricher(map[string]interface{}{"tenant": "acme"}, nil),
+				enricher(map[string]interface{}{"bio": strings.Repeat("x", 64)}, nil),
+			},
+			wantClaims: map[string]interface{}{"tenant": "acme"},
+		},
+		{
+			name: "should fail when an enricher fails",
+			enrichers: []app.ClaimsEnricher{
+				enricher(nil, errors.New("enricher failed")),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+			ts.UseClaimsEnrichers(func() []app.ClaimsEnricher { return tt.enrichers })
+
+			token, err := ts.GenerateAccessToken(ctx, u, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenService.GenerateAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			claims, err := ts.ParseToken(ctx, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.GetSubject() != u.GetIdString() {
+				t.Errorf("TokenService.ParseToken() sub = %v, want %v", claims.GetSubject(), u.GetIdString())
+			}
+
+			if got := claims.(*Claims).Custom; !reflect.DeepEqual(got, tt.wantClaims) {
+				t.Errorf("TokenService.ParseToken() custom claims = %v, want %v", got, tt.wantClaims)
+			}
+		})
+	}
+}
+
+func TestTokenService_TokenGeneration(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+	loggedOut := &model.User{Id: u.Id, TokenGeneration: 1}
+
+	ts0 := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, loggedOut), ts0.refreshTokenStore, ts0.denylist)
+
+	accessToken, err := ts0.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, err := ts0.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts0.ParseToken(ctx, accessToken); err != nil {
+		t.Errorf("TokenService.ParseToken() error = %v, want nil", err)
+	}
+
+	if _, err := ts.ParseToken(ctx, accessToken); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.ParseToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+
+	if _, err := ts.ValidateRefreshToken(ctx, refreshToken); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.ValidateRefreshToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+
+	if _, err := ts.RotateRefreshToken(ctx, refreshToken, loggedOut); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.RotateRefreshToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+}
+
+func TestTokenService_DisabledUser(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	u := &model.User{Id: primitive.NewObjectID()}
+	disabled := &model.User{Id: u.Id, Disabled: true}
+
+	ts0 := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, u), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t, disabled), ts0.refreshTokenStore, ts0.denylist)
+
+	accessToken, err := ts0.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.ParseToken(ctx, accessToken); !errors.Is(err, app.ErrTokenRevoked) {
+		t.Errorf("TokenService.ParseToken() error = %v, want %v", err, app.ErrTokenRevoked)
+	}
+
+	if _, err := ts.ResolveGrant(ctx, disabled, nil); !errors.Is(err, app.ErrUserDisabled) {
+		t.Errorf("TokenService.ResolveGrant() error = %v, want %v", err, app.ErrUserDisabled)
+	}
+}
+
+func TestTokenService_EmailVerifiedClaim(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	verified, unverified := true, false
+
+	tests := []struct {
+		name string
+		user *model.User
+		want *bool
+	}{
+		{
+			name: "should flag the verified email",
+			user: &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com", EmailVerified: true},
+			want: &verified,
+		},
+		{
+			name: "should flag the unverified email",
+			user: &model.User{Id: primitive.NewObjectID(), Email: "foo@bar.com"},
+			want: &unverified,
+		},
+		{
+			name: "should not flag the users without an email",
+			user: &model.User{Id: primitive.NewObjectID()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ts.GenerateAccessToken(ctx, tt.user, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := ts.ParseToken(ctx, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := claims.(*Claims).EmailVerified; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TokenService.GenerateAccessToken() email_verified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenService_ClientIdClaim(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	u := &model.User{Id: primitive.NewObjectID()}
+
+	generate := func(t *testing.T, issue func() (string, error)) app.Claims {
+		token, err := issue()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := ts.ParseToken(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return claims
+	}
+
+	tests := []struct {
+		name           string
+		issue          func() (string, error)
+		wantClientId   string
+		wantFirstParty bool
+	}{
+		{
+			name:           "should issue first-party tokens without a client",
+			issue:          func() (string, error) { return ts.GenerateAccessToken(ctx, u, nil) },
+			wantFirstParty: true,
+		},
+		{
+			name:         "should stamp the client of the grant",
+			issue:        func() (string, error) { return ts.GenerateAccessToken(ctx, u, &app.TokenGrant{ClientId: "partner"}) },
+			wantClientId: "partner",
+		},
+		{
+			name: "should not issue first-party tokens to the actors",
+			issue: func() (string, error) {
+				return ts.GenerateDelegatedAccessToken(ctx, u, &app.DelegatedTokenRequest{Actor: &app.Actor{Subject: "support"}})
+			},
+		},
+		{
+			name: "should stamp the client of the client tokens",
+			issue: func() (string, error) {
+				return ts.GenerateClientAccessToken(ctx, &model.OAuthClient{Id: "dispatch"}, nil)
+			},
+			wantClientId: "dispatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := generate(t, tt.issue)
+
+			if got := claims.GetClientId(); got != tt.wantClientId {
+				t.Errorf("Claims.GetClientId() = %v, want %v", got, tt.wantClientId)
+			}
+
+			if got := app.IsFirstPartyToken(claims); got != tt.wantFirstParty {
+				t.Errorf("app.IsFirstPartyToken() = %v, want %v", got, tt.wantFirstParty)
+			}
+		})
+	}
+
+	t.Run("should keep the client of the refresh token", func(t *testing.T) {
+		token, err := ts.GenerateRefreshToken(ctx, u, &app.TokenGrant{ClientId: "partner"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		grant, err := ts.RefreshTokenGrant(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims := generate(t, func() (string, error) { return ts.GenerateAccessToken(ctx, u, grant) })
+		if got := claims.GetClientId(); got != "partner" {
+			t.Errorf("Claims.GetClientId() = %v, want partner", got)
+		}
+	})
+}
+
+func TestTokenService_KeySet(t *testing.T) {
+	SetTokenServiceEnvForTesting(t)
+
+	ctx := context.Background()
+	ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+
+	got := ts.KeySet(ctx)
+
+	// access and refresh tokens share the same key while testing
+	if len(got.Keys) != 1 {
+		t.Fatalf("TokenService.KeySet() has %d keys, want %d", len(got.Keys), 1)
+	}
+
+	if got.Keys[0].Kty != "RSA" || got.Keys[0].Alg != "RS256" || got.Keys[0].Kid == "" {
+		t.Errorf("TokenService.KeySet() = %v, want RS256 key with kid", got.Keys[0])
+	}
+
+	u := &model.User{Id: primitive.NewObjectID()}
+
+	accessToken, err := ts.GenerateAccessToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, err := ts.GenerateRefreshToken(ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{accessToken, refreshToken} {
+		tkn, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tkn.Header["kid"] != got.Keys[0].Kid {
+			t.Errorf("token kid = %v, want %v", tkn.Header["kid"], got.Keys[0].Kid)
+		}
+	}
+}
+
+func TestTokenService_SigningAlgorithms(t *testing.T) {
+	for _, alg := range []string{"ES256", "EdDSA"} {
+		t.Run("should sign and verify tokens with "+alg, func(t *testing.T) {
+			SetTokenServiceEnvForTesting(t)
+
+			dir := t.TempDir()
+			kid := writeKeyForTesting(t, filepath.Join(dir, "active.pem"), alg, true)
+
+			t.Setenv("JWT_ACCESS_TOKEN_ALGORITHM", alg)
+			t.Setenv("JWT_ACCESS_TOKEN_KEY_DIR", dir)
+			t.Setenv("JWT_ACCESS_TOKEN_ACTIVE_KEY", "active.pem")
+
+			ctx := context.Background()
+			ts := NewTokenService(config.New(), NewLoggerMock(), newRepositoryForTesting(t), NewInMemoryRefreshTokenStore(), NewInMemoryTokenDenylist())
+			u := &model.User{Id: primitive.NewObjectID()}
+
+			token, err := ts.GenerateAccessToken(ctx, u, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tkn, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tkn.Method.Alg() != alg || tkn.Header["kid"] != kid {
+				t.Errorf("token alg = %v, kid = %v, want %v, %v", tkn.Method.Alg(), tkn.Header["kid"], alg, kid)
+			}
+
+			if _, err := ts.ParseToken(ctx, token); err != nil {
+				t.Errorf("TokenService.ParseToken() error = %v", err)
+			}
+
+			// signed by the refresh token keyring which still uses RS256
+			rs256, err := ts.sign(&Claims{StandardClaims: jwt.StandardClaims{Issuer: issuer, Subject: u.GetIdString()}}, ts.refreshTokenKeyring, refreshTokenHeaderType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ts.ParseToken(ctx, rs256); err == nil {
+				t.Errorf("TokenService.ParseToken() accepted a RS256 token")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/domain/model"
+	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoVerificationTokenStore struct {
+	app.VerificationTokenStore
+	config *config.Config
+	logger logger.ILogger
+	db     *mongo.Collection
+}
+
+func NewMongoVerificationTokenStore(config *config.Config, logger logger.ILogger, db *mongo.Client) *MongoVerificationTokenStore {
+	return &MongoVerificationTokenStore{
+		config: config,
+		logger: logger,
+		db: db.Database(config.Auth.DatabaseName, nil).
+			Collection(config.Auth.VerificationCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes used by the store. Expired tokens are
+// removed by mongo through the ttl index on expires_at.
+func (s *MongoVerificationTokenStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+	})
+	if err != nil {
+		s.logger.Warnf("error while creating verification token indexes: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Save stores a verification token
+func (s *MongoVerificationTokenStore) Save(ctx context.Context, token *model.VerificationToken) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.InsertOne(ctx, token); err != nil {
+		s.logger.Warnf("error while saving verification token: %s", err)
+		return app.NewInternalServerError(errors.New("error while saving verification token"))
+	}
+
+	return nil
+}
+
+// Consume returns the token and removes it in a single operation, so that
+// only one of concurrent requests can succeed
+func (s *MongoVerificationTokenStore) Consume(ctx context.Context, id string, purpose string) (*model.VerificationToken, error) {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	t := &model.VerificationToken{}
+	if err := s.db.FindOneAndDelete(ctx, bson.M{"_id": id, "purpose": purpose}).Decode(t); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, app.ErrVerificationTokenNotFound
+		}
+
+		s.logger.Warnf("error while consuming verification token: %s", err)
+		return nil, app.NewInternalServerError(errors.New("error while consuming verification token"))
+	}
+
+	if t.IsExpired() {
+		return nil, app.ErrVerificationTokenNotFound
+	}
+
+	return t, nil
+}
+
+// DeleteByUser removes the tokens of the purpose issued to the user
+func (s *MongoVerificationTokenStore) DeleteByUser(ctx context.Context, userId string, purpose string) error {
+	ctx, cancel := s.contextWithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.DeleteMany(ctx, bson.M{"user_id": userId, "purpose": purpose}); err != nil {
+		s.logger.Warnf("error while deleting verification tokens: %s", err)
+		return app.NewInternalServerError(errors.New("error while deleting verification tokens"))
+	}
+
+	return nil
+}
+
+func (s *MongoVerificationTokenStore) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.Mongo.SocketTimeout)*time.Second)
+}
+
+type InMemoryVerificationTokenStore struct {
+	app.VerificationTokenStore
+	mu     sync.Mutex
+	tokens map[string]*model.VerificationToken
+}
+
+func NewInMemoryVerificationTokenStore() *InMemoryVerificationTokenStore {
+	return &InMemoryVerificationTokenStore{
+		tokens: make(map[string]*model.VerificationToken),
+	}
+}
+
+// Save stores a verification token and drops the expired ones
+func (s *InMemoryVerificationTokenStore) Save(ctx context.Context, token *model.VerificationToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.IsExpired() {
+			delete(s.tokens, id)
+		}
+	}
+
+	t := *token
+	s.tokens[token.Id] = &t
+
+	return nil
+}
+
+// Consume returns the token and removes it
+func (s *InMemoryVerificationTokenStore) Consume(ctx context.Context, id string, purpose string) (*model.VerificationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[id]
+	if !ok || t.Purpose != purpose {
+		return nil, app.ErrVerificationTokenNotFound
+	}
+
+	delete(s.tokens, id)
+
+	if t.IsExpired() {
+		return nil, app.ErrVerificationTokenNotFound
+	}
+
+	return t, nil
+}
+
+// DeleteByUser removes the tokens of the purpose issued to the user
+func (s *InMemoryVerificationTokenStore) DeleteByUser(ctx context.Context, userId string, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.UserId == userId && t.Purpose == purpose {
+			delete(s.tokens, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package server
+
+import "github.com/orkungursel/hey-taxi-identity-api/internal/app"
+
+// RegisterClaimsEnricher adds an enricher for the access tokens. Enrichers are
+// expected to be registered by the plugins, and they run in the order they
+// are registered.
+func (s *Server) RegisterClaimsEnricher(e app.ClaimsEnricher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.claimsEnrichers = append(s.claimsEnrichers, e)
+
+	return nil
+}
+
+// ClaimsEnrichers returns the registered claims enrichers
+func (s *Server) ClaimsEnrichers() []app.ClaimsEnricher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]app.ClaimsEnricher(nil), s.claimsEnrichers...)
+}
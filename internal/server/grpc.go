@@ -17,7 +17,7 @@ func (s *Server) startGRPCServer(ctx context.Context, cancel context.CancelFunc)
 		MaxConnectionIdle:     time.Duration(s.config.Server.Grpc.MaxConnectionIdle) * time.Second,
 		MaxConnectionAge:      time.Duration(s.config.Server.Grpc.MaxConnectionAge) * time.Second,
 		MaxConnectionAgeGrace: time.Duration(s.config.Server.Grpc.MaxConnectionAgeGrace) * time.Second,
-	}), grpc.ChainUnaryInterceptor())
+	}), grpc.ChainUnaryInterceptor(s.grpcInterceptors...))
 
 	s.mapServices()
 
@@ -12,6 +12,14 @@ func (s *Server) RegisterGrpcService(gs GrpcService) error {
 	return nil
 }
 
+// RegisterGrpcInterceptor adds an interceptor to the unary calls of all gRPC
+// services. Interceptors run in the order they are registered.
+func (s *Server) RegisterGrpcInterceptor(i grpc.UnaryServerInterceptor) error {
+	s.grpcInterceptors = append(s.grpcInterceptors, i)
+
+	return nil
+}
+
 func (s *Server) mapServices() {
 	for _, gs := range s.grpcServices {
 		gs.Register(s.gs)
@@ -2,12 +2,15 @@ package server
 
 import "github.com/labstack/echo/v4"
 
+// ApiPrefix is the path prefix of the apis which are not registered as root
+const ApiPrefix = "/api/v1"
+
 func (s *Server) mapHandlers() {
 	s.echo.GET("/", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"service": s.config.App.Name})
 	})
 
-	root := s.echo.Group("/api/v1")
+	root := s.echo.Group(ApiPrefix)
 
 	for _, api := range s.httpHandlers {
 		if api.isRoot {
@@ -4,23 +4,28 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/labstack/echo/v4"
 	"github.com/orkungursel/hey-taxi-identity-api/config"
+	"github.com/orkungursel/hey-taxi-identity-api/internal/app"
 	"github.com/orkungursel/hey-taxi-identity-api/pkg/logger"
 	"google.golang.org/grpc"
 )
 
 type Server struct {
-	echo         *echo.Echo
-	gs           *grpc.Server
-	config       *config.Config
-	logger       logger.ILogger
-	ctx          context.Context
-	httpHandlers []HttpApiHandlerItem
-	grpcServices []GrpcService
-	done         chan struct{}
+	echo             *echo.Echo
+	gs               *grpc.Server
+	config           *config.Config
+	logger           logger.ILogger
+	ctx              context.Context
+	httpHandlers     []HttpApiHandlerItem
+	grpcServices     []GrpcService
+	grpcInterceptors []grpc.UnaryServerInterceptor
+	claimsEnrichers  []app.ClaimsEnricher
+	mu               sync.RWMutex
+	done             chan struct{}
 }
 
 func New(ctx context.Context, config *config.Config, logger logger.ILogger) *Server {